@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yogischogi/phyloage/phylotree"
+)
+
+// diffTrees compares the ages of two results trees and prints
+// the differences. args are the filenames of the old and the
+// new tree.
+func diffTrees(args []string) {
+	if len(args) != 2 {
+		fmt.Printf("Usage: phyloage diff old.txt new.txt\r\n")
+		os.Exit(1)
+	}
+	oldTree, err := phylotree.NewFromFile(args[0])
+	if err != nil {
+		fmt.Printf("Error reading tree from file %s, %v.\r\n", args[0], err)
+		os.Exit(1)
+	}
+	newTree, err := phylotree.NewFromFile(args[1])
+	if err != nil {
+		fmt.Printf("Error reading tree from file %s, %v.\r\n", args[1], err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s", phylotree.Diff(oldTree, newTree))
+}
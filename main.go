@@ -7,206 +7,853 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phyloage/run"
 	"github.com/yogischogi/phylofriend/genetic"
 	"github.com/yogischogi/phylofriend/genfiles"
 )
 
 func main() {
+	// Compare two results trees.
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		diffTrees(os.Args[2:])
+		return
+	}
+
 	// Command line flags.
+	defaults := run.DefaultOptions()
 	var (
 		treein     = flag.String("treein", "", "Input filename for phylogenetic tree (.txt).")
 		treeout    = flag.String("treeout", "", "Output filename for phylogenetic tree in TXT format.")
+		format     = flag.String("format", "text", "Output format for -treeout: text, newick, nexus or phyloxml.")
+		maxdepth   = flag.Int("maxdepth", -1, "Number of levels below the root in the tree output, negative is unlimited.")
+		collapse   = flag.Int("collapse", 0, "Collapses clades with fewer samples in the tree output.")
+		sortClades = flag.String("sort", "input", "Order of subclades in the tree output: tmrca, name or input.")
+		sortSmpls  = flag.String("sort-samples", "input", "Order of samples in the tree and trace output: id, strcount or input.")
+		showCounts = flag.Bool("n", false, "Shows the number of direct and downstream samples on each clade line.")
+		newickLen  = flag.Bool("newick-lengths", false, "Reads -treein in Newick format and uses branch lengths as STR-Counts.")
+		tabwidth   = flag.Int("tabwidth", defaults.TabWidth, "Number of spaces that a tab counts for the indentation of -treein.")
 		cal        = flag.Float64("cal", 1, "Calibration factor for TMRCA calculation.")
+		calibrate  = flag.String("calibrate", "", "Calculates the calibration factor from a clade with known TMRCA, format SNP:AGE, or auto for the ages in the tree.")
+		anchors    = flag.String("anchors", "", "Filename of clades with known TMRCAs to fit the calibration factor.")
+		fitOffset  = flag.Bool("fitoffset", false, "Fits the offset together with the calibration factor to the anchors.")
 		offset     = flag.Float64("offset", 0, "Offset is added to all calculated ages.")
 		topdown    = flag.Bool("topdown", true, "Performs a top down recalculation.")
 		personsin  = flag.String("personsin", "", "Input filename (.txt or .csv) or directory.")
+		personsfmt = flag.String("personsin-format", "auto", "Format of CSV persons files: auto, wide or long.")
+		exclMarker = flag.String("excludemarkers", "", "Comma separated list of markers or @filename of markers that are not used for the calculation.")
+		maxRate    = flag.Float64("maxrate", 0, "Markers with a higher mutation rate are not used for the calculation, 0 is unlimited.")
+		nullMarker = flag.String("nullmarkers", "", "Comma separated list of markers or @filename of markers, for which missing values of tested panels are null alleles.")
+		average    = flag.String("average", "mean", "Averaging of the STR counts of the samples of a clade: mean or median.")
+		trim       = flag.Float64("trim", 0, "Fraction of the smallest and largest sample STR counts of a clade that are trimmed before averaging, 0 is off.")
+		trimMode   = flag.String("trimmode", "drop", "Handling of trimmed STR counts: drop or winsorize.")
+		micro      = flag.String("microalleles", "strict", "Handling of microalleles like 17.2: strict or ignore (round to whole numbers).")
+		minMarkers = flag.Int("minmarkers", 0, "Minimum number of tested markers of a person, 0 uses all persons.")
+		idmatch    = flag.String("idmatch", "normalized", "Comparison of person and sample IDs: exact or normalized.")
+		idprefixes = flag.String("idprefixes", "", "Comma separated list of prefixes that are removed from normalized IDs.")
 		mrin       = flag.String("mrin", "", "Filename for the import of mutation rates.")
+		mrout      = flag.String("mrout", "", "Output filename for the mutation rates that are used for the calculation.")
+		strictMR   = flag.Bool("strict-rates", false, "Treats problems with mutation rates as errors.")
 		gentime    = flag.Float64("gentime", 1, "Generation time in years.")
 		inspect    = flag.String("inspect", "", "Comma separated list of SNP names to search for.")
+		inspectNeg = flag.Bool("inspect-negative", false, "Also lists clades and samples that are negative for the -inspect SNPs.")
+		regex      = flag.Bool("regex", false, "Treats the -inspect search terms as regular expressions.")
+		inspectPsn = flag.String("inspectpersons", "", "Comma separated list of names to search for in the persons' names and labels.")
+		query      = flag.String("query", "", "Comma separated list of SNP names to print the ages of these clades.")
+		queryFmt   = flag.String("queryformat", "text", "Output format for -query: text or json.")
+		negPrefix  = flag.String("negative-prefix", defaults.NegativePrefix, "Prefix of negative SNP calls in the input tree, empty to turn off.")
 		statistics = flag.Bool("statistics", false, "Prints marker statistics.")
+		selectOut  = flag.String("selectedout", "", "Output filename for the mutation rates of markers selected by -selectminfreq, -selectminvalues and -selectmaxvalues.")
+		selMinFreq = flag.Float64("selectminfreq", 1.0, "Minimum frequency of a marker for -selectedout.")
+		selMinVals = flag.Int("selectminvalues", 1, "Minimum number of different values of a marker for -selectedout.")
+		selMaxVals = flag.Int("selectmaxvalues", 5, "Maximum number of different values of a marker for -selectedout.")
+		cladeStats = flag.String("cladestats", "", "Comma separated list of clades or all to print marker statistics of their downstream samples.")
 		method     = flag.String("method", "parsimony", "Method to calculate modal haplotypes: phylofriend or parsimony.")
 		stage      = flag.Int("stage", 4, "Processing stage for parsimony algorithm: 1, 2, 3, 4.")
-		trace      = flag.String("trace", "", "Comma separated list of STR names to print out trace information.")
-		subclade   = flag.String("subclade", "", "Selects a specific branch of the tree.")
+		trace      = flag.String("trace", "", "Comma separated list of STR names, ranges or panels (ftdna37) to print out trace information or auto.")
+		traceout   = flag.String("traceout", "", "Output filename for trace information.")
+		traceDelta = flag.Bool("trace-changes-only", false, "Traces only values that differ from the parent's values.")
+		traceChg   = flag.Bool("tracechanges", false, "Traces all STRs whose values change somewhere in the tree.")
+		subclade   = flag.String("subclade", "", "Comma separated list of SNP names of branches of the tree to select.")
+		exclSample = flag.String("excludesamples", "", "Comma separated list of sample IDs to remove from the tree.")
+		exclClade  = flag.String("excludeclade", "", "Comma separated list of SNP names of branches to remove from the tree.")
+		exclude    = flag.String("exclude", "", "Short form of -excludeclade.")
 		htmlout    = flag.String("htmlout", "", "Output filename for persons in HTML format.")
-		model      = flag.String("model", "hybrid", "Mutation model: hybrid or infinite.")
+		personsout = flag.String("personsout", "", "Output filename for the modal haplotypes in CSV format.")
+		outSamples = flag.Bool("personsout-samples", false, "Adds the samples to -personsout.")
+		htmltree   = flag.String("htmltree", "", "Output filename for the tree in HTML format.")
+		model      = flag.String("model", "hybrid", "Mutation model: hybrid, infinite or both.")
+		distance   = flag.String("distance", "", "Distance function: hybrid, infinite or stepwise. Default is the function of -model.")
+		modelsout  = flag.String("compare-models", "", "Output filename for a CSV comparison of both mutation models.")
+		rerun      = flag.Bool("rerun-modals", true, "Recalculates modal haplotypes for each model if -model=both.")
+		previous   = flag.String("previous", "", "Filename of a previously calculated tree for an incremental update.")
+		verify     = flag.String("verify", "", "Filename of an expected results tree to verify the results.")
+		verifyTol  = flag.String("verify-tolerance", "1", "Tolerance for -verify in years or in percent, for example 10 or 1%.")
+		cmpTMRCA   = flag.Bool("comparetmrca", false, "Prints the mean absolute deviation from the TMRCAs of the input tree.")
+		evolution  = flag.String("evolution", "", "Prints the evolution of the modal haplotypes from the root to a clade.")
+		evoMarkers = flag.String("evolution-markers", "", "Comma separated list of STR names for -evolution.")
+		evoSamples = flag.Bool("evolution-samples", false, "Adds the clade's samples to the -evolution table.")
+		evoCSV     = flag.String("evolution-csv", "", "Output filename for the -evolution table in CSV format.")
+		weights    = flag.String("weights", "", "CSV file with sample weights: id,weight.")
+		priors     = flag.String("priors", "", "CSV file with prior TMRCA estimates: clade,age,sigma.")
+		dotout     = flag.String("dotout", "", "Output filename for the tree in Graphviz DOT format.")
+		svgout     = flag.String("svgout", "", "Output filename for a time-scaled tree in SVG format.")
+		svgScale   = flag.Float64("svg-scale", 10, "Pixels per 100 years for -svgout.")
+		svgFont    = flag.Float64("svg-fontsize", 12, "Font size in pixels for -svgout.")
+		svgSamples = flag.Bool("svg-samples", true, "Shows sample IDs in -svgout.")
+		uncertOut  = flag.String("uncertainreport", "", "Output filename for the markers of modal haplotypes that remain uncertain.")
+		kitsout    = flag.String("kitsout", "", "Output filename for the clade and age of each sample in CSV format.")
+		agesout    = flag.String("agesout", "", "Output filename for the ages of all clades in CSV format.")
+		mutations  = flag.String("mutations", "", "Output filename for the inferred mutation events of all branches (text or CSV).")
+		originout  = flag.String("originreport", "", "Output filename for the number of samples per origin and clade (CSV).")
+		originDpt  = flag.Int("origin-depth", 0, "Maximum tree depth for -originreport, 0 is unlimited.")
+		originMin  = flag.Int("origin-min", 0, "Minimum number of samples of a clade for -originreport.")
+		explain    = flag.String("explain-modal", "", "Explains the calculation of the modal haplotype of a clade.")
+		snprate    = flag.Float64("snprate", 0, "Years per SNP mutation to combine SNP-Counts with STR branch lengths.")
+		maxBranch  = flag.Float64("max-branch-gd", 0, "Maximum genetic distance between subclade and parent modals, 0 is off.")
+		exclBranch = flag.Bool("exclude-distant", false, "Excludes subclades exceeding -max-branch-gd from age calculations.")
+		complete   = flag.Bool("completeness", false, "Prints marker completeness for each clade.")
+		panelsRep  = flag.Bool("panelsreport", false, "Prints the number of samples per tested panel for each clade.")
+		minSupport = flag.Int("min-modal-support", defaults.MinSupport, "Minimum number of samples that support a modal marker value.")
+		compSupp   = flag.Int("completeness-support", phylotree.DefaultCompletenessSupport, "Lists markers of -completeness that are supported by fewer samples.")
+		maxUncert  = flag.Int("maxuncertain", -1, "Maximum number of uncertain modal values per clade, stops if exceeded. -1 is unlimited.")
+		maxUncPct  = flag.Float64("maxuncertainpct", -1, "Maximum percentage of uncertain modal values in the tree, stops if exceeded. -1 is unlimited.")
+		matrixout  = flag.String("tmrcamatrix", "", "Output filename for a CSV matrix of pairwise TMRCAs.")
+		matrixIDs  = flag.String("tmrcamatrix-ids", "", "Comma separated list of sample IDs for the TMRCA matrix.")
+		lca        = flag.String("lca", "", "Comma separated list of SNP names or sample IDs to find their lowest common ancestor.")
+		mrca       = flag.String("mrca", "", "Same as -lca, for example -mrca=ID1,ID2.")
+		tmrcaOf    = flag.String("tmrcaof", "", "Two comma separated SNP names to print the TMRCA of both clades.")
+		ageladder  = flag.String("ageladder", "", "Comma separated list of sample IDs to print their ancestral clades and ages.")
+		consistent = flag.Bool("consistency", false, "Prints the consistency index for each marker.")
+		ciMin      = flag.Float64("ci-threshold", 0.5, "Markers with a lower consistency index are exclusion candidates.")
+		outliers   = flag.Bool("outliers", false, "Prints samples with suspicious STR counts to Stderr.")
+		outlierZ   = flag.Float64("outlierz", 3, "Minimum z-score of the STR count of a sample for -outliers.")
+		outlierOut = flag.String("outliersout", "", "Output filename for the samples with suspicious STR counts.")
+		homoplasy  = flag.Bool("homoplasy", false, "Prints the number of mutation events, parallel and back mutations for each marker.")
+		maxSteps   = flag.Float64("max-steps", 0, "Maximum number of mutation steps for a single marker, 0 is unlimited.")
+		stepsMode  = flag.String("max-steps-mode", "cap", "Handling of larger differences than max-steps: cap or single.")
+		qualityout = flag.String("dataquality", "", "Output filename for a data quality report of the persons' data.")
+		matchout   = flag.String("matchreport", "", "Output filename for a list of persons and samples that did not match.")
+		strict     = flag.Bool("strict", false, "Treats severe data problems and inconsistent tree indentation as errors.")
+		compareout = flag.String("compare-methods", "", "Output filename for a CSV comparison of both modal methods.")
 	)
+	var grafts stringList
+	flag.Var(&grafts, "graft", "Attaches the tree from a file to a clade: file.txt:SNP. May be repeated.")
 	flag.Parse()
 
-	var (
-		persons       []*genetic.Person
-		mutationRates genetic.YstrMarkers
-		stat          *genetic.MarkerStatistics
-		err           error
-	)
+	// If the tree is written to stdout, all other messages
+	// are written to stderr to keep the output clean.
+	treeWriter := os.Stdout
+	if *treeout == "-" {
+		os.Stdout = os.Stderr
+	}
 
-	// Load phylogenetic tree from file.
-	if *treein == "" {
-		fmt.Printf("No filename for input tree specified.\r\n")
+	switch *format {
+	case "text", "newick", "nexus", "phyloxml":
+	default:
+		fmt.Printf("Error, unknown output format: %s.\r\n", *format)
 		os.Exit(1)
 	}
-	tree, err := phylotree.NewFromFile(*treein)
-	if err != nil {
-		fmt.Printf("Error reading tree from file, %v.\r\n", err)
+	switch *queryFmt {
+	case "text", "json":
+	default:
+		fmt.Printf("Error, unknown query format: %s.\r\n", *queryFmt)
 		os.Exit(1)
 	}
 
-	// Select subclade.
+	if *tabwidth < 1 {
+		fmt.Printf("Error, invalid tab width: %d.\r\n", *tabwidth)
+		os.Exit(1)
+	}
+
+	// Perform the calculation.
+	opts := defaults
+	if *treein != "" {
+		opts.TreeFiles = strings.Split(*treein, ",")
+	}
+	opts.Stdin = os.Stdin
+	opts.NewickLengths = *newickLen
+	for _, text := range grafts {
+		graft, err := run.ParseGraft(text)
+		if err != nil {
+			fmt.Printf("Error, %v.\r\n", err)
+			os.Exit(1)
+		}
+		opts.Grafts = append(opts.Grafts, graft)
+	}
+	opts.Priors = *priors
+	opts.Weights = *weights
 	if *subclade != "" {
-		tree = tree.Subclade(*subclade)
-		if tree == nil {
-			fmt.Printf("Error, could not find specified subclade %s.\r\n", *subclade)
+		opts.Subclades = strings.Split(*subclade, ",")
+	}
+	if *exclSample != "" {
+		opts.ExcludeSamples = strings.Split(*exclSample, ",")
+	}
+	if *exclClade != "" {
+		opts.ExcludeClades = strings.Split(*exclClade, ",")
+	}
+	if *exclude != "" {
+		opts.ExcludeClades = append(opts.ExcludeClades, strings.Split(*exclude, ",")...)
+	}
+	opts.MutationRates = *mrin
+	opts.StrictRates = *strictMR
+	if *personsin != "" {
+		opts.PersonsFiles = strings.Split(*personsin, ",")
+	}
+	opts.PersonsFormat = *personsfmt
+	opts.MinMarkers = *minMarkers
+	opts.MaxRate = *maxRate
+	if strings.HasPrefix(*exclMarker, "@") {
+		names, err := run.ReadMarkerList((*exclMarker)[1:])
+		if err != nil {
+			fmt.Printf("Error reading excluded markers, %v.\r\n", err)
+			os.Exit(1)
+		}
+		opts.ExcludeMarkers = names
+	} else if *exclMarker != "" {
+		opts.ExcludeMarkers = strings.Split(*exclMarker, ",")
+	}
+	if strings.HasPrefix(*nullMarker, "@") {
+		names, err := run.ReadMarkerList((*nullMarker)[1:])
+		if err != nil {
+			fmt.Printf("Error reading null markers, %v.\r\n", err)
+			os.Exit(1)
+		}
+		opts.NullMarkers = names
+	} else if *nullMarker != "" {
+		opts.NullMarkers = strings.Split(*nullMarker, ",")
+	}
+	switch *idmatch {
+	case "exact":
+		opts.IDMatch = phylotree.ExactIDMatch
+	case "normalized":
+		opts.IDMatch = phylotree.DefaultIDMatch
+		if *idprefixes != "" {
+			opts.IDMatch.Prefixes = strings.Split(*idprefixes, ",")
+		}
+	default:
+		fmt.Printf("Error, unknown ID matching: %s.\r\n", *idmatch)
+		os.Exit(1)
+	}
+	opts.Strict = *strict
+	opts.TabWidth = *tabwidth
+	opts.NegativePrefix = *negPrefix
+	opts.Method = *method
+	opts.Stage = *stage
+	opts.Model = *model
+	opts.Distance = *distance
+	opts.Microalleles = *micro
+	opts.Average = *average
+	opts.Trim = *trim
+	opts.TrimMode = *trimMode
+	opts.RerunModals = *rerun
+	opts.MaxSteps = *maxSteps
+	opts.StepsMode = *stepsMode
+	opts.MinSupport = *minSupport
+	opts.MaxUncertain = *maxUncert
+	opts.MaxUncertainPct = *maxUncPct
+	opts.MaxBranchGD = *maxBranch
+	opts.ExcludeDistant = *exclBranch
+	opts.GenTime = *gentime
+	opts.Calibration = *cal
+	if *calibrate != "" || *anchors != "" {
+		isSet := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) {
+			isSet[f.Name] = true
+		})
+		if isSet["cal"] {
+			fmt.Printf("Error, -calibrate and -anchors can not be used together with -cal.\r\n")
+			os.Exit(1)
+		}
+		if *fitOffset && isSet["offset"] {
+			fmt.Printf("Error, -fitoffset can not be used together with -offset.\r\n")
 			os.Exit(1)
 		}
+		if *calibrate == "auto" {
+			opts.TreeAnchors = true
+		} else if *calibrate != "" {
+			anchor, err := run.ParseCalibration(*calibrate)
+			if err != nil {
+				fmt.Printf("Error, %v.\r\n", err)
+				os.Exit(1)
+			}
+			opts.Anchors = append(opts.Anchors, anchor)
+		}
+		if *anchors != "" {
+			list, err := run.ReadAnchors(*anchors)
+			if err != nil {
+				fmt.Printf("Error reading anchors, %v.\r\n", err)
+				os.Exit(1)
+			}
+			opts.Anchors = append(opts.Anchors, list...)
+		}
+		opts.FitOffset = *fitOffset
+	}
+	opts.Offset = *offset
+	opts.TopDown = *topdown
+	opts.PanelWarnings = *panelsRep
+	opts.SNPRate = *snprate
+	opts.Previous = *previous
+	opts.Log = os.Stdout
+	opts.Warnings = os.Stderr
+	results, err := run.CalculateAll(opts)
+	if err != nil {
+		fmt.Printf("Error, %v.\r\n", err)
+		os.Exit(1)
 	}
 
-	// Read mutation rates from file.
-	if *mrin != "" {
-		mutationRates, err = genfiles.ReadMutationRates(*mrin)
+	// Write mutation rates that are used.
+	if *mrout != "" {
+		err = ioutil.WriteFile(*mrout, []byte(run.FormatMutationRates(results[0].MutationRates)), os.ModePerm)
 		if err != nil {
-			fmt.Printf("Error reading mutation rates %v.\r\n", err)
+			fmt.Printf("Error writing mutation rates to file, %v.\r\n", err)
 			os.Exit(1)
 		}
-	} else {
-		// Use default values.
-		mutationRates = genetic.DefaultMutationRates()
 	}
 
-	// Load genetic sample results.
-	if *personsin != "" {
-		filenames := strings.Split(*personsin, ",")
-		for _, filename := range filenames {
-			var pers []*genetic.Person
-			fileInfo, err := os.Stat(filename)
+	// Write data quality report.
+	if *qualityout != "" && results[0].Persons != nil {
+		err = ioutil.WriteFile(*qualityout, []byte(run.DataQualityReport(results[0].Persons)), os.ModePerm)
+		if err != nil {
+			fmt.Printf("Error writing data quality report to file, %v.\r\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// writeResults writes all outputs for a single result.
+	writeResults := func(result *run.Result, suffix string) {
+		tree := result.Tree
+		header := result.Header
+		var err error
+
+		// The order of the output may be changed.
+		sortedTree := tree
+		if *sortClades != "input" || *sortSmpls != "input" {
+			sortedTree, err = tree.Sorted(*sortClades, *sortSmpls)
+			if err != nil {
+				fmt.Printf("Error, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// The output tree may be limited to the top levels and
+		// small clades may be collapsed.
+		outTree := sortedTree
+		if *collapse > 0 {
+			outTree = outTree.Collapse(*collapse)
+		}
+		if *maxdepth >= 0 {
+			outTree = outTree.Truncate(*maxdepth)
+		}
+
+		// Write persons and samples that did not match.
+		if *matchout != "" && result.Persons != nil {
+			err = ioutil.WriteFile(withSuffix(*matchout, suffix), []byte(result.Matching.String()), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing match report to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Print marker statistics.
+		if *statistics == true && result.Statistics != nil {
+			fmt.Print(result.Statistics.String())
+		}
+
+		// Write mutation rates of a stable marker set.
+		if *selectOut != "" && result.Statistics != nil {
+			n, err := run.WriteSelectedMarkers(withSuffix(*selectOut, suffix), result.Statistics, *selMinFreq, *selMinVals, *selMaxVals)
+			if err != nil {
+				fmt.Printf("Error writing selected markers to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%d markers selected.\r\n", n)
+		}
+
+		// Print marker statistics of single clades.
+		if *cladeStats != "" && result.Persons != nil {
+			report, err := tree.StatisticsReport(strings.Split(*cladeStats, ","))
+			if err != nil {
+				fmt.Printf("Error calculating clade statistics, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(report)
+		}
+
+		// Compare both methods to calculate modal haplotypes.
+		if *compareout != "" && result.Persons != nil {
+			phylofriendTree := result.InputTree.Clone()
+			result.CalculateModals(phylofriendTree, "phylofriend", result.IsInfiniteAlleles)
+			result.CalculateAges(phylofriendTree)
+			parsimonyTree := result.InputTree.Clone()
+			result.CalculateModals(parsimonyTree, "parsimony", result.IsInfiniteAlleles)
+			result.CalculateAges(parsimonyTree)
+			table, summary, err := phylotree.CompareTrees(phylofriendTree, parsimonyTree, "phylofriend", "parsimony")
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*compareout, suffix), []byte(table), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing method comparison to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", summary)
+		}
+
+		// Explain the calculation of a modal haplotype.
+		if *explain != "" && result.Persons != nil {
+			explanation, err := result.InputTree.ExplainModal(*explain, result.Statistics, result.IsInfiniteAlleles, result.Limit, *minSupport)
+			if err != nil {
+				fmt.Printf("Error explaining modal haplotype, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", explanation)
+		}
+
+		// Compare the results of both mutation models.
+		if *modelsout != "" && result.InfiniteTree != nil {
+			table, _, err := phylotree.CompareTrees(tree, result.InfiniteTree, "hybrid", "infinite")
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*modelsout, suffix), []byte(table), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing model comparison to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Save resulting tree to file or print it out.
+		if *treeout != "" {
+			var buffer bytes.Buffer
+			switch *format {
+			case "newick":
+				buffer.WriteString(outTree.Newick())
+			case "nexus":
+				text, err := outTree.Nexus()
+				if err != nil {
+					fmt.Printf("Error creating NEXUS file, %v.\r\n", err)
+					os.Exit(1)
+				}
+				buffer.WriteString(text)
+			case "phyloxml":
+				text, err := outTree.PhyloXML()
+				if err != nil {
+					fmt.Printf("Error creating phyloXML, %v.\r\n", err)
+					os.Exit(1)
+				}
+				buffer.WriteString(text)
+			default:
+				date := time.Now().Format("2006 Jan 2")
+				buffer.WriteString("// This tree was created by the phyloage program: https://github.com/yogischogi/phyloage\r\n")
+				buffer.WriteString("// Command used:\r\n// ")
+				for _, arg := range os.Args {
+					buffer.WriteString(arg)
+					buffer.WriteString(" ")
+				}
+				buffer.WriteString("\r\n")
+				buffer.WriteString("// " + date + "\r\n")
+				buffer.WriteString(header)
+				buffer.WriteString("\r\n")
+				buffer.WriteString(outTree.Format(phylotree.PrintOptions{SampleCounts: *showCounts}))
+			}
+			var err error
+			if *treeout == "-" {
+				_, err = treeWriter.Write(buffer.Bytes())
+			} else {
+				err = ioutil.WriteFile(withSuffix(*treeout, suffix), buffer.Bytes(), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing tree to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Printf("%s%v\r\n", header, outTree)
+		}
+
+		// Compare results with an expected results tree.
+		if *verify != "" {
+			tolerance, err := phylotree.ParseTolerance(*verifyTol)
+			if err != nil {
+				fmt.Printf("Error, %v.\r\n", err)
+				os.Exit(1)
+			}
+			expected, err := phylotree.NewFromFileOptions(*verify, opts.ReadOptions())
+			if err != nil {
+				fmt.Printf("Error reading expected tree from file, %v.\r\n", err)
+				os.Exit(1)
+			}
+			if diff := tree.Verify(expected, tolerance); diff != "" {
+				fmt.Printf("Verification failed:\r\n%s", diff)
+				os.Exit(1)
+			}
+			fmt.Printf("Verification passed.\r\n")
+		}
+
+		// Compare results with the TMRCAs of the input tree.
+		if *cmpTMRCA == true {
+			fmt.Printf("%s", tree.TMRCAComparison())
+		}
+
+		// Write Persons' Y-STR values in HTML format.
+		if *htmlout != "" {
+			persons := tree.Persons()
+			err = genfiles.WritePersonsAsHTML(withSuffix(*htmlout, suffix), persons, genetic.MaxMarkers)
+			if err != nil {
+				fmt.Printf("Error writing persons data to HTML file, %v.\n", err)
+			}
+		}
+
+		// Write modal haplotypes in CSV format.
+		if *personsout != "" {
+			persons := tree.ModalPersons()
+			if *outSamples == true {
+				persons = tree.Persons()
+			}
+			err = writePersonsCSV(withSuffix(*personsout, suffix), persons)
+			if err != nil {
+				fmt.Printf("Error writing persons to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Write tree in HTML format.
+		if *htmltree != "" {
+			err = ioutil.WriteFile(withSuffix(*htmltree, suffix), []byte(outTree.HTMLTree()), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing tree to HTML file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Print tree with values of specified STRs or
+		// of all STRs that mutate.
+		// The output is a CSV table if the output file ends with .csv.
+		if *trace != "" || *traceChg {
+			var result string
+			isCSV := strings.HasSuffix(strings.ToLower(*traceout), ".csv")
+			snps := strings.Split(*trace, ",")
+			if *traceDelta && isCSV {
+				fmt.Printf("Error, -trace-changes-only can not be used for CSV output.\r\n")
+				os.Exit(1)
+			}
+			if !*traceChg {
+				if _, err := sortedTree.TraceIndices(snps); err != nil {
+					fmt.Printf("Error in -trace, %v.\r\n", err)
+					os.Exit(1)
+				}
+			}
+			switch {
+			case *traceChg && isCSV:
+				result, err = sortedTree.TraceChangesCSV()
+			case *traceChg:
+				result = sortedTree.TraceChanges()
+			case isCSV:
+				result, err = sortedTree.TraceCSV(snps)
+			case *traceDelta:
+				result = sortedTree.TraceChangesOnly(snps)
+			default:
+				result = sortedTree.Trace(snps)
+			}
+			if err != nil {
+				fmt.Printf("Error creating trace table, %v.\r\n", err)
+				os.Exit(1)
+			}
+			if *traceout != "" {
+				err = ioutil.WriteFile(withSuffix(*traceout, suffix), []byte(result), os.ModePerm)
+				if err != nil {
+					fmt.Printf("Error writing trace to file, %v.\r\n", err)
+					os.Exit(1)
+				}
+			} else {
+				fmt.Printf("%s", result)
+			}
+		}
+
+		// Write matrix of pairwise TMRCAs.
+		if *matrixout != "" {
+			var ids []string
+			if *matrixIDs != "" {
+				ids = strings.Split(*matrixIDs, ",")
+			}
+			matrix, err := tree.TMRCAMatrix(ids)
+			if err != nil {
+				fmt.Printf("Error creating TMRCA matrix, %v.\r\n", err)
+				os.Exit(1)
+			}
+			err = ioutil.WriteFile(withSuffix(*matrixout, suffix), []byte(matrix), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing TMRCA matrix to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Print the lowest common ancestor of tree nodes.
+		// -mrca is another name for -lca.
+		for _, list := range []string{*lca, *mrca} {
+			if list == "" {
+				continue
+			}
+			names := strings.Split(list, ",")
+			for i, _ := range names {
+				names[i] = strings.TrimSpace(names[i])
+			}
+			report, err := tree.LCAReport(names...)
+			if err != nil {
+				fmt.Printf("Error finding lowest common ancestor, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", report)
+		}
+
+		// Print the TMRCA of two clades.
+		if *tmrcaOf != "" {
+			names := strings.Split(*tmrcaOf, ",")
+			if len(names) != 2 {
+				fmt.Printf("Error, -tmrcaof needs exactly two SNP names.\r\n")
+				os.Exit(1)
+			}
+			distance := result.Distance(result.IsInfiniteAlleles)
+			report, err := tree.InterCladeReport(strings.TrimSpace(names[0]), strings.TrimSpace(names[1]), result.MutationRates, distance)
 			switch {
+			case err == phylotree.ErrSameLineage:
+				fmt.Printf("%s", report)
+				fmt.Printf("Note, %v.\r\n", err)
 			case err != nil:
-				fmt.Printf("Error, something is wrong with personsin, %v.\r\n", err)
+				fmt.Printf("Error finding TMRCA of clades, %v.\r\n", err)
 				os.Exit(1)
-			case fileInfo.IsDir():
-				pers, err = genfiles.ReadPersonsFromDir(filename)
-			case strings.HasSuffix(strings.ToLower(filename), ".csv"):
-				pers, err = genfiles.ReadPersonsFromCSV(filename, 0)
 			default:
-				pers, err = genfiles.ReadPersonsFromTXT(filename)
+				fmt.Printf("%s", report)
 			}
+		}
+
+		// Print the dated ancestral clades of samples.
+		if *ageladder != "" {
+			ids := strings.Split(*ageladder, ",")
+			for i, _ := range ids {
+				ids[i] = strings.TrimSpace(ids[i])
+			}
+			ladder, err := tree.AgeLadder(ids)
 			if err != nil {
-				fmt.Printf("Error loading persons data %v.\r\n", err)
+				fmt.Printf("Error creating age ladder, %v.\r\n", err)
 				os.Exit(1)
 			}
-			persons = append(persons, pers...)
+			fmt.Printf("%s", ladder)
 		}
-		tree.InsertPersons(persons)
 
-		// Calculate marker statistics.
-		if *statistics == true || *method == "parsimony" {
-			stat = genetic.NewStatistics(persons)
+		// Print consistency indices of all markers.
+		if *consistent == true {
+			fmt.Printf("%s", tree.ConsistencyReport(*ciMin))
 		}
 
-		// Print marker statistics.
-		if *statistics == true {
-			fmt.Print(stat.String())
+		// Print parallel and back mutations of all markers.
+		if *homoplasy == true {
+			fmt.Printf("%s", tree.HomoplasyReport())
+		}
 
-			// XXX Temporary code.
-			// WriteToFile(stat)
+		// Print samples with suspicious STR counts.
+		if *outliers == true || *outlierOut != "" {
+			report := tree.OutliersReport(*outlierZ, result.MutationRates, result.Distance(result.IsInfiniteAlleles))
+			if *outlierOut != "" {
+				err := ioutil.WriteFile(withSuffix(*outlierOut, suffix), []byte(report), os.ModePerm)
+				if err != nil {
+					fmt.Printf("Error writing outliers to file, %v.\r\n", err)
+					os.Exit(1)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "%s", report)
+			}
 		}
 
-		var isInfiniteAlleles bool
-		switch *model {
-		case "infinite":
-			isInfiniteAlleles = true
-		case "hybrid":
-			isInfiniteAlleles = false
-		default:
-			fmt.Printf("Error, unknown mutation model: %s.\n", *model)
-			os.Exit(1)
+		// Print marker completeness of each clade.
+		if *complete == true {
+			fmt.Printf("%s", tree.CompletenessReport(*compSupp))
 		}
 
-		// Calculate modal haplotypes.
-		switch *method {
-		case "phylofriend":
-			tree.CalculateModalHaplotypes()
-		case "parsimony":
-			tree.CalculateModalHaplotypesParsimony(stat, *stage, isInfiniteAlleles)
-		default:
-			fmt.Printf("Error, unknown method %q to calculate modal haplotypes.\r\n", *method)
-			os.Exit(1)
+		// Print tested panels of the samples of each clade.
+		if *panelsRep == true {
+			fmt.Printf("%s", tree.PanelsReport())
 		}
 
-		if isInfiniteAlleles == true {
-			tree.CalculateDistances(mutationRates, genetic.DistanceInfiniteAlleles)
-		} else {
-			tree.CalculateDistances(mutationRates, genetic.DistanceHybrid)
-		}
-	}
-
-	// Calculate the age of this clade and all subclades.
-	// If the STR-Count is provided in the original tree input
-	// file the calculation can be performed even without sample
-	// data.
-	tree.CalculateAge(*gentime, *cal, *offset)
-	// Top down recalculation for more realistic results.
-	if *topdown == true {
-		tree.RecalculateAge(*gentime, *cal, *offset)
-	}
-
-	// Save resulting tree to file or print it out.
-	if *treeout != "" {
-		date := time.Now().Format("2006 Jan 2")
-		var buffer bytes.Buffer
-		buffer.WriteString("// This tree was created by the phyloage program: https://github.com/yogischogi/phyloage\r\n")
-		buffer.WriteString("// Command used:\r\n// ")
-		for _, arg := range os.Args {
-			buffer.WriteString(arg)
-			buffer.WriteString(" ")
-		}
-		buffer.WriteString("\r\n")
-		buffer.WriteString("// " + date + "\r\n\r\n")
-		buffer.WriteString(tree.String())
-		err := ioutil.WriteFile(*treeout, buffer.Bytes(), os.ModePerm)
-		if err != nil {
-			fmt.Printf("Error writing tree to file, %v.\r\n", err)
-			os.Exit(1)
+		// Write tree in Graphviz DOT format.
+		if *dotout != "" {
+			err := ioutil.WriteFile(withSuffix(*dotout, suffix), []byte(outTree.DOT()), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing DOT file, %v.\r\n", err)
+				os.Exit(1)
+			}
 		}
-	} else {
-		fmt.Printf("%v\r\n", tree)
-	}
 
-	// Write Persons' Y-STR values in HTML format.
-	if *htmlout != "" {
-		persons := tree.Persons()
-		err = genfiles.WritePersonsAsHTML(*htmlout, persons, genetic.MaxMarkers)
-		if err != nil {
-			fmt.Printf("Error writing persons data to HTML file, %v.\n", err)
+		// Draw a time-scaled tree in SVG format.
+		if *svgout != "" {
+			options := phylotree.SVGOptions{
+				Scale:       *svgScale,
+				FontSize:    *svgFont,
+				ShowSamples: *svgSamples,
+				Offset:      result.Offset}
+			err := ioutil.WriteFile(withSuffix(*svgout, suffix), []byte(tree.SVG(options)), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing SVG file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Write ages of all clades.
+		if *agesout != "" {
+			table, err := outTree.AgesCSV()
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*agesout, suffix), []byte(table), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing ages to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Write mutation events of all branches.
+		// The output is a CSV table if the output file ends with .csv.
+		if *mutations != "" {
+			var report string
+			if strings.HasSuffix(strings.ToLower(*mutations), ".csv") {
+				report, err = sortedTree.MutationsCSV()
+			} else {
+				report = sortedTree.MutationsReport()
+			}
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*mutations, suffix), []byte(report), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing mutations to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Write markers of modal haplotypes that remain uncertain.
+		if *uncertOut != "" {
+			err = ioutil.WriteFile(withSuffix(*uncertOut, suffix), []byte(tree.UncertainReport()), os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error writing uncertain markers to file, %v.\r\n", err)
+				os.Exit(1)
+			}
 		}
-	}
 
-	// Print tree with values of specified STRs.
-	if *trace != "" {
-		snps := strings.Split(*trace, ",")
-		fmt.Printf("%s", tree.Trace(snps))
+		// Write clades and ages of all samples.
+		if *kitsout != "" {
+			table, err := tree.KitsCSV()
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*kitsout, suffix), []byte(table), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing kits to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Write number of samples per origin.
+		if *originout != "" {
+			report, err := tree.OriginReport(*originDpt, *originMin)
+			if err == nil {
+				err = ioutil.WriteFile(withSuffix(*originout, suffix), []byte(report), os.ModePerm)
+			}
+			if err != nil {
+				fmt.Printf("Error writing origin report to file, %v.\r\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Print the evolution of the modal haplotypes.
+		if *evolution != "" {
+			var strs []string
+			if *evoMarkers != "" {
+				strs = strings.Split(*evoMarkers, ",")
+			}
+			text, table, err := tree.Evolution(*evolution, strs, *evoSamples)
+			if err != nil {
+				fmt.Printf("Error creating evolution table, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", text)
+			if *evoCSV != "" {
+				err = ioutil.WriteFile(withSuffix(*evoCSV, suffix), []byte(table), os.ModePerm)
+				if err != nil {
+					fmt.Printf("Error writing evolution table to file, %v.\r\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+
+		// Print the ages of single clades.
+		if *query != "" {
+			report, err := tree.QueryReport(strings.Split(*query, ","), *queryFmt)
+			if err != nil {
+				fmt.Printf("Error in query, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", report)
+		}
+
+		// Search for SNPs and print out information about the matching subclades.
+		if *inspect != "" {
+			searchTerms := strings.Split(*inspect, ",")
+			report, err := tree.InspectMatching(searchTerms, *regex)
+			if err != nil {
+				fmt.Printf("Error, %v.\r\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s", report)
+			if *inspectNeg {
+				fmt.Printf("%s", tree.InspectNegative(searchTerms))
+			}
+		}
+
+		// Search for persons by their names and labels.
+		if *inspectPsn != "" {
+			fmt.Printf("%s", tree.InspectPersons(strings.Split(*inspectPsn, ",")))
+		}
 	}
 
-	// Search for SNPs and print out information about the matching subclades.
-	if *inspect != "" {
-		searchTerms := strings.Split(*inspect, ",")
-		fmt.Printf("%s", tree.Inspect(searchTerms))
+	// Write the outputs for each selected subclade. If several
+	// subclades are selected, the output filenames get the name
+	// of the subclade as a suffix.
+	for _, result := range results {
+		suffix := ""
+		if len(results) > 1 {
+			suffix = result.Tree.SNPs[0]
+		}
+		writeResults(result, suffix)
 	}
 }
 
-// XXX Temporary method to determine stable marker set.
-func WriteToFile(statistics *genetic.MarkerStatistics) {
-	filename := "mutrates.txt"
-	minFreq := 1.0
-	nValuesMin := 1
-	nValuesMax := 5
-	stats := statistics.Select(minFreq, nValuesMin, nValuesMax)
-	err := ioutil.WriteFile(filename, []byte(stats.MutationRates()), os.ModePerm)
-	if err != nil {
-		fmt.Printf("Error writing mutation rates to file, %v.\r\n", err)
-		os.Exit(1)
+// withSuffix appends suffix to the filename in front of the
+// extension, for example tree.txt becomes tree_Z301.txt. Characters
+// that are not allowed in filenames are replaced by underscores.
+// The filename "-" and an empty suffix are not changed.
+func withSuffix(filename, suffix string) string {
+	if suffix == "" || filename == "-" {
+		return filename
 	}
+	suffix = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, suffix)
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "_" + suffix + ext
+}
+
+// stringList is a flag that may be used multiple times.
+// Each value may contain a comma separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, strings.Split(value, ",")...)
+	return nil
 }
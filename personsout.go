@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// writePersonsCSV writes persons in wide CSV format to a file.
+// Each row contains the ID, the name and the marker values of a person.
+// The marker columns are named by their FTDNA names. Uncertain
+// and missing values are written as empty cells.
+func writePersonsCSV(filename string, persons []*genetic.Person) error {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	header := []string{"Kit Number", "Name"}
+	for _, marker := range genetic.YstrMarkerTable {
+		name := marker.FTDNAName
+		if name == "" {
+			name = marker.InternalName
+		}
+		header = append(header, name)
+	}
+	writer.Write(header)
+	for _, person := range persons {
+		row := []string{person.ID, person.Name}
+		for _, value := range person.YstrMarkers {
+			if value > 0 && value != phylotree.Uncertain {
+				row = append(row, fmt.Sprintf("%g", value))
+			} else {
+				row = append(row, "")
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buffer.Bytes(), os.ModePerm)
+}
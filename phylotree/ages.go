@@ -0,0 +1,63 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AgesCSV returns a table in CSV format that contains the time
+// estimates of all clades in depth first order. Clades without
+// time estimates are listed with empty age cells. TMRCA estimates
+// of other models or averaging methods are added as extra columns.
+func (c *Clade) AgesCSV() (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	header := []string{"Clade", "SNPs", "Parent", "Samples", "STRs Downstream",
+		"formed", "TMRCA", "CI lower", "CI upper", "Samples direct", "Samples with data"}
+	models := make([]string, 0, len(c.ModelTMRCAs))
+	for model, _ := range c.ModelTMRCAs {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		header = append(header, fmt.Sprintf("TMRCA (%s)", model))
+	}
+	writer.Write(header)
+	c.agesPrint(writer, "", models)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// agesPrint writes the rows for AgesCSV. parent is the name
+// of the parent clade. models are the names of the extra columns.
+func (c *Clade) agesPrint(writer *csv.Writer, parent string, models []string) {
+	total, withData := c.DownstreamSampleCount()
+	row := []string{c.SNPs[0], strings.Join(c.SNPs, ", "), parent,
+		fmt.Sprintf("%d", total), "", "", "", "", "",
+		fmt.Sprintf("%d", c.SampleCount()), fmt.Sprintf("%d", withData)}
+	if c.STRCountDownstream >= 0 {
+		row[4] = fmt.Sprintf("%.2f", c.STRCountDownstream)
+		row[5] = fmt.Sprintf("%.0f", c.AgeSTR)
+		row[6] = fmt.Sprintf("%.0f", c.TMRCA_STR)
+		row[7] = fmt.Sprintf("%.0f", c.TMRCAlower)
+		row[8] = fmt.Sprintf("%.0f", c.TMRCAupper)
+	}
+	for _, model := range models {
+		if tmrca, exists := c.ModelTMRCAs[model]; exists && c.STRCountDownstream >= 0 {
+			row = append(row, fmt.Sprintf("%.0f", tmrca))
+		} else {
+			row = append(row, "")
+		}
+	}
+	writer.Write(row)
+	for i, _ := range c.Subclades {
+		c.Subclades[i].agesPrint(writer, c.SNPs[0], models)
+	}
+}
@@ -0,0 +1,39 @@
+package phylotree
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isAliasSeparator returns true for characters that separate
+// equivalent SNP names, for example FGC12345/Y98765 or BY3332=Z8188.
+func isAliasSeparator(r rune) bool {
+	return r == '/' || r == '=' || unicode.IsSpace(r)
+}
+
+// snpMatches returns true if the SNP name snp or one of it's
+// aliases equals searchTerm. Case is ignored.
+func snpMatches(snp, searchTerm string) bool {
+	searchTerm = strings.ToLower(trimName(searchTerm))
+	if strings.ToLower(trimName(snp)) == searchTerm {
+		return true
+	}
+	for _, alias := range strings.FieldsFunc(snp, isAliasSeparator) {
+		if strings.ToLower(trimName(alias)) == searchTerm {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasKeys returns the lower case names of all aliases of the
+// SNP name snp. Empty names are skipped.
+func aliasKeys(snp string) []string {
+	var keys []string
+	for _, alias := range strings.FieldsFunc(snp, isAliasSeparator) {
+		if key := strings.ToLower(trimName(alias)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
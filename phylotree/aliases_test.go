@@ -0,0 +1,55 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAliases checks that clades and samples are found by each of
+// their equivalent SNP names and that the combined names are printed.
+func TestAliases(t *testing.T) {
+	tree := mustParse(t, "FGC1/Y1, BY3332=Z8188\r\n"+
+		"\tA\r\n\t\tid:7, FGC20/Y20\r\n"+
+		"\tB\r\n\t\tC\r\n\t\t\tD\r\n\t\t\t\tFGC12345/Y98765\r\n\t\t\t\t\tid:8\r\n")
+
+	tests := []struct {
+		term  string
+		clade string
+	}{
+		{"Y1", "FGC1/Y1"},
+		{"fgc1", "FGC1/Y1"},
+		{"Z8188", "FGC1/Y1"},
+		{"BY3332=Z8188", "FGC1/Y1"},
+		{"Y98765", "FGC12345/Y98765"},
+		{"FGC12345", "FGC12345/Y98765"},
+	}
+	for _, test := range tests {
+		clade, err := tree.FindSubclade(test.term)
+		if err != nil {
+			t.Errorf("FindSubclade(%s), %v", test.term, err)
+			continue
+		}
+		if clade.SNPs[0] != test.clade {
+			t.Errorf("FindSubclade(%s) = %s, expected %s", test.term, clade.SNPs[0], test.clade)
+		}
+		if sub := tree.Subclade(test.term); sub != clade {
+			t.Errorf("Subclade(%s) differs from FindSubclade", test.term)
+		}
+		if result := tree.Inspect([]string{test.term}); !strings.Contains(result, test.clade) {
+			t.Errorf("Inspect(%s) = %q, expected %s", test.term, result, test.clade)
+		}
+	}
+	if clade := tree.Subclade("Y9876"); clade != nil {
+		t.Errorf("Subclade(Y9876) = %s, expected no match", clade.SNPs[0])
+	}
+
+	// Leaf sample.
+	sample, _ := tree.FindSample("7")
+	if sample == nil || !sample.Contains("Y20") || !sample.Contains("FGC20") {
+		t.Fatalf("sample 7 does not contain it's aliases")
+	}
+	result := tree.Inspect([]string{"Y20"})
+	if !strings.Contains(result, "id:7") || !strings.Contains(result, "FGC20/Y20") {
+		t.Errorf("Inspect(Y20) = %q", result)
+	}
+}
@@ -0,0 +1,34 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// AgeAnchors returns all clades of this tree that have an age
+// anchor in tree order.
+func (c *Clade) AgeAnchors() []*Clade {
+	var result []*Clade
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.AgeAnchor > 0 {
+			result = append(result, clade)
+		}
+		return nil
+	})
+	return result
+}
+
+// AnchorReport returns the calculated TMRCA of each clade with an
+// age anchor next to the anchor and their difference.
+func (c *Clade) AnchorReport() string {
+	var buffer bytes.Buffer
+	for _, clade := range c.AgeAnchors() {
+		if clade.STRCountDownstream >= 0 {
+			buffer.WriteString(fmt.Sprintf("%s: Age: %g, TMRCA: %.0f, Δ %+.0f\r\n",
+				clade.SNPs[0], clade.AgeAnchor, clade.TMRCA_STR, clade.TMRCA_STR-clade.AgeAnchor))
+		} else {
+			buffer.WriteString(fmt.Sprintf("%s: Age: %g, TMRCA: unknown\r\n", clade.SNPs[0], clade.AgeAnchor))
+		}
+	}
+	return buffer.String()
+}
@@ -12,6 +12,9 @@ type valueSigma struct {
 	sigma2 float64
 	// weight is the weight for weighted averages.
 	weight float64
+	// years is a time in years that is added after the value
+	// has been converted into years. It has no variance.
+	years float64
 }
 
 // avgCalculator calculates a weighted average and it's standard deviation.
@@ -22,7 +25,14 @@ type avgCalculator struct {
 
 // add adds a value and it's squared standard deviation to the calculator.
 func (a *avgCalculator) add(value, sigma2 float64) {
-	a.entries = append(a.entries, valueSigma{value: value, sigma2: sigma2})
+	a.addYears(value, sigma2, 0)
+}
+
+// addYears works like add. years is added to the value after it
+// has been converted into years, for example the age of ancient
+// samples.
+func (a *avgCalculator) addYears(value, sigma2, years float64) {
+	a.entries = append(a.entries, valueSigma{value: value, sigma2: sigma2, years: years})
 	a.size++
 }
 
@@ -51,6 +61,17 @@ func (a *avgCalculator) avg() (average, sigma2 float64) {
 	return average, sigma2
 }
 
+// avgYears returns the average of the years of all entries. The
+// weights are the same as for the average of the values, so avg
+// must be called before.
+func (a *avgCalculator) avgYears() float64 {
+	result := 0.0
+	for _, e := range a.entries {
+		result += e.years * e.weight
+	}
+	return result
+}
+
 // confidenceIntervalsNormal returns the lower and the upper
 // bound for a 95% confidence interval and a number of mutations.
 // average is the average number of mutations and sigma2 it's variance.
@@ -0,0 +1,88 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// BlendBranchLengths combines the STR based length of the incoming
+// branch of this clade and all subclades with the length derived
+// from the clade's SNP-Count. Both lengths are weighted by their
+// inverse variances, assuming Poisson distributed mutation counts.
+// The variances are calculated from the expected numbers of
+// mutations for the combined branch length, so that the result is
+// the branch length that explains both mutation counts best.
+// The STR-Count is given in generations. It is converted into a
+// number of mutations by the sum of the mutation rates of the
+// compared markers. If it is unknown, one mutation per generation
+// is assumed.
+// The age when the clade was formed (AgeSTR) is recalculated by
+// using the combined branch length. The TMRCA is not changed.
+// yearsPerSNP is the average number of years per SNP mutation.
+// offset is the same offset that was used to calculate the ages.
+// The result is a report that shows for each clade which source
+// contributed most of the mutations and thus dominated the
+// branch length.
+func (c *Clade) BlendBranchLengths(yearsPerSNP, offset float64) string {
+	var buffer bytes.Buffer
+	c.blendBranchLength(yearsPerSNP, offset, &buffer)
+	return buffer.String()
+}
+
+// blendBranchLength performs the calculation for BlendBranchLengths.
+func (c *Clade) blendBranchLength(yearsPerSNP, offset float64, buffer *bytes.Buffer) {
+	if c.SNPCount >= 0 && c.STRCount >= 0 && c.STRCountDownstream > 0 && yearsPerSNP > 0 {
+		// Years per generation, including all calibrations.
+		yearsPerSTR := (c.TMRCA_STR - offset) / c.STRCountDownstream
+		// STR mutations per generation.
+		strRate := c.RateCompared
+		if strRate <= 0 {
+			strRate = 1
+		}
+
+		// Mutation counts and mutation rates per year.
+		strMutations := c.STRCount * strRate
+		strPerYear := strRate / yearsPerSTR
+		snpMutations := c.SNPCount
+		snpPerYear := 1 / yearsPerSNP
+
+		branch := (strMutations + snpMutations) / (strPerYear + snpPerYear)
+		c.AgeSTR = c.TMRCA_STR + branch
+
+		source := "STR"
+		if snpMutations > strMutations {
+			source = "SNP"
+		}
+		share := 0.0
+		if strMutations+snpMutations > 0 {
+			share = 100 * snpMutations / (strMutations + snpMutations)
+		}
+		buffer.WriteString(fmt.Sprintf("%s: branch length: %.0f, STR: %.0f, SNP: %.0f, dominated by %s, SNP mutations: %.0f%%\r\n",
+			c.SNPs[0], branch, c.STRCount*yearsPerSTR, snpMutations*yearsPerSNP, source, share))
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].blendBranchLength(yearsPerSNP, offset, buffer)
+	}
+}
+
+// CheckBranchDistances returns all subclades of this clade, whose
+// modal haplotypes are more than maxDistance mutations away from
+// the modal haplotype of their parent. The distances must have been
+// calculated before. If exclude is true, these subclades are excluded
+// from the age calculation of their parents.
+func (c *Clade) CheckBranchDistances(maxDistance float64, exclude bool) []string {
+	var result []string
+	for i, _ := range c.Subclades {
+		subclade := c.Subclades[i]
+		if subclade.STRCount > maxDistance {
+			msg := fmt.Sprintf("%s: distance to parent %s: %.0f mutations", subclade.SNPs[0], c.SNPs[0], subclade.STRCount)
+			if exclude {
+				subclade.isExcluded = true
+				msg += ", excluded"
+			}
+			result = append(result, msg)
+		}
+		result = append(result, subclade.CheckBranchDistances(maxDistance, exclude)...)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package phylotree
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+func TestBlendBranchLengths(t *testing.T) {
+	tests := []struct {
+		name      string
+		strCount  float64
+		snpCount  float64
+		dominated string
+	}{
+		{"SNP evidence", 1, 12, "dominated by SNP"},
+		{"STR evidence", 60, 1, "dominated by STR"},
+	}
+	for _, test := range tests {
+		// 30 generations of 33 years for the TMRCA and 0.25 STR
+		// mutations per generation, which is typical for 111 markers.
+		clade := mustParse(t, "R\r\n")
+		clade.STRCount, clade.SNPCount = test.strCount, test.snpCount
+		clade.STRCountDownstream, clade.TMRCA_STR = 30, 990
+		clade.RateCompared = 0.25
+		report := clade.BlendBranchLengths(83, 0)
+		if !strings.Contains(report, test.dominated) {
+			t.Errorf("%s: report = %q, want %s", test.name, report, test.dominated)
+		}
+
+		// The SNP evidence must clearly win.
+		strYears, snpYears := test.strCount*33, test.snpCount*83
+		branch := clade.AgeSTR - clade.TMRCA_STR
+		if test.snpCount > test.strCount && math.Abs(branch-snpYears) >= math.Abs(branch-strYears) {
+			t.Errorf("%s: branch length %.0f, STR: %.0f, SNP: %.0f", test.name, branch, strYears, snpYears)
+		}
+	}
+}
+
+// TestCheckBranchDistances checks a tree with one deliberately
+// corrupted subclade.
+func TestCheckBranchDistances(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14, "DYS391": 10, "DYS439": 12, "DYS389i": 13, "DYS392": 13}
+	corrupted := map[string]float64{"DYS393": 16, "DYS390": 28, "DYS19": 18, "DYS391": 13, "DYS439": 15, "DYS389i": 16, "DYS392": 16}
+	persons := []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+		newPerson(t, "3", base),
+		newPerson(t, "4", withValue(base, "DYS19", 15)),
+		newPerson(t, "5", corrupted),
+		newPerson(t, "6", corrupted),
+	}
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n\tC\r\n\t\tid:5\r\n\t\tid:6\r\n")
+	tree.InsertPersons(persons)
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(persons), 4, false)
+	tree.CalculateDistances(genetic.DefaultMutationRates(), DistanceStepwise)
+	included := tree.Clone()
+	maxDistance := 3 * math.Max(tree.Subclade("A").STRCount, tree.Subclade("B").STRCount)
+
+	branches := tree.CheckBranchDistances(maxDistance, true)
+	if len(branches) != 1 || !strings.HasPrefix(branches[0], "C: distance to parent R:") || !strings.HasSuffix(branches[0], ", excluded") {
+		t.Fatalf("branches = %q, want only C", branches)
+	}
+	if !tree.Subclade("C").isExcluded || tree.Subclade("A").isExcluded || tree.Subclade("B").isExcluded {
+		t.Errorf("wrong subclades excluded")
+	}
+
+	// The excluded subclade must not make it's parent older.
+	tree.CalculateAge(33, 1, 0)
+	included.CalculateAge(33, 1, 0)
+	if tree.TMRCA_STR >= included.TMRCA_STR {
+		t.Errorf("TMRCA = %.0f, want less than %.0f", tree.TMRCA_STR, included.TMRCA_STR)
+	}
+}
@@ -0,0 +1,71 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// SamplePersons returns the persons of all samples of this clade
+// and it's subclades. Modal haplotypes, excluded samples and
+// samples with a weight of 0 are not included.
+func (c *Clade) SamplePersons() []*genetic.Person {
+	return c.samplePersons()
+}
+
+// Statistics returns the marker statistics of all persons
+// returned by SamplePersons and the number of persons.
+func (c *Clade) Statistics() (*genetic.MarkerStatistics, int) {
+	persons := c.SamplePersons()
+	return genetic.NewStatistics(persons), len(persons)
+}
+
+// StatisticsReport returns the marker statistics of the clades
+// named by names. If names contains "all", the statistics of all
+// clades with samples are returned in tree order.
+func (c *Clade) StatisticsReport(names []string) (string, error) {
+	isAll := false
+	for _, name := range names {
+		if strings.ToLower(strings.TrimSpace(name)) == "all" {
+			isAll = true
+		}
+	}
+	var clades []*Clade
+	if isAll {
+		c.Walk(func(path []*Clade, clade *Clade) error {
+			clades = append(clades, clade)
+			return nil
+		})
+	} else {
+		var missing []string
+		for _, name := range names {
+			clade, err := c.FindSubclade(strings.TrimSpace(name))
+			if err != nil {
+				missing = append(missing, err.Error())
+				continue
+			}
+			clades = append(clades, clade)
+		}
+		if len(missing) > 0 {
+			return "", errors.New(strings.Join(missing, "; "))
+		}
+	}
+	var buffer bytes.Buffer
+	for _, clade := range clades {
+		statistics, n := clade.Statistics()
+		switch {
+		case n == 0 && isAll:
+			// Skip clades without samples.
+		case n == 0:
+			buffer.WriteString(fmt.Sprintf("%s: no samples with data\r\n\r\n", clade.SNPs[0]))
+		default:
+			buffer.WriteString(fmt.Sprintf("%s: statistics of %d persons\r\n", clade.SNPs[0], n))
+			buffer.WriteString(statistics.String())
+			buffer.WriteString("\r\n")
+		}
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,63 @@
+package phylotree
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestCloneIndependent checks that changing a copy of a tree
+// leaves the original untouched.
+func TestCloneIndependent(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA, A1, fix:DYS393=13, prior: 4500 300\r\n\t\tid:1, S1\r\n\tid:2\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		newPerson(t, "1", map[string]float64{"DYS393": 13}),
+		newPerson(t, "2", map[string]float64{"DYS393": 14}),
+	})
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(tree.SamplePersons()), 4, false)
+	original := tree.String()
+	dys393 := mustIndex(t, "DYS393")
+	modal := tree.Person.YstrMarkers[dys393]
+
+	clone := tree.Clone()
+	if clone.Parent != nil || clone.Subclades[0].Parent != clone {
+		t.Errorf("wrong parents in clone")
+	}
+	a := clone.Subclade("A")
+	a.SNPs[1] = "changed"
+	a.AddSNP("new")
+	a.FixedValues[dys393] = 20
+	a.Prior.Age = 1
+	a.Samples[0].SNPs[0] = "changed"
+	a.Samples[0].Person.YstrMarkers[dys393] = 20
+	a.Samples = append(a.Samples, newSample())
+	clone.Person.YstrMarkers[dys393] = 20
+	clone.Subclades = clone.Subclades[:0]
+
+	if got := tree.String(); got != original {
+		t.Errorf("original changed:\n%s\nwant:\n%s", got, original)
+	}
+	if tree.Subclade("A").Samples[0].Person.YstrMarkers[dys393] != 13 || tree.Person.YstrMarkers[dys393] != modal {
+		t.Errorf("persons of the original changed")
+	}
+	if tree.Subclade("A").FixedValues[dys393] != 13 || tree.Subclade("A").Prior.Age != 4500 {
+		t.Errorf("fixed values or prior of the original changed")
+	}
+}
+
+// TestAddSubclade checks that AddSubclade adds a copy and that
+// AttachSubclade adds the clade itself. Both set the parents.
+func TestAddSubclade(t *testing.T) {
+	tree := mustParse(t, "R\r\n")
+	other := mustParse(t, "A\r\n\tB\r\n")
+	tree.AddSubclade(*other)
+	a := tree.Subclades[0]
+	if a == other || a.Parent != tree || a.Subclades[0].Parent != a {
+		t.Errorf("AddSubclade does not add a copy with parents")
+	}
+	c := mustParse(t, "C\r\n")
+	tree.AttachSubclade(c)
+	if tree.Subclades[1] != c || c.Parent != tree {
+		t.Errorf("AttachSubclade does not add the clade itself")
+	}
+}
@@ -0,0 +1,50 @@
+package phylotree
+
+import (
+	"bytes"
+	"strings"
+)
+
+// headerMark starts the header that the phyloage program writes
+// to a results tree. The header is not preserved when a results
+// tree is read, because it is newly written for each output.
+const headerMark = "// This tree was created by the phyloage program"
+
+// splitComment splits a line of text into the text before a
+// comment and the comment itself without the leading //.
+// A comment starts with // like in many programming languages.
+// If a line contains only whitespace characters before the comment,
+// text is empty. hasComment is true if the line contains a comment.
+func splitComment(line string) (text, comment string, hasComment bool) {
+	text = line
+	idxComment := strings.Index(line, "//")
+	if idxComment >= 0 {
+		text = line[0:idxComment]
+		comment = line[idxComment+2:]
+		hasComment = true
+	}
+	if strings.TrimSpace(text) == "" {
+		text = ""
+	}
+	return text, comment, hasComment
+}
+
+// writeComments writes comment lines with the specified
+// indentation into buffer.
+func writeComments(buffer *bytes.Buffer, comments []string, indent int) {
+	for _, comment := range comments {
+		for i := 0; i < indent; i++ {
+			buffer.WriteString("\t")
+		}
+		buffer.WriteString("//" + comment + "\r\n")
+	}
+}
+
+// commentString returns the comment at the end of the line
+// of element e. If there is none, the result is empty.
+func (e *Element) commentString() string {
+	if e.Comment == "" {
+		return ""
+	}
+	return " //" + e.Comment
+}
@@ -0,0 +1,61 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+)
+
+// CompareTrees compares two trees with identical structure, whose
+// modal haplotypes and ages have been calculated by different methods
+// or mutation models. nameA and nameB are the names of the methods.
+// The result is a CSV table that shows for each clade the number of
+// different marker values in the modal haplotypes and the differences
+// of STR counts and TMRCAs. summary is a short summary for the
+// whole tree.
+func CompareTrees(a, b *Clade, nameA, nameB string) (table, summary string, err error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	writer.Write([]string{"Clade", "Markers different",
+		"STR-Count " + nameA, "STR-Count " + nameB, "STR-Count difference",
+		"TMRCA " + nameA, "TMRCA " + nameB, "TMRCA difference"})
+
+	var clades, markers int
+	var sumTMRCA, maxTMRCA float64
+	var compare func(a, b *Clade)
+	compare = func(a, b *Clade) {
+		different := 0
+		if a.Person != nil && b.Person != nil {
+			for i, _ := range a.Person.YstrMarkers {
+				if a.Person.YstrMarkers[i] != b.Person.YstrMarkers[i] {
+					different++
+				}
+			}
+		}
+		diffTMRCA := b.TMRCA_STR - a.TMRCA_STR
+		writer.Write([]string{a.SNPs[0], fmt.Sprintf("%d", different),
+			fmt.Sprintf("%.0f", a.STRCount), fmt.Sprintf("%.0f", b.STRCount),
+			fmt.Sprintf("%.0f", b.STRCount-a.STRCount),
+			fmt.Sprintf("%.0f", a.TMRCA_STR), fmt.Sprintf("%.0f", b.TMRCA_STR),
+			fmt.Sprintf("%.0f", diffTMRCA)})
+		clades++
+		markers += different
+		sumTMRCA += math.Abs(diffTMRCA)
+		maxTMRCA = math.Max(maxTMRCA, math.Abs(diffTMRCA))
+		for i := 0; i < len(a.Subclades) && i < len(b.Subclades); i++ {
+			compare(a.Subclades[i], b.Subclades[i])
+		}
+	}
+	compare(a, b)
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", "", err
+	}
+	summary = fmt.Sprintf("Compared %s and %s: clades: %d, different marker values: %d, "+
+		"average TMRCA difference: %.0f, maximum TMRCA difference: %.0f\r\n",
+		nameA, nameB, clades, markers, sumTMRCA/float64(clades), maxTMRCA)
+	return buffer.String(), summary, nil
+}
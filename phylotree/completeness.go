@@ -0,0 +1,198 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// panel is a group of Y-STR markers that is usually tested together.
+// Panels are defined by the marker indices in FTDNA order,
+// first is the index of the first marker, last the index
+// after the last marker.
+type panel struct {
+	name  string
+	first int
+	last  int
+}
+
+// panels are the usual FTDNA panel tiers.
+var panels = []panel{
+	{name: "Y12", first: 0, last: 12},
+	{name: "Y25", first: 12, last: 25},
+	{name: "Y37", first: 25, last: 37},
+	{name: "Y67", first: 37, last: 67},
+	{name: "Y111", first: 67, last: 111},
+}
+
+// Completeness describes how well the markers of a clade
+// are covered by the downstream samples.
+type Completeness struct {
+	// Samples is the number of downstream samples with Y-STR data.
+	Samples int
+	// Support is the number of downstream samples that have
+	// a value for a marker.
+	Support []int
+}
+
+// Completeness calculates the marker coverage of all downstream
+// samples of this clade.
+func (c *Clade) Completeness() Completeness {
+	persons := c.samplePersons()
+	result := Completeness{
+		Samples: len(persons),
+		Support: make([]int, len(genetic.YstrMarkers{}))}
+	for _, person := range persons {
+		for i, value := range person.YstrMarkers {
+			if value != 0 {
+				result.Support[i]++
+			}
+		}
+	}
+	return result
+}
+
+// Fraction returns the fraction of downstream samples that
+// have a value for marker.
+func (m Completeness) Fraction(marker int) float64 {
+	if m.Samples == 0 {
+		return 0
+	}
+	return float64(m.Support[marker]) / float64(m.Samples)
+}
+
+// Grade returns a quality grade for the modal haplotype of the
+// clade: A, B, C or D. The grade is determined by the panel tier with
+// the lowest completeness among the tiers that have been tested by at
+// least one downstream sample. A means at least 90%, B at least 75%,
+// C at least 50%. The result is - if there are no samples with data.
+func (m Completeness) Grade() string {
+	lowest := 101.0
+	for _, p := range panels {
+		if !m.isTested(p) {
+			continue
+		}
+		if percent := m.panelPercentage(p); percent < lowest {
+			lowest = percent
+		}
+	}
+	switch {
+	case lowest > 100:
+		return "-"
+	case lowest >= 90:
+		return "A"
+	case lowest >= 75:
+		return "B"
+	case lowest >= 50:
+		return "C"
+	}
+	return "D"
+}
+
+// isTested returns true if at least one marker of panel p has
+// a value in one of the downstream samples.
+func (m Completeness) isTested(p panel) bool {
+	for i := p.first; i < p.last && i < len(m.Support); i++ {
+		if m.Support[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PanelPercentages returns the average completeness of the markers
+// of each panel tier in percent. The keys are the panel names.
+func (m Completeness) PanelPercentages() map[string]float64 {
+	result := make(map[string]float64)
+	for _, p := range panels {
+		result[p.name] = m.panelPercentage(p)
+	}
+	return result
+}
+
+// panelPercentage returns the average completeness of the markers
+// of panel p in percent.
+func (m Completeness) panelPercentage(p panel) float64 {
+	last := p.last
+	if last > len(m.Support) {
+		last = len(m.Support)
+	}
+	if last <= p.first {
+		return 0
+	}
+	sum := 0.0
+	for i := p.first; i < last; i++ {
+		sum += m.Fraction(i)
+	}
+	return 100 * sum / float64(last-p.first)
+}
+
+// Unsupported returns the indices of all markers that have a value
+// in at least one but less than minSupport downstream samples.
+func (m Completeness) Unsupported(minSupport int) []int {
+	var result []int
+	for i, support := range m.Support {
+		if support > 0 && support < minSupport {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// DefaultCompletenessSupport is the default minimum support for
+// CompletenessReport. Markers that have been observed in a single
+// sample only are listed.
+const DefaultCompletenessSupport = 2
+
+// CompletenessReport returns a nicely formatted tree that shows
+// the marker completeness of each clade per panel tier.
+// Markers that were observed in less than minSupport samples
+// are listed for each clade.
+func (c *Clade) CompletenessReport(minSupport int) string {
+	var buffer bytes.Buffer
+	c.completenessPrint(&buffer, 0, minSupport)
+	return buffer.String()
+}
+
+// completenessPrint creates the formatted tree for CompletenessReport.
+func (c *Clade) completenessPrint(buffer *bytes.Buffer, indent int, minSupport int) {
+	for i := 0; i < indent; i++ {
+		buffer.WriteString("\t")
+	}
+	completeness := c.Completeness()
+	buffer.WriteString(c.Element.String())
+	buffer.WriteString(fmt.Sprintf(", Samples: %d", completeness.Samples))
+	for _, p := range panels {
+		buffer.WriteString(fmt.Sprintf(", %s: %.0f%%", p.name, completeness.panelPercentage(p)))
+	}
+	buffer.WriteString(", grade: " + completeness.Grade())
+	unsupported := completeness.Unsupported(minSupport)
+	if len(unsupported) > 0 {
+		buffer.WriteString(", unsupported:")
+		for _, i := range unsupported {
+			buffer.WriteString(" ")
+			buffer.WriteString(genetic.YstrMarkerTable[i].InternalName)
+		}
+	}
+	buffer.WriteString("\r\n")
+	for i, _ := range c.Subclades {
+		c.Subclades[i].completenessPrint(buffer, indent+1, minSupport)
+	}
+}
+
+// samplePersons returns the persons of all samples of this
+// clade and it's subclades. Modal haplotypes and samples with
+// a weight of 0 are not included.
+func (c *Clade) samplePersons() []*genetic.Person {
+	persons := make([]*genetic.Person, 0)
+	for i, _ := range c.Samples {
+		if c.Samples[i].hasInfluence() {
+			persons = append(persons, c.Samples[i].Person)
+		}
+	}
+	for i, _ := range c.Subclades {
+		persons = append(persons, c.Subclades[i].samplePersons()...)
+	}
+	return persons
+}
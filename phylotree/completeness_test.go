@@ -0,0 +1,33 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestCompletenessSupport checks that markers which have been
+// observed in a single sample are listed with the default support.
+func TestCompletenessSupport(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24}
+	tree := mustParse(t, "R\r\n\tid:1\r\n\tid:2\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		newPerson(t, "1", withValue(base, "DYS19", 14)),
+		newPerson(t, "2", base),
+	})
+
+	tests := []struct {
+		minSupport int
+		listed     bool
+	}{
+		{1, false},
+		{DefaultCompletenessSupport, true},
+	}
+	for _, test := range tests {
+		report := tree.CompletenessReport(test.minSupport)
+		if listed := strings.Contains(report, "unsupported: DYS19\r\n"); listed != test.listed {
+			t.Errorf("support %d, DYS19 listed %t, want %t:\n%s", test.minSupport, listed, test.listed, report)
+		}
+	}
+}
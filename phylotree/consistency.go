@@ -0,0 +1,100 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// MarkerConsistency holds the consistency index of a marker.
+type MarkerConsistency struct {
+	// Marker is the index of the marker.
+	Marker int
+	// MinChanges is the minimum possible number of changes:
+	// the number of distinct observed values minus one.
+	MinChanges int
+	// Changes is the number of changes implied by the
+	// modal haplotypes of the tree.
+	Changes int
+	// Index is MinChanges divided by Changes.
+	Index float64
+}
+
+// markerChanges counts the number of value changes of a marker
+// between this clade's modal haplotype and it's children.
+// Subclades are included recursively. Values <= 0 are ignored.
+func (c *Clade) markerChanges(marker int) int {
+	changes := 0
+	if c.Person == nil {
+		return changes
+	}
+	value := c.Person.YstrMarkers[marker]
+	isChange := func(child float64) bool {
+		return value > 0 && child > 0 && child != value
+	}
+	for i, _ := range c.Samples {
+		if c.Samples[i].hasInfluence() && isChange(c.Samples[i].Person.YstrMarkers[marker]) {
+			changes++
+		}
+	}
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].Person != nil && isChange(c.Subclades[i].Person.YstrMarkers[marker]) {
+			changes++
+		}
+		changes += c.Subclades[i].markerChanges(marker)
+	}
+	return changes
+}
+
+// ConsistencyIndices calculates the consistency index for every
+// marker that has been observed in the samples of this clade.
+// The result is sorted in ascending order by the index.
+// The modal haplotypes must be calculated before.
+func (c *Clade) ConsistencyIndices() []MarkerConsistency {
+	persons := c.samplePersons()
+	result := make([]MarkerConsistency, 0)
+	nMarkers := len(genetic.YstrMarkers{})
+	for marker := 0; marker < nMarkers; marker++ {
+		values := make(map[float64]bool)
+		for _, person := range persons {
+			if value := person.YstrMarkers[marker]; value > 0 {
+				values[value] = true
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		ci := MarkerConsistency{
+			Marker:     marker,
+			MinChanges: len(values) - 1,
+			Changes:    c.markerChanges(marker),
+			Index:      1}
+		if ci.Changes > 0 {
+			ci.Index = float64(ci.MinChanges) / float64(ci.Changes)
+		}
+		result = append(result, ci)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Index < result[j].Index
+	})
+	return result
+}
+
+// ConsistencyReport returns a list of the consistency indices
+// of all markers. Markers with an index below threshold are
+// marked as candidates for exclusion.
+func (c *Clade) ConsistencyReport(threshold float64) string {
+	var buffer bytes.Buffer
+	for _, ci := range c.ConsistencyIndices() {
+		name := genetic.YstrMarkerTable[ci.Marker].InternalName
+		buffer.WriteString(fmt.Sprintf("%s: CI: %.2f, min changes: %d, changes: %d",
+			name, ci.Index, ci.MinChanges, ci.Changes))
+		if ci.Index < threshold {
+			buffer.WriteString(", exclude candidate")
+		}
+		buffer.WriteString("\r\n")
+	}
+	return buffer.String()
+}
@@ -0,0 +1,38 @@
+package phylotree
+
+import "fmt"
+
+// SampleCount returns the number of samples that are directly
+// attached to this clade.
+func (c *Clade) SampleCount() int {
+	return len(c.Samples) + c.collapsed.directSamples
+}
+
+// DownstreamSampleCount returns the number of samples of this clade
+// and all subclades. withData is the number of these samples that
+// have Y-STR data.
+func (c *Clade) DownstreamSampleCount() (total, withData int) {
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		total += len(clade.Samples) + clade.collapsed.samples
+		withData += clade.collapsed.withData
+		for i, _ := range clade.Samples {
+			if clade.Samples[i].Person != nil {
+				withData++
+			}
+		}
+		return nil
+	})
+	return total, withData
+}
+
+// countString returns the number of direct and downstream samples
+// of this clade for the tree output. The number of samples with
+// Y-STR data is added if some samples have no data.
+func (c *Clade) countString() string {
+	total, withData := c.DownstreamSampleCount()
+	result := fmt.Sprintf(", n=%d/%d", c.SampleCount(), total)
+	if withData > 0 && withData < total {
+		result += fmt.Sprintf(" (%d with data)", withData)
+	}
+	return result
+}
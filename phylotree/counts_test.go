@@ -0,0 +1,24 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSampleCounts checks that the number of samples is only
+// written if it is requested by the print options and that
+// the written tree can be read again.
+func TestSampleCounts(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tid:3\r\n")
+	if text := tree.String(); strings.Contains(text, "n=") {
+		t.Errorf("String() contains sample counts:\n%s", text)
+	}
+	text := tree.Format(PrintOptions{SampleCounts: true})
+	if !strings.Contains(text, "R, n=1/3\r\n") || !strings.Contains(text, "A, n=2/2\r\n") {
+		t.Errorf("Format() misses sample counts:\n%s", text)
+	}
+	reread := mustParse(t, text)
+	if reread.String() != tree.String() {
+		t.Errorf("reread tree:\n%s\nexpected:\n%s", reread, tree)
+	}
+}
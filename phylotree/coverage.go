@@ -0,0 +1,96 @@
+package phylotree
+
+import (
+	"fmt"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// comparedRate returns the sum of the mutation rates of all markers
+// for which both haplotypes have values.
+func comparedRate(ystr1, ystr2, mutationRates genetic.YstrMarkers) float64 {
+	sum := 0.0
+	for i, _ := range ystr1 {
+		if ystr1[i] > 0 && ystr2[i] > 0 && mutationRates[i] > 0 {
+			sum += mutationRates[i]
+		}
+	}
+	return sum
+}
+
+// maxRateCompared returns the largest RateCompared of all
+// samples and subclades of this clade.
+func (c *Clade) maxRateCompared() float64 {
+	max := c.RateCompared
+	for i, _ := range c.Samples {
+		if c.Samples[i].RateCompared > max {
+			max = c.Samples[i].RateCompared
+		}
+	}
+	for i, _ := range c.Subclades {
+		if rate := c.Subclades[i].maxRateCompared(); rate > max {
+			max = rate
+		}
+	}
+	return max
+}
+
+// setCoverage sets the coverage of this clade, all samples
+// and subclades relative to maxRate.
+func (c *Clade) setCoverage(maxRate float64) {
+	c.Element.setCoverage(maxRate)
+	for i, _ := range c.Samples {
+		c.Samples[i].setCoverage(maxRate)
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].setCoverage(maxRate)
+	}
+}
+
+// setCoverage sets the coverage of this element relative to maxRate.
+func (e *Element) setCoverage(maxRate float64) {
+	if maxRate > 0 {
+		e.coverage = e.RateCompared / maxRate
+	} else {
+		e.coverage = 0
+	}
+}
+
+// coverageFactor returns the factor by which the variance of
+// STRCount is increased, because fewer markers were compared than
+// for the best covered element of the tree. The factor is 1 if
+// the coverage is unknown.
+func (e *Element) coverageFactor() float64 {
+	if e.coverage <= 0 {
+		return 1
+	}
+	return 1 / e.coverage
+}
+
+// panelAverage calculates the average number of compared markers.
+type panelAverage struct {
+	sum   float64
+	count float64
+}
+
+// add adds the number of compared markers of e, if e has been compared.
+func (p *panelAverage) add(e *Element) {
+	if e.MarkersCompared > 0 {
+		p.sum += float64(e.MarkersCompared)
+		p.count++
+	}
+}
+
+// avg returns the average number of compared markers.
+func (p *panelAverage) avg() float64 {
+	if p.count == 0 {
+		return 0
+	}
+	return p.sum / p.count
+}
+
+// Details returns a detailed string representation of this clade,
+// including the average number of compared markers.
+func (c *Clade) Details() string {
+	return fmt.Sprintf("%sPanel size: %.0f markers\r\n", c.Element.Details(), c.PanelSize)
+}
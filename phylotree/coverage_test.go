@@ -0,0 +1,37 @@
+package phylotree
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestCoverageInterval checks that identical STR counts yield a
+// wider confidence interval for 37 markers than for 111 markers.
+func TestCoverageInterval(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\t\tid:3\r\n\tB\r\n\t\tid:4\r\n\t\tid:5\r\n\t\tid:6\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		panelPerson("1", 111), panelPerson("2", 111), panelPerson("3", 111),
+		panelPerson("4", 37), panelPerson("5", 37), panelPerson("6", 37),
+	})
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(tree.SamplePersons()), 4, false)
+	tree.CalculateDistances(genetic.DefaultMutationRates(), genetic.DistanceHybrid)
+	for _, name := range []string{"A", "B"} {
+		clade := tree.Subclade(name)
+		for i, _ := range clade.Samples {
+			clade.Samples[i].STRCount = 20
+		}
+	}
+	tree.CalculateAge(33, 1, 0)
+
+	a, b := tree.Subclade("A"), tree.Subclade("B")
+	if a.TMRCA_STR != b.TMRCA_STR {
+		t.Fatalf("TMRCAs differ: %g, %g", a.TMRCA_STR, b.TMRCA_STR)
+	}
+	if widthA, widthB := a.TMRCAupper-a.TMRCAlower, b.TMRCAupper-b.TMRCAlower; widthB <= widthA {
+		t.Errorf("interval for 37 markers %.0f, want wider than %.0f for 111 markers", widthB, widthA)
+	}
+	if a.PanelSize != 111 || b.PanelSize != 37 {
+		t.Errorf("panel sizes = %g, %g, want 111, 37", a.PanelSize, b.PanelSize)
+	}
+}
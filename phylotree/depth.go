@@ -0,0 +1,99 @@
+package phylotree
+
+import "fmt"
+
+// collapsedCount is the number of subclades and samples
+// below a clade that are not part of a truncated tree.
+type collapsedCount struct {
+	subclades int
+	// samples includes the samples of all subclades.
+	samples int
+	// directSamples are the samples of the clade itself.
+	directSamples int
+	// withData is the number of samples that have Y-STR data.
+	withData int
+}
+
+// isEmpty returns true if nothing has been collapsed.
+func (cc collapsedCount) isEmpty() bool {
+	return cc.subclades == 0 && cc.samples == 0
+}
+
+// summary returns a short description of the collapsed
+// subclades and samples.
+func (cc collapsedCount) summary() string {
+	return fmt.Sprintf("… %d subclades, %d samples collapsed", cc.subclades, cc.samples)
+}
+
+// String returns a comment that summarizes the collapsed subclades
+// and samples. The result is empty if nothing has been collapsed.
+func (cc collapsedCount) String() string {
+	if cc.isEmpty() {
+		return ""
+	}
+	return " // " + cc.summary()
+}
+
+// Truncate returns a copy of this clade that contains only the
+// subclades up to maxDepth levels below this clade. Depth 0 means
+// this clade only. The samples and subclades of the clades at
+// maxDepth are removed and summarized on the line of their clade.
+// A negative maxDepth returns an unchanged copy. The ages are not
+// recalculated.
+func (c *Clade) Truncate(maxDepth int) *Clade {
+	result := c.Clone()
+	if maxDepth >= 0 {
+		result.truncate(maxDepth)
+	}
+	return result
+}
+
+// truncate removes all samples and subclades below depth
+// levels of this clade.
+func (c *Clade) truncate(depth int) {
+	if depth > 0 {
+		for i, _ := range c.Subclades {
+			c.Subclades[i].truncate(depth - 1)
+		}
+		return
+	}
+	// Clades that have been collapsed before keep their counts.
+	c.collapsed.directSamples = c.SampleCount()
+	subclades := 0
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade != c {
+			subclades++
+		}
+		subclades += clade.collapsed.subclades
+		return nil
+	})
+	c.collapsed.subclades = subclades
+	c.collapsed.samples, c.collapsed.withData = c.DownstreamSampleCount()
+	c.Samples = nil
+	c.Subclades = nil
+}
+
+// Collapse returns a copy of this clade in which all subclades that
+// contain fewer than minSamples samples, including the samples of
+// their own subclades, are collapsed. The samples and subclades of
+// a collapsed clade are removed and summarized on the line of the
+// clade. This clade itself is never collapsed. The ages are not
+// recalculated.
+func (c *Clade) Collapse(minSamples int) *Clade {
+	result := c.Clone()
+	result.collapse(minSamples)
+	return result
+}
+
+// collapse collapses all subclades of this clade that contain
+// fewer than minSamples samples.
+func (c *Clade) collapse(minSamples int) {
+	for i, _ := range c.Subclades {
+		subclade := c.Subclades[i]
+		if total, _ := subclade.DownstreamSampleCount(); total < minSamples {
+			subclade.truncate(0)
+		} else {
+			subclade.collapse(minSamples)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package phylotree
+
+import "testing"
+
+// TestTruncateCollapsed checks that truncating a collapsed tree
+// counts the subclades and samples that have been collapsed before.
+func TestTruncateCollapsed(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tB\r\n\t\t\tid:2\r\n\t\t\tC\r\n\t\t\t\tid:3\r\n"+
+		"\t\tD\r\n\t\t\tid:4\r\n\t\t\tid:5\r\n\t\t\tid:6\r\n")
+	collapsed := tree.Collapse(3)
+	b := collapsed.Subclade("B")
+	if b == nil || b.collapsed.subclades != 1 || b.collapsed.samples != 2 {
+		t.Fatalf("collapsed B = %+v", b)
+	}
+
+	for _, truncated := range []*Clade{tree.Truncate(1), collapsed.Truncate(1)} {
+		a := truncated.Subclades[0]
+		expected := collapsedCount{subclades: 3, samples: 6, directSamples: 1}
+		if a.collapsed != expected {
+			t.Errorf("truncated A = %+v, expected %+v", a.collapsed, expected)
+		}
+		if total, _ := truncated.DownstreamSampleCount(); total != 6 {
+			t.Errorf("truncated tree has %d samples, expected 6", total)
+		}
+	}
+
+	// Collapsing the root's child and truncating at it's level.
+	truncated := tree.Collapse(10).Truncate(0)
+	expected := collapsedCount{subclades: 4, samples: 6, directSamples: 0}
+	if truncated.collapsed != expected {
+		t.Errorf("truncated root = %+v, expected %+v", truncated.collapsed, expected)
+	}
+}
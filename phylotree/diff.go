@@ -0,0 +1,83 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Diff compares the ages of two previously calculated results
+// trees. Clades are matched by their SNP names. Clades match if
+// they have an SNP in common, so the order of the SNPs within a
+// clade does not matter. The result lists the old and new TMRCA
+// of each matching clade and marks new values that lie outside
+// of the old confidence interval. Clades that exist in only one
+// of the trees are listed separately.
+func Diff(oldTree, newTree *Clade) string {
+	// Index of the old clades by lower case SNP names.
+	oldClades := make(map[string]*Clade)
+	oldTree.Walk(func(path []*Clade, c *Clade) error {
+		for _, snp := range c.SNPs {
+			oldClades[strings.ToLower(snp)] = c
+		}
+		return nil
+	})
+
+	var buffer bytes.Buffer
+	var added []string
+	matched := make(map[*Clade]bool)
+	newTree.Walk(func(path []*Clade, c *Clade) error {
+		var old *Clade
+		for _, snp := range c.SNPs {
+			if old = oldClades[strings.ToLower(snp)]; old != nil {
+				break
+			}
+		}
+		if old == nil {
+			added = append(added, c.SNPs[0])
+			return nil
+		}
+		matched[old] = true
+		buffer.WriteString(fmt.Sprintf("%s: TMRCA: %s -> %s", c.SNPs[0], diffAge(old.InputAges), diffAge(c.InputAges)))
+		if old.InputAges != nil && c.InputAges != nil {
+			oldAges, newAges := old.InputAges, c.InputAges
+			buffer.WriteString(fmt.Sprintf(" (%+.0f)", newAges.TMRCA-oldAges.TMRCA))
+			if oldAges.TMRCAlower >= 0 && oldAges.TMRCAupper >= 0 &&
+				(newAges.TMRCA < oldAges.TMRCAlower || newAges.TMRCA > oldAges.TMRCAupper) {
+				buffer.WriteString(", outside confidence interval")
+			}
+		}
+		buffer.WriteString("\r\n")
+		return nil
+	})
+
+	var removed []string
+	oldTree.Walk(func(path []*Clade, c *Clade) error {
+		if !matched[c] {
+			removed = append(removed, c.SNPs[0])
+		}
+		return nil
+	})
+
+	if len(added) > 0 {
+		buffer.WriteString("Added clades:\r\n")
+		for _, name := range added {
+			buffer.WriteString(name + "\r\n")
+		}
+	}
+	if len(removed) > 0 {
+		buffer.WriteString("Removed clades:\r\n")
+		for _, name := range removed {
+			buffer.WriteString(name + "\r\n")
+		}
+	}
+	return buffer.String()
+}
+
+// diffAge returns the TMRCA of ages as text for Diff.
+func diffAge(ages *Ages) string {
+	if ages == nil || ages.TMRCA < 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%.0f", ages.TMRCA)
+}
@@ -0,0 +1,76 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DOT returns this tree as a directed graph in the DOT language
+// of Graphviz. Clades are drawn as boxes, labeled by their first
+// SNPs and TMRCAs. Samples are drawn as ellipses, labeled by their
+// IDs. Clades without TMRCA are drawn dashed. The edges are labeled
+// by the STR counts of the children.
+func (c *Clade) DOT() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph phylotree {\r\n")
+	buffer.WriteString("\trankdir=LR;\r\n")
+	n := 0
+	c.dotPrint(&buffer, &n)
+	buffer.WriteString("}\r\n")
+	return buffer.String()
+}
+
+// dotPrint writes the nodes and edges of this clade and all of it's
+// descendants into buffer. n is the number of nodes so far, which
+// is used to create unique node names. The result is the node
+// name of this clade.
+func (c *Clade) dotPrint(buffer *bytes.Buffer, n *int) string {
+	name := dotNode(n)
+	label := c.SNPs[0]
+	style := "solid"
+	if c.STRCountDownstream >= 0 {
+		label += fmt.Sprintf("\nTMRCA: %.0f", c.TMRCA_STR)
+	} else {
+		style = "dashed"
+	}
+	buffer.WriteString(fmt.Sprintf("\t%s [shape=box, style=%s, label=%s];\r\n", name, style, dotQuote(label)))
+	for i, _ := range c.Samples {
+		sample := dotNode(n)
+		buffer.WriteString(fmt.Sprintf("\t%s [shape=ellipse, label=%s];\r\n", sample, dotQuote(c.Samples[i].ID)))
+		buffer.WriteString(dotEdge(name, sample, c.Samples[i].STRCount))
+	}
+	for i, _ := range c.Subclades {
+		subclade := c.Subclades[i].dotPrint(buffer, n)
+		buffer.WriteString(dotEdge(name, subclade, c.Subclades[i].STRCount))
+	}
+	if !c.collapsed.isEmpty() {
+		collapsed := dotNode(n)
+		buffer.WriteString(fmt.Sprintf("\t%s [shape=note, style=dashed, label=%s];\r\n", collapsed, dotQuote(c.collapsed.summary())))
+		buffer.WriteString(dotEdge(name, collapsed, Uncertain))
+	}
+	return name
+}
+
+// dotNode returns a new unique node name.
+func dotNode(n *int) string {
+	name := fmt.Sprintf("n%d", *n)
+	*n++
+	return name
+}
+
+// dotEdge returns an edge from parent to child, labeled by strCount.
+func dotEdge(parent, child string, strCount float64) string {
+	if strCount < 0 {
+		return fmt.Sprintf("\t%s -> %s;\r\n", parent, child)
+	}
+	return fmt.Sprintf("\t%s -> %s [label=\"%.0f\"];\r\n", parent, child, strCount)
+}
+
+// dotQuote returns text as a quoted DOT string.
+func dotQuote(text string) string {
+	text = strings.Replace(text, "\\", "\\\\", -1)
+	text = strings.Replace(text, "\"", "\\\"", -1)
+	text = strings.Replace(text, "\n", "\\n", -1)
+	return "\"" + text + "\""
+}
@@ -0,0 +1,83 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// samplePosition is a sample together with the clade that contains it.
+type samplePosition struct {
+	sample *Sample
+	clade  *Clade
+}
+
+// String returns the clade and the line number of the sample.
+func (p samplePosition) String() string {
+	if p.sample.lineNo > 0 {
+		return fmt.Sprintf("%s line %d", p.clade.SNPs[0], p.sample.lineNo)
+	}
+	return p.clade.SNPs[0]
+}
+
+// samplePositions adds all samples of this clade and it's subclades
+// to positions. The keys are the sample IDs. ids contains the IDs
+// in the order of their first appearance.
+func (c *Clade) samplePositions(positions map[string][]samplePosition, ids *[]string) {
+	for i, _ := range c.Samples {
+		id := c.Samples[i].ID
+		if _, exists := positions[id]; !exists {
+			*ids = append(*ids, id)
+		}
+		positions[id] = append(positions[id], samplePosition{sample: &c.Samples[i], clade: c})
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].samplePositions(positions, ids)
+	}
+}
+
+// ResolveDuplicates checks for samples that are listed multiple times
+// in the tree. A sample may be listed once as confirmed sample and
+// additionally as tentative sample in other places. Tentative samples
+// are excluded from all calculations.
+// The result is a report that lists all tentative samples together
+// with the clade of their confirmed counterpart. If a sample is listed
+// more than once without being tentative, an error is returned.
+func (c *Clade) ResolveDuplicates() (string, error) {
+	positions := make(map[string][]samplePosition)
+	var ids []string
+	c.samplePositions(positions, &ids)
+
+	var buffer bytes.Buffer
+	var duplicates []string
+	for _, id := range ids {
+		var confirmed []string
+		var confirmedLines []string
+		var tentative []string
+		for _, pos := range positions[id] {
+			if pos.sample.Tentative {
+				tentative = append(tentative, pos.clade.SNPs[0])
+			} else {
+				confirmed = append(confirmed, pos.clade.SNPs[0])
+				confirmedLines = append(confirmedLines, pos.String())
+			}
+		}
+		if len(confirmed) > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("%s (%s)", id, strings.Join(confirmedLines, ", ")))
+			continue
+		}
+		if len(tentative) > 0 {
+			confirmedClade := "none"
+			if len(confirmed) == 1 {
+				confirmedClade = confirmed[0]
+			}
+			buffer.WriteString(fmt.Sprintf("id:%s, confirmed: %s, tentative: %s\r\n",
+				id, confirmedClade, strings.Join(tentative, ", ")))
+		}
+	}
+	if len(duplicates) > 0 {
+		return buffer.String(), errors.New("duplicate samples: " + strings.Join(duplicates, "; "))
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,60 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+func TestResolveDuplicates(t *testing.T) {
+	tests := []struct {
+		name   string
+		tree   string
+		report string
+		err    string
+	}{
+		{
+			name:   "tentative duplicate",
+			tree:   "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:1, tentative\r\n",
+			report: "id:1, confirmed: A, tentative: B\r\n",
+		},
+		{
+			name:   "tentative only",
+			tree:   "R\r\n\tA\r\n\t\tid:1, tentative\r\n\t\tid:2\r\n",
+			report: "id:1, confirmed: none, tentative: A\r\n",
+		},
+		{
+			name: "confirmed duplicate",
+			tree: "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:1\r\n",
+			err:  "duplicate samples: 1 (A line 3, B line 6)",
+		},
+	}
+	for _, test := range tests {
+		report, err := mustParse(t, test.tree).ResolveDuplicates()
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s: error = %v, want %s", test.name, err, test.err)
+		case report != test.report:
+			t.Errorf("%s: report = %q, want %q", test.name, report, test.report)
+		}
+	}
+}
+
+// TestTentativeExcluded checks that tentative samples are not used
+// for calculations and that they keep their marker in the output.
+func TestTentativeExcluded(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\tB\r\n\t\tid:2\r\n\t\tid:1, tentative\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		newPerson(t, "1", map[string]float64{"DYS393": 13}),
+		newPerson(t, "2", map[string]float64{"DYS393": 14}),
+	})
+	if persons := tree.Subclade("B").SamplePersons(); len(persons) != 1 || persons[0].ID != "2" {
+		t.Errorf("persons of B = %v, want only 2", persons)
+	}
+	if !strings.Contains(tree.String(), "id:1, tentative") {
+		t.Errorf("tentative marker missing:\n%s", tree.String())
+	}
+}
@@ -0,0 +1,72 @@
+package phylotree
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// utf8BOM is the byte order mark of UTF-8 files.
+const utf8BOM = "\xef\xbb\xbf"
+
+// decodeText converts the content of a tree file into a string.
+// A leading byte order mark is removed. UTF-16 files are detected
+// by their byte order marks or by zero bytes and are decoded.
+// Unicode white space, for example non-breaking spaces, is
+// replaced by ordinary spaces and zero width characters
+// are removed.
+func decodeText(content []byte) string {
+	var text string
+	switch {
+	case len(content) >= 2 && content[0] == 0xff && content[1] == 0xfe:
+		text = decodeUTF16(content[2:], false)
+	case len(content) >= 2 && content[0] == 0xfe && content[1] == 0xff:
+		text = decodeUTF16(content[2:], true)
+	case len(content) >= 2 && content[0] != 0 && content[1] == 0:
+		text = decodeUTF16(content, false)
+	case len(content) >= 2 && content[0] == 0 && content[1] != 0:
+		text = decodeUTF16(content, true)
+	default:
+		text = strings.TrimPrefix(string(content), utf8BOM)
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case isZeroWidth(r):
+			return -1
+		case r != '\t' && r != '\n' && r != '\r' && unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// decodeUTF16 decodes UTF-16 text. isBigEndian specifies the byte order.
+func decodeUTF16(content []byte, isBigEndian bool) string {
+	codes := make([]uint16, len(content)/2)
+	for i, _ := range codes {
+		if isBigEndian {
+			codes[i] = uint16(content[2*i])<<8 | uint16(content[2*i+1])
+		} else {
+			codes[i] = uint16(content[2*i+1])<<8 | uint16(content[2*i])
+		}
+	}
+	return string(utf16.Decode(codes))
+}
+
+// isZeroWidth returns true for invisible characters that are often
+// copied from web pages, including byte order marks.
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\ufeff', '\u200b', '\u200c', '\u200d', '\u2060':
+		return true
+	}
+	return false
+}
+
+// trimName removes white space and zero width characters from the
+// beginning and the end of an SNP name or search term.
+func trimName(name string) string {
+	return strings.TrimFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || isZeroWidth(r)
+	})
+}
@@ -0,0 +1,45 @@
+package phylotree
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestEncodings reads trees with byte order marks, in UTF-16 and
+// with non-breaking spaces and zero width characters inside SNP
+// names. All clades must be found by their SNP names.
+func TestEncodings(t *testing.T) {
+	files := []string{
+		"bom_utf8.txt",
+		"bom_utf16le.txt",
+		"bom_utf16be.txt",
+		"special_chars.txt",
+	}
+	for _, file := range files {
+		tree, err := NewFromFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if tree.SNPs[0] != "R-L23" {
+			t.Errorf("%s: root is named %q, want R-L23", file, tree.SNPs[0])
+		}
+		for _, names := range [][]string{{"R-L23"}, {"Z2103", "Z2105"}, {"L584"}} {
+			for _, name := range names {
+				clade := tree.Subclade(name)
+				if clade == nil {
+					t.Errorf("%s: Subclade(%s) not found", file, name)
+				} else if clade.SNPs[0] != names[0] {
+					t.Errorf("%s: Subclade(%s) = %q, want %s", file, name, clade.SNPs[0], names[0])
+				}
+				clade, err = tree.FindSubclade(name)
+				if err != nil || clade == nil || clade.SNPs[0] != names[0] {
+					t.Errorf("%s: FindSubclade(%s) = %v, %v", file, name, clade, err)
+				}
+			}
+		}
+		if l584 := tree.Subclade("L584"); l584 == nil || len(l584.Samples) != 1 || l584.Samples[0].STRCount != 12 {
+			t.Errorf("%s: sample id:2 not found in L584", file)
+		}
+	}
+}
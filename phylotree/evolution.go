@@ -0,0 +1,133 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// changeMark marks values that have changed compared to the
+// previous row of the evolution table.
+const changeMark = "*"
+
+// evolutionRow is a row of the evolution table.
+type evolutionRow struct {
+	name   string
+	person *genetic.Person
+	// previous is the haplotype that is used to detect changes.
+	previous *genetic.Person
+}
+
+// Evolution returns a table that shows the evolution of the modal
+// haplotypes from the root of the tree down to the clade named
+// cladeName. The rows are the ancestor clades, the columns are the
+// markers specified by STRs. If STRs is empty, all markers are
+// shown that change along the path. If withSamples is true, the
+// samples of the clade are added as rows.
+// Values that differ from the row above are marked by a *.
+// The result is returned as aligned text and in CSV format.
+func (c *Clade) Evolution(cladeName string, STRs []string, withSamples bool) (text, table string, err error) {
+	path := c.pathTo(cladeName)
+	if path == nil || !path[len(path)-1].contains(cladeName) {
+		return "", "", errors.New(fmt.Sprintf("could not find clade %s", cladeName))
+	}
+
+	// Create rows.
+	var rows []evolutionRow
+	var previous *genetic.Person
+	for _, clade := range path {
+		if clade.Person != nil {
+			rows = append(rows, evolutionRow{name: clade.SNPs[0], person: clade.Person, previous: previous})
+			previous = clade.Person
+		}
+	}
+	if withSamples {
+		clade := path[len(path)-1]
+		for i, _ := range clade.Samples {
+			if clade.Samples[i].Person != nil {
+				rows = append(rows, evolutionRow{
+					name:     clade.Samples[i].ID,
+					person:   clade.Samples[i].Person,
+					previous: clade.Person})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return "", "", errors.New("no haplotypes available")
+	}
+
+	// Select markers.
+	var markers []int
+	for _, str := range STRs {
+		index := MarkerIndex(str)
+		if index < 0 {
+			return "", "", errors.New(fmt.Sprintf("unknown marker %s", str))
+		}
+		markers = append(markers, index)
+	}
+	if len(STRs) == 0 {
+		for i, _ := range rows[0].person.YstrMarkers {
+			for _, row := range rows {
+				if isChanged(row, i) {
+					markers = append(markers, i)
+					break
+				}
+			}
+		}
+	}
+
+	// Create table.
+	records := make([][]string, 0, len(rows)+1)
+	header := []string{"Name"}
+	for _, marker := range markers {
+		header = append(header, genetic.YstrMarkerTable[marker].InternalName)
+	}
+	records = append(records, header)
+	for _, row := range rows {
+		record := []string{row.name}
+		for _, marker := range markers {
+			value := fmt.Sprintf("%g", row.person.YstrMarkers[marker])
+			if isChanged(row, marker) {
+				value += changeMark
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+
+	// Write text.
+	var textBuffer bytes.Buffer
+	tw := tabwriter.NewWriter(&textBuffer, 0, 4, 2, ' ', 0)
+	for _, record := range records {
+		for _, field := range record {
+			fmt.Fprintf(tw, "%s\t", field)
+		}
+		fmt.Fprintf(tw, "\r\n")
+	}
+	tw.Flush()
+
+	// Write CSV.
+	var csvBuffer bytes.Buffer
+	writer := csv.NewWriter(&csvBuffer)
+	writer.UseCRLF = true
+	writer.WriteAll(records)
+	if err := writer.Error(); err != nil {
+		return "", "", err
+	}
+	return textBuffer.String(), csvBuffer.String(), nil
+}
+
+// isChanged returns true if the value of marker differs
+// from the previous haplotype of the row.
+func isChanged(row evolutionRow, marker int) bool {
+	if row.previous == nil {
+		return false
+	}
+	value := row.person.YstrMarkers[marker]
+	previous := row.previous.YstrMarkers[marker]
+	return value > 0 && previous > 0 && value != previous
+}
@@ -0,0 +1,35 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// excludedMark marks excluded samples in the text output.
+// It is also accepted as input instead of "exclude".
+const excludedMark = "[excluded]"
+
+// ExclusionReport returns a list of all excluded samples
+// together with their clades and the reasons for exclusion.
+func (c *Clade) ExclusionReport() string {
+	var buffer bytes.Buffer
+	c.exclusionPrint(&buffer)
+	return buffer.String()
+}
+
+// exclusionPrint creates the list for ExclusionReport.
+func (c *Clade) exclusionPrint(buffer *bytes.Buffer) {
+	for i, _ := range c.Samples {
+		sample := &c.Samples[i]
+		if sample.Excluded {
+			reason := sample.ExcludeReason
+			if reason == "" {
+				reason = "no reason given"
+			}
+			buffer.WriteString(fmt.Sprintf("id:%s, clade: %s, excluded: %s\r\n", sample.ID, c.SNPs[0], reason))
+		}
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].exclusionPrint(buffer)
+	}
+}
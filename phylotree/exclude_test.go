@@ -0,0 +1,43 @@
+package phylotree
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestExcludedStatistics checks that the values of an excluded
+// sample do not show up in the marker statistics.
+func TestExcludedStatistics(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24}
+	persons := []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+		newPerson(t, "3", map[string]float64{"DYS393": 20, "DYS390": 30}),
+	}
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\t\tid:3, exclude: suspected NPE\r\n")
+	tree.InsertPersons(persons)
+	statistics, n := tree.Statistics()
+	if n != 2 {
+		t.Errorf("number of persons = %d, want 2", n)
+	}
+	want := map[string]map[float64]int{
+		"DYS393": {13: 1, 14: 1},
+		"DYS390": {24: 2},
+	}
+	for name, occurrences := range want {
+		got := statistics.Markers[mustIndex(t, name)].ValuesOccurrences
+		if len(got) != len(occurrences) {
+			t.Errorf("%s: values occurrences = %v, want %v", name, got, occurrences)
+			continue
+		}
+		for value, count := range occurrences {
+			if got[value] != count {
+				t.Errorf("%s: values occurrences = %v, want %v", name, got, occurrences)
+			}
+		}
+	}
+	if report := tree.ExclusionReport(); report != "id:3, clade: A, excluded: suspected NPE\r\n" {
+		t.Errorf("exclusion report = %q", report)
+	}
+}
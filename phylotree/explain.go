@@ -0,0 +1,69 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// ExplainModal explains how the modal haplotype of the clade named
+// cladeName is calculated by the parsimony method. The calculation
+// is repeated for the processing stages 1, 2 and 4 on copies of
+// this tree. The result is a table that contains for each marker
+// the values of the clade's children, the results of the stages
+// and the closest real world value to the stage 2 result.
+// The parameters are the same as for CalculateModalHaplotypesParsimony.
+func (c *Clade) ExplainModal(cladeName string, statistics *genetic.MarkerStatistics, isInfiniteAlleles bool, limit StepLimit, minSupport int) (string, error) {
+	// Calculate modal haplotypes for each stage.
+	stages := []int{1, 2, 4}
+	clades := make([]*Clade, len(stages))
+	for i, stage := range stages {
+		tree := c.Clone()
+		tree.CalculateModalHaplotypesParsimonyLimited(statistics, stage, isInfiniteAlleles, limit, minSupport)
+		clade, err := tree.FindSubclade(cladeName)
+		if err != nil {
+			return "", err
+		}
+		clades[i] = clade
+	}
+	stage1, stage2, stage4 := clades[0], clades[1], clades[2]
+
+	var buffer bytes.Buffer
+	tw := tabwriter.NewWriter(&buffer, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Marker\tChildren\tStage 1\tStage 2\tClosest\tFinal\t\r\n")
+	for marker, _ := range stage1.Person.YstrMarkers {
+		// Values of the children as seen by the maximum parsimony stage.
+		var children []string
+		for i, _ := range stage1.Samples {
+			if stage1.Samples[i].hasInfluence() {
+				if value := stage1.Samples[i].Person.YstrMarkers[marker]; value != 0 {
+					children = append(children, fmt.Sprintf("%g", value))
+				}
+			}
+		}
+		for i, _ := range stage1.Subclades {
+			if value := stage1.Subclades[i].Person.YstrMarkers[marker]; value != 0 {
+				children = append(children, fmt.Sprintf("%g", value))
+			}
+		}
+		final := stage4.Person.YstrMarkers[marker]
+		if len(children) == 0 && final == 0 {
+			continue
+		}
+		average := stage2.Person.YstrMarkers[marker]
+		closest, isUnique := closestKey(average, statistics.Markers[marker].ValuesOccurrences)
+		closestStr := fmt.Sprintf("%g", closest)
+		if !isUnique {
+			closestStr += " (not unique)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%g\t%.2f\t%s\t%g\t\r\n",
+			genetic.YstrMarkerTable[marker].InternalName,
+			strings.Join(children, " "),
+			stage1.Person.YstrMarkers[marker], average, closestStr, final)
+	}
+	tw.Flush()
+	return buffer.String(), nil
+}
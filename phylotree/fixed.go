@@ -0,0 +1,114 @@
+package phylotree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// parseFixedValue parses a fixed marker value from a clade
+// annotation. Format: DYS393=13
+// It returns the index of the marker and it's value.
+func parseFixedValue(text string) (marker int, value float64, err error) {
+	parts := strings.Split(text, "=")
+	if len(parts) != 2 {
+		return -1, 0, errors.New(fmt.Sprintf("invalid fixed value: %s", text))
+	}
+	marker = MarkerIndex(parts[0])
+	if marker < 0 {
+		return -1, 0, errors.New(fmt.Sprintf("unknown marker in fixed value: %s", parts[0]))
+	}
+	value, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || value <= 0 {
+		return -1, 0, errors.New(fmt.Sprintf("invalid marker value in fixed value: %s", parts[1]))
+	}
+	return marker, value, nil
+}
+
+// fixedMarkers returns the indices of all fixed marker values
+// of this clade in ascending order.
+func (c *Clade) fixedMarkers() []int {
+	markers := make([]int, 0, len(c.FixedValues))
+	for marker, _ := range c.FixedValues {
+		markers = append(markers, marker)
+	}
+	sort.Ints(markers)
+	return markers
+}
+
+// fixedString returns the fixed values of this clade in
+// the same format that is used in the input tree.
+func (c *Clade) fixedString() string {
+	var result string
+	for _, marker := range c.fixedMarkers() {
+		name := genetic.YstrMarkerTable[marker].InternalName
+		result += fmt.Sprintf(", fix:%s=%g", name, c.FixedValues[marker])
+	}
+	return result
+}
+
+// applyFixedValues sets the fixed marker values in the modal
+// haplotype of this clade and all subclades.
+func (c *Clade) applyFixedValues() {
+	c.applyOwnFixedValues()
+	for i, _ := range c.Subclades {
+		c.Subclades[i].applyFixedValues()
+	}
+}
+
+// applyOwnFixedValues sets the fixed marker values in the modal
+// haplotype of this clade. Subclades are not changed.
+func (c *Clade) applyOwnFixedValues() {
+	if c.Person == nil {
+		return
+	}
+	for marker, value := range c.FixedValues {
+		c.Person.YstrMarkers[marker] = value
+	}
+}
+
+// FixedValueConflicts returns a list of fixed marker values
+// that are contradicted by the majority of the clade's children.
+// Children are samples and subclades that have a value for the marker.
+func (c *Clade) FixedValueConflicts() []string {
+	var result []string
+	for _, marker := range c.fixedMarkers() {
+		fixed := c.FixedValues[marker]
+		differ := 0
+		total := 0
+		for i, _ := range c.Samples {
+			if c.Samples[i].hasInfluence() {
+				if value := c.Samples[i].Person.YstrMarkers[marker]; value > 0 {
+					total++
+					if value != fixed {
+						differ++
+					}
+				}
+			}
+		}
+		for i, _ := range c.Subclades {
+			if c.Subclades[i].Person != nil {
+				if value := c.Subclades[i].Person.YstrMarkers[marker]; value > 0 {
+					total++
+					if value != fixed {
+						differ++
+					}
+				}
+			}
+		}
+		if 2*differ > total {
+			name := genetic.YstrMarkerTable[marker].InternalName
+			result = append(result,
+				fmt.Sprintf("%s: fixed value %s=%g differs from %d of %d children",
+					c.SNPs[0], name, fixed, differ, total))
+		}
+	}
+	for i, _ := range c.Subclades {
+		result = append(result, c.Subclades[i].FixedValueConflicts()...)
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package phylotree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Graft attaches the tree sub as a new subclade to the clade
+// whose SNPs contain target. An error is returned if the target
+// clade is not found or ambiguous, or if an SNP of sub already
+// appears in this tree.
+func (c *Clade) Graft(target string, sub *Clade) error {
+	clade, err := c.FindSubclade(target)
+	if err != nil {
+		return errors.New(fmt.Sprintf("graft target, %v", err))
+	}
+	snps := make(map[string]string)
+	c.addSNPs(snps)
+	if snp, exists := sub.duplicateSNP(snps); exists {
+		return errors.New(fmt.Sprintf("SNP %s of grafted tree is already part of the tree", snp))
+	}
+	clade.AttachSubclade(sub)
+	return nil
+}
@@ -0,0 +1,64 @@
+package phylotree
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// gzipFile is a file that is decompressed while reading.
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close closes the decompressor and the file.
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// bufferedFile is a file that is read by a buffered reader.
+type bufferedFile struct {
+	*bufio.Reader
+	file *os.File
+}
+
+// Close closes the file.
+func (b *bufferedFile) Close() error {
+	return b.file.Close()
+}
+
+// OpenFile opens a file for reading. Files that are compressed
+// by gzip are detected by their first bytes and decompressed
+// on the fly.
+func OpenFile(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(file)
+	magic, _ := reader.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &gzipFile{Reader: gz, file: file}, nil
+	}
+	return &bufferedFile{Reader: reader, file: file}, nil
+}
+
+// IsGzipFile returns true if the file is compressed by gzip.
+func IsGzipFile(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	magic := make([]byte, 2)
+	n, _ := io.ReadFull(file, magic)
+	return n == 2 && magic[0] == 0x1f && magic[1] == 0x8b, nil
+}
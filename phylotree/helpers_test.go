@@ -0,0 +1,49 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// mustParse parses a tree in text format or stops the test.
+func mustParse(t *testing.T, text string) *Clade {
+	t.Helper()
+	tree, err := NewFromReader(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parsing tree, %v", err)
+	}
+	return tree
+}
+
+// mustIndex returns the index of the marker name or stops the test.
+func mustIndex(t *testing.T, name string) int {
+	t.Helper()
+	index := MarkerIndex(name)
+	if index < 0 {
+		t.Fatalf("unknown marker %s", name)
+	}
+	return index
+}
+
+// newPerson creates a person with the marker values in values.
+// The keys are marker names.
+func newPerson(t *testing.T, id string, values map[string]float64) *genetic.Person {
+	t.Helper()
+	person := &genetic.Person{ID: id, Name: id, Label: id}
+	for name, value := range values {
+		person.YstrMarkers[mustIndex(t, name)] = value
+	}
+	return person
+}
+
+// withValue returns a copy of values with the marker name set to value.
+func withValue(values map[string]float64, name string, value float64) map[string]float64 {
+	result := make(map[string]float64, len(values)+1)
+	for k, v := range values {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
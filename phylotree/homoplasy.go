@@ -0,0 +1,79 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// MarkerHomoplasy contains the statistics of the mutation events
+// of a single marker in the whole tree.
+type MarkerHomoplasy struct {
+	// Marker is the index of the marker.
+	Marker int
+	// Events is the number of branches on which the marker mutates.
+	Events int
+	// Steps is the total number of mutation steps.
+	Steps float64
+	// Clades is the number of distinct parent clades below which
+	// the marker mutates. Values larger than 1 indicate parallel
+	// mutations on independent lineages.
+	Clades int
+	// Reversals is the number of events that return to an
+	// earlier ancestral value.
+	Reversals int
+}
+
+// Homoplasy returns the mutation statistics of all markers that
+// mutate somewhere in the tree. The statistics are calculated from
+// the events returned by Mutations. The result is sorted by the
+// number of events, the most frequent first.
+// The modal haplotypes must be calculated before.
+func (c *Clade) Homoplasy() []MarkerHomoplasy {
+	stats := make(map[int]*MarkerHomoplasy)
+	parents := make(map[int]map[string]bool)
+	for _, m := range c.Mutations() {
+		if m.IsUnknown {
+			continue
+		}
+		stat, exists := stats[m.Marker]
+		if !exists {
+			stat = &MarkerHomoplasy{Marker: m.Marker}
+			stats[m.Marker] = stat
+			parents[m.Marker] = make(map[string]bool)
+		}
+		stat.Events++
+		stat.Steps += math.Abs(m.Steps)
+		if m.IsReversal {
+			stat.Reversals++
+		}
+		parents[m.Marker][m.Parent] = true
+	}
+	result := make([]MarkerHomoplasy, 0, len(stats))
+	for marker, stat := range stats {
+		stat.Clades = len(parents[marker])
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Events != result[j].Events {
+			return result[i].Events > result[j].Events
+		}
+		return result[i].Marker < result[j].Marker
+	})
+	return result
+}
+
+// HomoplasyReport returns the statistics of Homoplasy,
+// one marker per line.
+func (c *Clade) HomoplasyReport() string {
+	var buffer bytes.Buffer
+	for _, stat := range c.Homoplasy() {
+		name := genetic.YstrMarkerTable[stat.Marker].InternalName
+		buffer.WriteString(fmt.Sprintf("%s: events: %d, steps: %g, clades: %d, reversals: %d\r\n",
+			name, stat.Events, stat.Steps, stat.Clades, stat.Reversals))
+	}
+	return buffer.String()
+}
@@ -0,0 +1,62 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// htmlStyle is the style sheet for HTMLTree.
+const htmlStyle = `body { font-family: sans-serif; }
+ul { list-style-type: none; padding-left: 1.5em; }
+summary { cursor: pointer; }
+.snps { font-weight: bold; }
+.ages { color: #444; }
+.uncertain { color: #a00; font-style: italic; }
+.sample { color: #036; }
+.collapsed { color: #888; font-style: italic; }`
+
+// HTMLTree returns this tree as a self-contained HTML page.
+// Clades are shown as nested collapsible lists with their SNPs
+// and time estimates. Samples are shown with their IDs and
+// STR counts. Clades without time estimates are marked.
+func (c *Clade) HTMLTree() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("<!DOCTYPE html>\r\n<html>\r\n<head>\r\n<meta charset=\"UTF-8\">\r\n")
+	buffer.WriteString(fmt.Sprintf("<title>%s</title>\r\n", html.EscapeString(c.SNPs[0])))
+	buffer.WriteString("<style>\r\n" + htmlStyle + "\r\n</style>\r\n")
+	buffer.WriteString("</head>\r\n<body>\r\n<ul>\r\n")
+	c.htmlPrint(&buffer)
+	buffer.WriteString("</ul>\r\n</body>\r\n</html>\r\n")
+	return buffer.String()
+}
+
+// htmlPrint writes this clade and all of it's descendants
+// as list items into buffer.
+func (c *Clade) htmlPrint(buffer *bytes.Buffer) {
+	buffer.WriteString("<li><details open><summary>")
+	buffer.WriteString(fmt.Sprintf("<span class=\"snps\">%s</span>", html.EscapeString(strings.Join(c.SNPs, ", "))))
+	if c.STRCountDownstream >= 0 {
+		buffer.WriteString(fmt.Sprintf(" <span class=\"ages\">formed: %.0f, TMRCA: %.0f, CI:[%.0f, %.0f]</span>",
+			c.AgeSTR, c.TMRCA_STR, c.TMRCAlower, c.TMRCAupper))
+	} else {
+		buffer.WriteString(" <span class=\"uncertain\">age unknown</span>")
+	}
+	buffer.WriteString("</summary>\r\n<ul>\r\n")
+	for i, _ := range c.Samples {
+		sample := &c.Samples[i]
+		buffer.WriteString(fmt.Sprintf("<li class=\"sample\">id:%s", html.EscapeString(sample.ID)))
+		if sample.STRCount >= 0 {
+			buffer.WriteString(fmt.Sprintf(", STR-Count: %.0f", sample.STRCount))
+		}
+		buffer.WriteString("</li>\r\n")
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].htmlPrint(buffer)
+	}
+	if !c.collapsed.isEmpty() {
+		buffer.WriteString(fmt.Sprintf("<li class=\"collapsed\">%s</li>\r\n", html.EscapeString(c.collapsed.summary())))
+	}
+	buffer.WriteString("</ul>\r\n</details></li>\r\n")
+}
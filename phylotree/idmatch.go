@@ -0,0 +1,62 @@
+package phylotree
+
+import (
+	"strings"
+)
+
+// IDMatch specifies how the IDs of persons and samples
+// are compared.
+type IDMatch struct {
+	// Normalized specifies that IDs are compared after removing
+	// white space, leading zeros and prefixes and ignoring case.
+	// Otherwise IDs must be identical.
+	Normalized bool
+	// Prefixes are removed from the beginning of normalized IDs,
+	// for example "kit" or "#". Case is ignored.
+	Prefixes []string
+}
+
+// DefaultIDMatch compares normalized IDs without removing prefixes.
+var DefaultIDMatch = IDMatch{Normalized: true}
+
+// ExactIDMatch compares IDs exactly.
+var ExactIDMatch = IDMatch{Normalized: false}
+
+// normalize returns the normalized form of id.
+func (m IDMatch) normalize(id string) string {
+	if !m.Normalized {
+		return id
+	}
+	result := strings.ToLower(trimName(id))
+	for _, prefix := range m.Prefixes {
+		prefix = strings.ToLower(trimName(prefix))
+		if prefix != "" && strings.HasPrefix(result, prefix) {
+			result = trimName(result[len(prefix):])
+			break
+		}
+	}
+	// Spreadsheet programs often remove leading zeros.
+	if trimmed := strings.TrimLeft(result, "0"); trimmed != "" {
+		result = trimmed
+	}
+	return result
+}
+
+// FindSampleMatching returns the sample whose ID matches id
+// and the clade that contains it. If the sample is not found,
+// the results are nil. If the ID appears multiple times, the
+// first sample is returned.
+func (c *Clade) FindSampleMatching(id string, match IDMatch) (*Sample, *Clade) {
+	var sample *Sample
+	var parent *Clade
+	id = match.normalize(id)
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		if match.normalize(s.ID) == id {
+			sample = s
+			parent = path[len(path)-1]
+			return errFound
+		}
+		return nil
+	})
+	return sample, parent
+}
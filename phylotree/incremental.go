@@ -0,0 +1,128 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ApplyPrevious compares this tree with a previously calculated tree
+// and marks the changed clades. A clade is changed if it is new, if
+// it's samples or subclades are different from the previous tree, if
+// the STR counts of it's samples or subclades differ from the previous
+// tree or if one of it's subclades is changed. Thus all clades on the
+// path from a changed sample up to the root are changed.
+// The STR counts of samples with new Y-STR values differ from the
+// previous tree, so ApplyPrevious must be called after the distances
+// have been calculated and before the ages are calculated.
+// CalculateAge keeps the time estimates of the previous tree for all
+// unchanged clades, so that their results stay exactly the same.
+// Changed clades are newly calculated from the samples and the kept
+// results of their unchanged subclades.
+func (c *Clade) ApplyPrevious(previous *Clade) {
+	index := make(map[string]*Clade)
+	previous.indexClades(index)
+	c.markChanged(index)
+}
+
+// indexClades adds this clade and all subclades to index.
+// The keys are the lower case names of the first SNPs.
+func (c *Clade) indexClades(index map[string]*Clade) {
+	index[strings.ToLower(c.SNPs[0])] = c
+	for i, _ := range c.Subclades {
+		c.Subclades[i].indexClades(index)
+	}
+}
+
+// markChanged sets isChanged for this clade and all subclades
+// by comparing them with the clades of a previous tree.
+// The ages of the previous clades are stored in previousAges.
+// The result is true if this clade is changed.
+func (c *Clade) markChanged(previous map[string]*Clade) bool {
+	c.isChanged = false
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].markChanged(previous) {
+			c.isChanged = true
+		}
+	}
+	prev, exists := previous[strings.ToLower(c.SNPs[0])]
+	c.previousAges = nil
+	if exists {
+		c.previousAges = prev.InputAges
+	}
+	switch {
+	case !exists:
+		c.isChanged = true
+	case len(prev.Samples) != len(c.Samples) || len(prev.Subclades) != len(c.Subclades):
+		c.isChanged = true
+	default:
+		for i, _ := range c.Samples {
+			if !c.Samples[i].equals(&prev.Samples[i]) {
+				c.isChanged = true
+			}
+		}
+		for i, _ := range c.Subclades {
+			subclade, prevSubclade := c.Subclades[i], prev.Subclades[i]
+			if !strings.EqualFold(subclade.SNPs[0], prevSubclade.SNPs[0]) ||
+				!sameCount(subclade.STRCount, prevSubclade.STRCount) {
+				c.isChanged = true
+			}
+		}
+	}
+	return c.isChanged
+}
+
+// equals returns true if this sample has the same ID, the same
+// settings and, as far as the tree shows, the same STR count
+// as other.
+func (s *Sample) equals(other *Sample) bool {
+	return s.ID == other.ID && s.Weight == other.Weight &&
+		s.Sampled == other.Sampled && s.isExcluded() == other.isExcluded() &&
+		sameCount(s.STRCount, other.STRCount)
+}
+
+// sameCount returns true if two STR counts are the same with
+// the precision of the text output.
+func sameCount(a, b float64) bool {
+	if a < 0 || b < 0 {
+		return a < 0 && b < 0
+	}
+	return fmt.Sprintf("%.0f", a) == fmt.Sprintf("%.0f", b)
+}
+
+// keepPrevious sets the time estimates of this clade to the
+// values of the previous tree, if this clade has not changed
+// since the previous calculation. Sigma2 is not part of the tree
+// file, so it keeps the newly calculated value.
+func (c *Clade) keepPrevious() {
+	if c.isChanged || c.previousAges == nil || c.previousAges.STRCountDownstream < 0 {
+		return
+	}
+	ages := c.previousAges
+	c.STRCountDownstream = ages.STRCountDownstream
+	c.AgeSTR = ages.AgeSTR
+	c.TMRCA_STR = ages.TMRCA
+	c.TMRCAlower = ages.TMRCAlower
+	c.TMRCAupper = ages.TMRCAupper
+}
+
+// IncrementalReport returns a report of all clades that have been
+// marked as changed by ApplyPrevious and their differences in age.
+func (c *Clade) IncrementalReport() string {
+	var buffer bytes.Buffer
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if !clade.isChanged {
+			return nil
+		}
+		buffer.WriteString(fmt.Sprintf("Recalculated %s", clade.SNPs[0]))
+		prev := clade.previousAges
+		if prev != nil && prev.STRCountDownstream >= 0 && clade.STRCountDownstream >= 0 {
+			buffer.WriteString(fmt.Sprintf(", TMRCA: %.0f -> %.0f (%+.0f), formed: %.0f -> %.0f (%+.0f)",
+				prev.TMRCA, clade.TMRCA_STR, clade.TMRCA_STR-prev.TMRCA,
+				prev.AgeSTR, clade.AgeSTR, clade.AgeSTR-prev.AgeSTR))
+		}
+		buffer.WriteString("\r\n")
+		return nil
+	})
+	return buffer.String()
+}
@@ -0,0 +1,96 @@
+package phylotree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+const incrementalTree = "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n"
+
+// incrementalPersons returns the persons for incrementalTree.
+func incrementalPersons(t *testing.T) []*genetic.Person {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14, "DYS391": 10, "DYS439": 12}
+	return []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+		newPerson(t, "3", withValue(base, "DYS19", 15)),
+		newPerson(t, "4", withValue(base, "DYS390", 23)),
+	}
+}
+
+// calculateIncremental calculates tree like calculate, but keeps the
+// results of previous for unchanged clades.
+func calculateIncremental(t *testing.T, tree *Clade, persons []*genetic.Person, previous string) {
+	tree.InsertPersons(persons)
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(tree.SamplePersons()), 4, false)
+	tree.CalculateDistances(genetic.DefaultMutationRates(), genetic.DistanceHybrid)
+	tree.ApplyPrevious(mustParse(t, previous))
+	tree.CalculateAge(33, 1, 0)
+}
+
+func TestIncrementalNoChange(t *testing.T) {
+	persons := incrementalPersons(t)
+	first := mustParse(t, incrementalTree)
+	calculate(first, persons)
+	previous := first.String()
+
+	second := mustParse(t, incrementalTree)
+	calculateIncremental(t, second, persons, previous)
+	if got := second.String(); got != previous {
+		t.Errorf("tree changed:\n%s\nwant:\n%s", got, previous)
+	}
+	if report := second.IncrementalReport(); report != "" {
+		t.Errorf("recalculated clades: %q", report)
+	}
+}
+
+func TestIncrementalChangedSample(t *testing.T) {
+	persons := incrementalPersons(t)
+	first := mustParse(t, incrementalTree)
+	calculate(first, persons)
+	previous := first.String()
+
+	// Kit 3 has new Y-STR values.
+	changed := incrementalPersons(t)
+	changed[2] = newPerson(t, "3", map[string]float64{"DYS393": 15, "DYS390": 26, "DYS19": 17, "DYS391": 12, "DYS439": 12})
+	second := mustParse(t, incrementalTree)
+	calculateIncremental(t, second, changed, previous)
+
+	report := second.IncrementalReport()
+	for _, name := range []string{"R", "B"} {
+		if !strings.Contains(report, "Recalculated "+name) {
+			t.Errorf("%s has not been recalculated: %q", name, report)
+		}
+	}
+	if strings.Contains(report, "Recalculated A") {
+		t.Errorf("A has been recalculated: %q", report)
+	}
+	prevA, a := first.Subclade("A"), second.Subclade("A")
+	if fmtAge(a.TMRCA_STR) != fmtAge(prevA.TMRCA_STR) || fmtAge(a.AgeSTR) != fmtAge(prevA.AgeSTR) {
+		t.Errorf("A: TMRCA %g, formed %g, want %g, %g", a.TMRCA_STR, a.AgeSTR, prevA.TMRCA_STR, prevA.AgeSTR)
+	}
+	if b := second.Subclade("B"); b.TMRCA_STR <= first.Subclade("B").TMRCA_STR {
+		t.Errorf("B: TMRCA %g, want more than %g", b.TMRCA_STR, first.Subclade("B").TMRCA_STR)
+	}
+}
+
+// fmtAge returns an age with the precision of the text output.
+func fmtAge(age float64) string {
+	return fmt.Sprintf("%.0f", age)
+}
+
+func TestNewCladeBracketSNP(t *testing.T) {
+	clade, err := newClade("A, Z[1], STRs Downstream: 10, formed: 500, TMRCA: 330, CI:[200, 480]", DefaultNegativePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clade.SNPs) != 2 || clade.SNPs[1] != "Z[1]" {
+		t.Errorf("SNPs = %v, want [A Z[1]]", clade.SNPs)
+	}
+	if clade.InputAges == nil || clade.InputAges.TMRCAlower != 200 || clade.InputAges.TMRCAupper != 480 {
+		t.Errorf("ages = %+v, want CI 200, 480", clade.InputAges)
+	}
+}
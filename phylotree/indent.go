@@ -0,0 +1,109 @@
+package phylotree
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// countSpaces counts the white spaces at the beginning
+// of a line. Tabs advance to the next multiple of tabWidth.
+func countSpaces(line string, tabWidth int) int {
+	if tabWidth < 1 {
+		tabWidth = 1
+	}
+	result := 0
+	for _, c := range line {
+		switch {
+		case c == '\t':
+			result += tabWidth - result%tabWidth
+		case unicode.IsSpace(c):
+			result++
+		default:
+			return result
+		}
+	}
+	return result
+}
+
+// checkMixedIndentation returns a problem if some lines are indented
+// by tabs and others by spaces. The result is empty if the file uses
+// a consistent style. tabWidth is the number of spaces for a tab.
+func checkMixedIndentation(lines []lineInfo, tabWidth int) []string {
+	var problems []string
+	hasTabs, hasSpaces := false, false
+	for i, _ := range lines {
+		for _, c := range lines[i].text {
+			if c == '\t' {
+				hasTabs = true
+			} else if unicode.IsSpace(c) {
+				hasSpaces = true
+			} else {
+				break
+			}
+		}
+		if hasTabs && hasSpaces {
+			problems = append(problems, fmt.Sprintf("line: %d, indentation mixes tabs and spaces, a tab counts as %d spaces",
+				lines[i].lineNo, tabWidth))
+			break
+		}
+	}
+	return problems
+}
+
+// checkIndentation checks the indentation structure of the lines
+// of a tree in text format. The result is a list of problems with
+// line numbers. A line must either be indented one level deeper
+// than the previous line or it must have the same indentation as
+// one of the previous line's ancestors. Samples must not have
+// children. tabWidth is the number of spaces for a tab.
+func checkIndentation(lines []lineInfo, tabWidth int) []string {
+	problems := checkMixedIndentation(lines, tabWidth)
+	if len(lines) == 0 {
+		return problems
+	}
+	// levels are the indentations of the ancestors of the
+	// current line, starting with the root.
+	levels := []int{lines[0].indent}
+	// step is the indentation of a single level, determined
+	// by the first indented line.
+	step := 0
+	isSample := false
+	for i := 1; i < len(lines); i++ {
+		line := &lines[i]
+		if line.indent > levels[len(levels)-1] {
+			// Line is a child of the previous line.
+			if isSample {
+				// The line is ignored by the parser.
+				problems = append(problems, fmt.Sprintf("line: %d, sample has children", line.lineNo))
+				continue
+			}
+			if step == 0 {
+				step = line.indent - levels[len(levels)-1]
+			} else if line.indent-levels[len(levels)-1] != step {
+				problems = append(problems, fmt.Sprintf("line: %d, indentation jumps several levels", line.lineNo))
+			}
+			levels = append(levels, line.indent)
+		} else {
+			// Line is a sibling of the previous line or of one of it's ancestors.
+			for len(levels) > 1 && levels[len(levels)-1] > line.indent {
+				levels = levels[:len(levels)-1]
+			}
+			switch {
+			case len(levels) == 1:
+				problems = append(problems, fmt.Sprintf("line: %d, line is not indented below the root", line.lineNo))
+			case levels[len(levels)-1] != line.indent:
+				problems = append(problems, fmt.Sprintf("line: %d, indentation does not match any parent level", line.lineNo))
+				levels = append(levels, line.indent)
+			}
+		}
+		isSample = strings.Contains(line.text, "id:")
+	}
+	return problems
+}
+
+// ParseWarnings returns problems of the input tree that did not
+// stop the parsing, for example inconsistent indentation.
+func (c *Clade) ParseWarnings() []string {
+	return c.parseWarnings
+}
@@ -0,0 +1,68 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIndentation(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		tabWidth int
+		warnings int
+	}{
+		{"tabs", "R\r\n\tA\r\n\t\tid:1\r\n\tB\r\n\t\tid:2\r\n", 4, 0},
+		{"two spaces", "R\r\n  A\r\n    id:1\r\n  B\r\n    id:2\r\n", 4, 0},
+		{"four spaces", "R\r\n    A\r\n        id:1\r\n    B\r\n        id:2\r\n", 4, 0},
+		{"mixed, tab as four spaces", "R\r\n\tA\r\n\t\tid:1\r\n    B\r\n        id:2\r\n", 4, 1},
+		{"mixed, tab as two spaces", "R\r\n\tA\r\n\t\tid:1\r\n  B\r\n    id:2\r\n", 2, 1},
+	}
+	for _, test := range tests {
+		options := DefaultReadOptions()
+		options.TabWidth = test.tabWidth
+		tree, err := NewFromReaderOptions(strings.NewReader(test.text), options)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		a, b := tree.Subclade("A"), tree.Subclade("B")
+		if len(tree.Subclades) != 2 || a == nil || b == nil || a.Parent != tree || b.Parent != tree ||
+			len(a.Samples) != 1 || len(b.Samples) != 1 {
+			t.Errorf("%s: wrong tree:\n%s", test.name, tree)
+		}
+		if n := len(tree.ParseWarnings()); n != test.warnings {
+			t.Errorf("%s: warnings = %q, want %d", test.name, tree.ParseWarnings(), test.warnings)
+		}
+
+		// Strict reading turns warnings into errors.
+		options.Strict = true
+		_, err = NewFromReaderOptions(strings.NewReader(test.text), options)
+		if (err != nil) != (test.warnings > 0) {
+			t.Errorf("%s: strict reading, error = %v", test.name, err)
+		}
+	}
+}
+
+// TestTabWidth checks that tabs advance the indentation to the
+// next multiple of the tab width.
+func TestTabWidth(t *testing.T) {
+	tests := []struct {
+		line     string
+		tabWidth int
+		want     int
+	}{
+		{"\tA", 4, 4},
+		{"\t\tA", 4, 8},
+		{"  \tA", 4, 4},
+		{"\t  A", 4, 6},
+		{"\tA", 2, 2},
+		{"    A", 2, 4},
+		{"A", 4, 0},
+	}
+	for _, test := range tests {
+		if got := countSpaces(test.line, test.tabWidth); got != test.want {
+			t.Errorf("countSpaces(%q, %d) = %d, want %d", test.line, test.tabWidth, got, test.want)
+		}
+	}
+}
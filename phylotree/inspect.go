@@ -0,0 +1,164 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// termMatcher compares the names of tree elements with a search term.
+type termMatcher struct {
+	term string
+	// pattern is nil if the search term must match exactly.
+	pattern *regexp.Regexp
+}
+
+// newTermMatcher creates a matcher for term. If isRegex is true, term
+// is a regular expression. Otherwise term may contain the wildcards
+// * for any sequence of characters and ? for a single character.
+// Case is ignored.
+func newTermMatcher(term string, isRegex bool) (termMatcher, error) {
+	term = strings.TrimSpace(term)
+	result := termMatcher{term: term}
+	var expr string
+	switch {
+	case isRegex:
+		expr = "(?i)" + term
+	case strings.ContainsAny(term, "*?"):
+		expr = regexp.QuoteMeta(term)
+		expr = strings.Replace(expr, `\*`, ".*", -1)
+		expr = strings.Replace(expr, `\?`, ".", -1)
+		expr = "(?i)^" + expr + "$"
+	default:
+		return result, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return result, errors.New(fmt.Sprintf("invalid search pattern %q, %v", term, err))
+	}
+	result.pattern = pattern
+	return result, nil
+}
+
+// isPattern returns true if the search term may match
+// multiple elements.
+func (m termMatcher) isPattern() bool {
+	return m.pattern != nil
+}
+
+// matchesName returns true if the SNP name or one of it's
+// aliases matches the pattern.
+func (m termMatcher) matchesName(name string) bool {
+	if m.pattern.MatchString(trimName(name)) {
+		return true
+	}
+	for _, alias := range strings.FieldsFunc(name, isAliasSeparator) {
+		if m.pattern.MatchString(trimName(alias)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesElement returns true if one of the SNPs of e
+// matches the search term.
+func (m termMatcher) matchesElement(e *Element) bool {
+	if m.pattern == nil {
+		return e.Contains(m.term)
+	}
+	for _, snp := range e.SNPs {
+		if m.matchesName(snp) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSample returns true if one of the SNPs or the
+// ID of s matches the search term.
+func (m termMatcher) matchesSample(s *Sample) bool {
+	if m.pattern == nil {
+		return s.Contains(m.term)
+	}
+	return m.matchesElement(&s.Element) || m.pattern.MatchString(s.ID)
+}
+
+// inspectHit returns the details of a clade or a sample that has been
+// found by Inspect. path contains the ancestors of clade. If sample
+// is not nil, the sample of clade has been found. The result contains
+// the path from the root and the ages of clade.
+func inspectHit(path []*Clade, clade *Clade, sample *Sample) string {
+	var buffer bytes.Buffer
+	names := make([]string, 0, len(path)+1)
+	for _, ancestor := range path {
+		names = append(names, ancestor.SNPs[0])
+	}
+	names = append(names, clade.SNPs[0])
+	buffer.WriteString("Path: " + strings.Join(names, " > ") + "\r\n")
+	if sample != nil {
+		buffer.WriteString(sample.Details())
+		if sample.STRCount >= 0 {
+			buffer.WriteString(fmt.Sprintf("STR-Count to modal of %s: %.0f\r\n", clade.SNPs[0], sample.STRCount))
+		}
+	} else {
+		buffer.WriteString(clade.Details())
+	}
+	if clade.STRCountDownstream >= 0 {
+		buffer.WriteString(fmt.Sprintf("%s: formed: %.0f, TMRCA: %.0f, CI:[%.0f, %.0f]\r\n",
+			clade.SNPs[0], clade.AgeSTR, clade.TMRCA_STR, clade.TMRCAlower, clade.TMRCAupper))
+	}
+	return buffer.String()
+}
+
+// InspectPersons looks at all samples of this clade and it's
+// subclades and reports the samples whose names or labels contain
+// one of the search terms. Case is ignored. The names and labels are
+// taken from the persons' data and from the samples of the tree.
+// Each match is reported with the sample ID, the names, the clade
+// and it's TMRCA. The results are in the order of the search terms.
+func (c *Clade) InspectPersons(searchTerms []string) string {
+	var buffer bytes.Buffer
+	for _, term := range searchTerms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			continue
+		}
+		c.WalkSamples(func(path []*Clade, s *Sample) error {
+			names := []string{s.Name}
+			if s.Person != nil {
+				names = append(names, s.Person.Name, s.Person.Label)
+			}
+			isMatch := false
+			for _, name := range names {
+				if strings.Contains(strings.ToLower(name), term) {
+					isMatch = true
+				}
+			}
+			if !isMatch {
+				return nil
+			}
+			clade := path[len(path)-1]
+			buffer.WriteString(fmt.Sprintf("id:%s", s.ID))
+			if s.Name != "" {
+				buffer.WriteString(", name: " + s.Name)
+			}
+			if s.Person != nil && s.Person.Name != "" && s.Person.Name != s.Name {
+				buffer.WriteString(", name: " + s.Person.Name)
+			}
+			if s.Person != nil && s.Person.Label != "" {
+				buffer.WriteString(", label: " + s.Person.Label)
+			}
+			buffer.WriteString(", clade: " + clade.SNPs[0])
+			if clade.STRCountDownstream >= 0 {
+				buffer.WriteString(fmt.Sprintf(", TMRCA: %.0f, CI:[%.0f, %.0f]", clade.TMRCA_STR, clade.TMRCAlower, clade.TMRCAupper))
+			} else {
+				buffer.WriteString(", TMRCA: unknown")
+			}
+			buffer.WriteString("\r\n")
+			return nil
+		})
+	}
+	return buffer.String()
+}
@@ -0,0 +1,52 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// KitsCSV returns a table in CSV format that contains a row for each
+// sample: the sample ID, the path of clades from the root to the clade
+// that contains the sample, the TMRCA of this clade and the STR count
+// of the sample. Samples without Y-STR data are marked.
+func (c *Clade) KitsCSV() (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	writer.Write([]string{"Kit", "Path", "Clade", "TMRCA", "CI lower", "CI upper", "STR-Count", "STR data"})
+	c.kitsPrint(writer, nil)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// kitsPrint writes the rows for KitsCSV. path contains the
+// names of all ancestors of this clade.
+func (c *Clade) kitsPrint(writer *csv.Writer, path []string) {
+	ownPath := make([]string, len(path), len(path)+1)
+	copy(ownPath, path)
+	ownPath = append(ownPath, c.SNPs[0])
+	for i, _ := range c.Samples {
+		sample := &c.Samples[i]
+		row := []string{sample.ID, strings.Join(ownPath, " > "), c.SNPs[0], "", "", "", "", "missing"}
+		if c.STRCountDownstream >= 0 {
+			row[3] = fmt.Sprintf("%.0f", c.TMRCA_STR)
+			row[4] = fmt.Sprintf("%.0f", c.TMRCAlower)
+			row[5] = fmt.Sprintf("%.0f", c.TMRCAupper)
+		}
+		if sample.Person != nil {
+			row[7] = "yes"
+			if sample.STRCount >= 0 {
+				row[6] = fmt.Sprintf("%.0f", sample.STRCount)
+			}
+		}
+		writer.Write(row)
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].kitsPrint(writer, ownPath)
+	}
+}
@@ -0,0 +1,33 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// AgeLadder returns a table for each of the samples with the given
+// IDs, that lists all ancestral clades of the sample from the
+// terminal clade up to the root, together with their TMRCAs and
+// confidence intervals. The samples do not need to have Y-STR data.
+func (c *Clade) AgeLadder(ids []string) (string, error) {
+	index := c.ancestry()
+	var buffer bytes.Buffer
+	for _, id := range ids {
+		path, exists := index[id]
+		if !exists {
+			return "", errors.New("sample not found: " + id)
+		}
+		buffer.WriteString(fmt.Sprintf("Age ladder for id:%s\r\n", id))
+		for i := len(path) - 1; i >= 0; i-- {
+			clade := path[i]
+			if clade.STRCountDownstream < 0 {
+				buffer.WriteString(fmt.Sprintf("\t%s, TMRCA: unknown\r\n", clade.SNPs[0]))
+			} else {
+				buffer.WriteString(fmt.Sprintf("\t%s, TMRCA: %.0f, CI:[%.0f, %.0f]\r\n",
+					clade.SNPs[0], clade.TMRCA_STR, clade.TMRCAlower, clade.TMRCAupper))
+			}
+		}
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,250 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// ancestry maps sample IDs to the path of clades from the
+// root down to the clade that contains the sample.
+type ancestry map[string][]*Clade
+
+// ancestry creates the ancestry index for all samples of this
+// clade and it's subclades.
+func (c *Clade) ancestry() ancestry {
+	result := make(ancestry)
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		// Copy the path, because it is reused by WalkSamples.
+		ownPath := make([]*Clade, len(path))
+		copy(ownPath, path)
+		result[s.ID] = ownPath
+		return nil
+	})
+	return result
+}
+
+// commonPath returns the part of two paths that both paths have
+// in common. The last clade of the result is the lowest common
+// ancestor. Both paths must start at the same root.
+func commonPath(path1, path2 []*Clade) []*Clade {
+	n := 0
+	for n < len(path1) && n < len(path2) && path1[n] == path2[n] {
+		n++
+	}
+	return path1[:n]
+}
+
+// sampleIDs returns the IDs of all samples of this clade
+// and it's subclades.
+func (c *Clade) sampleIDs() []string {
+	ids := make([]string, 0)
+	for i, _ := range c.Samples {
+		ids = append(ids, c.Samples[i].ID)
+	}
+	for i, _ := range c.Subclades {
+		ids = append(ids, c.Subclades[i].sampleIDs()...)
+	}
+	return ids
+}
+
+// TMRCAMatrix returns a matrix in CSV format that contains the
+// TMRCA for each pair of samples. The TMRCA of two samples is the
+// TMRCA of their lowest common ancestor clade.
+// ids is the list of sample IDs for the matrix. If ids is empty,
+// all samples of the tree are used.
+func (c *Clade) TMRCAMatrix(ids []string) (string, error) {
+	if len(ids) == 0 {
+		ids = c.sampleIDs()
+	}
+	index := c.ancestry()
+	paths := make([][]*Clade, len(ids))
+	for i, id := range ids {
+		path, exists := index[id]
+		if !exists {
+			return "", errors.New(fmt.Sprintf("could not find sample %s", id))
+		}
+		paths[i] = path
+	}
+
+	// Calculate rows in parallel.
+	rows := make([][]string, len(ids))
+	var wg sync.WaitGroup
+	for i, _ := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			row := make([]string, len(ids)+1)
+			row[0] = ids[i]
+			for j, _ := range ids {
+				if i == j {
+					row[j+1] = "0"
+					continue
+				}
+				common := commonPath(paths[i], paths[j])
+				lca := common[len(common)-1]
+				// Leave the cell empty if the TMRCA is uncertain.
+				if lca.STRCountDownstream >= 0 {
+					row[j+1] = fmt.Sprintf("%.0f", lca.TMRCA_STR)
+				}
+			}
+			rows[i] = row
+		}(i)
+	}
+	wg.Wait()
+
+	// Write CSV.
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	writer.Write(append([]string{""}, ids...))
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// pathTo returns the path of clades from this clade down to the
+// clade that is named by an SNP or that contains the sample with
+// the ID name. If nothing is found, the result is nil.
+func (c *Clade) pathTo(name string) []*Clade {
+	if c.contains(name) {
+		return []*Clade{c}
+	}
+	for i, _ := range c.Samples {
+		if c.Samples[i].Contains(name) {
+			return []*Clade{c}
+		}
+	}
+	for i, _ := range c.Subclades {
+		if path := c.Subclades[i].pathTo(name); path != nil {
+			return append([]*Clade{c}, path...)
+		}
+	}
+	return nil
+}
+
+// lcaPaths returns the path from this clade to the lowest common
+// ancestor of names and the paths from this clade to each of the
+// named nodes. names may be SNP names or sample IDs. All names
+// that can not be found are reported by the error.
+func (c *Clade) lcaPaths(names []string) (common []*Clade, paths [][]*Clade, err error) {
+	if len(names) < 2 {
+		return nil, nil, errors.New("at least two names are needed")
+	}
+	var missing []string
+	for _, name := range names {
+		path := c.pathTo(name)
+		if path == nil {
+			missing = append(missing, name)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(missing) > 0 {
+		return nil, nil, errors.New(fmt.Sprintf("could not find %s", strings.Join(missing, ", ")))
+	}
+	common = paths[0]
+	for _, path := range paths[1:] {
+		common = commonPath(common, path)
+	}
+	return common, paths, nil
+}
+
+// LCA returns the lowest common ancestor clade of names.
+// names may be SNP names or sample IDs. If one of them is
+// an ancestor of the others, the ancestor is returned. Samples
+// that belong to the same clade have this clade as their
+// lowest common ancestor.
+func (c *Clade) LCA(names ...string) (*Clade, error) {
+	path, _, err := c.lcaPaths(names)
+	if err != nil {
+		return nil, err
+	}
+	return path[len(path)-1], nil
+}
+
+// LCAReport returns a textual description of the lowest common
+// ancestor of names, including the path from the root, it's time
+// estimates and the path of SNPs from the ancestor down to each
+// of the named nodes.
+func (c *Clade) LCAReport(names ...string) (string, error) {
+	path, paths, err := c.lcaPaths(names)
+	if err != nil {
+		return "", err
+	}
+	lca := path[len(path)-1]
+	var buffer bytes.Buffer
+	list := strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	buffer.WriteString(fmt.Sprintf("LCA of %s: %s%s\r\n", list, lca.Element.String(), lca.ageString()))
+	buffer.WriteString("Path: " + cladeNames(path) + "\r\n")
+	for i, name := range names {
+		buffer.WriteString(fmt.Sprintf("\t%s: %s\r\n", name, cladeNames(paths[i][len(path)-1:])))
+	}
+	return buffer.String(), nil
+}
+
+// cladeNames returns the names of the clades of path
+// separated by >.
+func cladeNames(path []*Clade) string {
+	names := make([]string, len(path))
+	for i, clade := range path {
+		names[i] = clade.SNPs[0]
+	}
+	return strings.Join(names, " > ")
+}
+
+// ErrSameLineage is returned by InterCladeReport if one clade
+// is ancestral to the other.
+var ErrSameLineage = errors.New("the clades are on the same lineage")
+
+// InterCladeReport returns the TMRCA of the clades named by the SNPs
+// a and b, which is the TMRCA of their lowest common ancestor, and the
+// number of STR mutations between their modal haplotypes, which is
+// calculated by distance. If one clade is ancestral to the other,
+// the report contains the age of the ancestor and the error is
+// ErrSameLineage.
+func (c *Clade) InterCladeReport(a, b string, mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) (string, error) {
+	// Both names must be unambiguous clades, not samples.
+	for _, name := range []string{a, b} {
+		if _, err := c.FindSubclade(name); err != nil {
+			return "", err
+		}
+	}
+	path, paths, err := c.lcaPaths([]string{a, b})
+	if err != nil {
+		return "", err
+	}
+	lca := path[len(path)-1]
+	cladeA, cladeB := paths[0][len(paths[0])-1], paths[1][len(paths[1])-1]
+
+	var buffer bytes.Buffer
+	switch lca {
+	case cladeA:
+		buffer.WriteString(fmt.Sprintf("%s is ancestral to %s: ", a, b))
+	case cladeB:
+		buffer.WriteString(fmt.Sprintf("%s is ancestral to %s: ", b, a))
+	default:
+		buffer.WriteString(fmt.Sprintf("TMRCA of %s and %s: ", a, b))
+	}
+	buffer.WriteString(strings.Join(lca.SNPs, ", "))
+	if lca.STRCountDownstream >= 0 {
+		buffer.WriteString(fmt.Sprintf(", TMRCA: %.0f, CI:[%.0f, %.0f]\r\n", lca.TMRCA_STR, lca.TMRCAlower, lca.TMRCAupper))
+	} else {
+		buffer.WriteString(", TMRCA: unknown\r\n")
+	}
+	if cladeA.Person != nil && cladeB.Person != nil {
+		buffer.WriteString(fmt.Sprintf("STR mutations between the modal haplotypes of %s and %s: %.1f\r\n",
+			a, b, distance(cladeA.Person.YstrMarkers, cladeB.Person.YstrMarkers, mutationRates)))
+	}
+	if lca == cladeA || lca == cladeB {
+		return buffer.String(), ErrSameLineage
+	}
+	return buffer.String(), nil
+}
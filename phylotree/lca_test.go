@@ -0,0 +1,97 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestTMRCAMatrixUncertain checks that uncertain TMRCAs are
+// left empty in the matrix.
+func TestTMRCAMatrixUncertain(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tid:3\r\n")
+	tree.STRCountDownstream, tree.TMRCA_STR = 2, 1500
+	a := tree.Subclade("A")
+	a.STRCountDownstream, a.TMRCA_STR = Uncertain, Uncertain
+	got, err := tree.TMRCAMatrix(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ",3,1,2\r\n3,0,1500,1500\r\n1,1500,0,\r\n2,1500,,0\r\n"
+	if got != want {
+		t.Errorf("matrix = %q, want %q", got, want)
+	}
+}
+
+// TestLCAReport checks the lowest common ancestor of two and
+// more nodes and that all missing names are reported.
+func TestLCAReport(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tB\r\n\t\t\tid:1\r\n\t\tC\r\n\t\t\tid:2\r\n\t\tid:3\r\n\tD\r\n\t\tid:4\r\n")
+	tests := []struct {
+		names []string
+		lca   string
+	}{
+		{[]string{"1", "2"}, "A"},
+		{[]string{"1", "3"}, "A"},
+		{[]string{"B", "1"}, "B"},
+		{[]string{"1", "2", "4"}, "R"},
+	}
+	for _, test := range tests {
+		lca, err := tree.LCA(test.names...)
+		if err != nil || lca.SNPs[0] != test.lca {
+			t.Errorf("LCA(%v) = %v, %v, want %s", test.names, lca, err, test.lca)
+		}
+	}
+
+	report, err := tree.LCAReport("1", "2", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "LCA of 1, 2 and 3: A\r\nPath: R > A\r\n\t1: A > B\r\n\t2: A > C\r\n\t3: A\r\n"
+	if report != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+
+	_, err = tree.LCA("1", "5", "E")
+	if err == nil || err.Error() != "could not find 5, E" {
+		t.Errorf("error = %v", err)
+	}
+	_, err = tree.LCAReport("1", "YF9999")
+	if err == nil || err.Error() != "could not find YF9999" {
+		t.Errorf("LCAReport error = %v, want could not find YF9999", err)
+	}
+}
+
+// TestInterCladeReport checks the TMRCA of two clades, the
+// distance of their modal haplotypes and the errors for missing
+// clades and clades on the same lineage.
+func TestInterCladeReport(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tB\r\n\t\t\tid:1\r\n\t\tC\r\n\t\t\tid:2\r\n\tD\r\n\t\tid:3\r\n")
+	a := tree.Subclade("A")
+	a.STRCountDownstream, a.TMRCA_STR, a.TMRCAlower, a.TMRCAupper = 5, 1000, 800, 1200
+	rates := genetic.DefaultMutationRates()
+	distance := func(p1, p2 genetic.YstrMarkers, rates genetic.YstrMarkers) float64 { return 3 }
+	tree.Subclade("B").Person = newPerson(t, "B", map[string]float64{"DYS393": 13})
+	tree.Subclade("C").Person = newPerson(t, "C", map[string]float64{"DYS393": 14})
+
+	report, err := tree.InterCladeReport("B", "C", rates, distance)
+	want := "TMRCA of B and C: A, TMRCA: 1000, CI:[800, 1200]\r\n" +
+		"STR mutations between the modal haplotypes of B and C: 3.0\r\n"
+	if err != nil || report != want {
+		t.Errorf("report = %q, %v, want %q", report, err, want)
+	}
+
+	report, err = tree.InterCladeReport("C", "A", rates, distance)
+	if err != ErrSameLineage || !strings.HasPrefix(report, "A is ancestral to C: A, TMRCA: 1000") {
+		t.Errorf("same lineage: report = %q, %v", report, err)
+	}
+
+	if _, err = tree.InterCladeReport("B", "E", rates, distance); err == nil || err == ErrSameLineage {
+		t.Errorf("missing clade: error = %v", err)
+	}
+	// Sample IDs are no clades.
+	if _, err = tree.InterCladeReport("B", "3", rates, distance); err == nil {
+		t.Errorf("sample ID accepted as clade")
+	}
+}
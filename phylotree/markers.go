@@ -0,0 +1,137 @@
+package phylotree
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// panelSizes are the sizes of the usual FTDNA panels. The panel
+// ftdna37 contains the first 37 markers in FTDNA order.
+var panelSizes = []int{12, 25, 37, 67, 111}
+
+// TraceIndices returns the indices of the Y-STR markers specified by
+// STRs. The names may be internal names or the names used by FTDNA or
+// YFull. "auto" means all markers that vary among the samples of this
+// clade and "all" means all markers. The FTDNA panels are named by
+// ftdna12, ftdna25, ftdna37, ftdna67 and ftdna111. Ranges of markers
+// in FTDNA order may be specified by names, for example DYS393-DYS19,
+// or by numbers starting at 1, for example 1-37. Markers that are
+// selected multiple times are only included once, in the order of
+// their first selection. Unknown names are reported in the error,
+// while the result contains all markers that were found.
+func (c *Clade) TraceIndices(STRs []string) ([]int, error) {
+	var indices []int
+	var unknown []string
+	isIncluded := make(map[int]bool)
+	add := func(index int) {
+		if !isIncluded[index] {
+			isIncluded[index] = true
+			indices = append(indices, index)
+		}
+	}
+	addRange := func(first, last int) {
+		for i := first; i <= last; i++ {
+			add(i)
+		}
+	}
+	for _, str := range STRs {
+		name := strings.ToLower(strings.TrimSpace(str))
+		if name == "" {
+			continue
+		}
+		switch {
+		case name == "auto":
+			for _, index := range c.VaryingMarkers() {
+				add(index)
+			}
+		case name == "all":
+			addRange(0, len(genetic.YstrMarkerTable)-1)
+		case MarkerIndex(name) >= 0:
+			add(MarkerIndex(name))
+		case panelSize(name) > 0:
+			addRange(0, panelSize(name)-1)
+		default:
+			first, last, ok := markerRange(name)
+			if !ok {
+				unknown = append(unknown, strings.TrimSpace(str))
+				continue
+			}
+			addRange(first, last)
+		}
+	}
+	if len(unknown) > 0 {
+		return indices, errors.New("unknown markers: " + strings.Join(unknown, ", "))
+	}
+	return indices, nil
+}
+
+// panelSize returns the number of markers of the FTDNA panel
+// named by name, for example 37 for ftdna37. If name is not a
+// panel, the result is 0.
+func panelSize(name string) int {
+	if !strings.HasPrefix(name, "ftdna") {
+		return 0
+	}
+	size, err := strconv.Atoi(name[5:])
+	if err != nil {
+		return 0
+	}
+	for _, s := range panelSizes {
+		if s == size {
+			return size
+		}
+	}
+	return 0
+}
+
+// markerRange returns the indices of the first and last marker
+// of a range, for example DYS393-DYS19 or 1-37.
+func markerRange(name string) (first, last int, ok bool) {
+	parts := strings.Split(name, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	n := len(genetic.YstrMarkerTable)
+	from, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	to, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 == nil && err2 == nil {
+		if from < 1 || to < from || to > n {
+			return 0, 0, false
+		}
+		return from - 1, to - 1, true
+	}
+	first, last = MarkerIndex(parts[0]), MarkerIndex(parts[1])
+	if first < 0 || last < first {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// MarkerIndices returns the indices of the markers named by name.
+// name may be the name of a single marker or the name of a multi-copy
+// marker without the copy letter, for example DYS464 for DYS464a,
+// DYS464b and so on. The result is empty if no marker is found.
+func MarkerIndices(name string) []int {
+	if index := MarkerIndex(name); index >= 0 {
+		return []int{index}
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+	var result []int
+	for _, marker := range genetic.YstrMarkerTable {
+		for _, markerName := range []string{marker.InternalName, marker.FTDNAName, marker.YFullName} {
+			markerName = strings.ToLower(markerName)
+			if len(markerName) == len(name)+1 && strings.HasPrefix(markerName, name) &&
+				markerName[len(name)] >= 'a' && markerName[len(name)] <= 'z' {
+				result = append(result, marker.Index)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// Matching describes how persons have been inserted into the tree.
+type Matching struct {
+	// Persons is the number of different person IDs.
+	Persons int
+	// Matched is the number of persons that matched a sample.
+	Matched int
+	// UnmatchedPersons are the IDs of persons that matched no sample.
+	UnmatchedPersons []string
+	// UnmatchedSamples are the IDs of samples without a person.
+	UnmatchedSamples []string
+	// unmatchedClades are the clades of UnmatchedSamples.
+	unmatchedClades []string
+}
+
+// Match compares persons with the samples of the tree. It must
+// be called after InsertPersons.
+func (c *Clade) Match(persons []*genetic.Person) Matching {
+	var result Matching
+	// inserted are the IDs of all persons in the tree.
+	inserted := make(map[string]bool)
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		if s.Person == nil {
+			result.UnmatchedSamples = append(result.UnmatchedSamples, s.ID)
+			result.unmatchedClades = append(result.unmatchedClades, path[len(path)-1].SNPs[0])
+		} else {
+			inserted[s.Person.ID] = true
+		}
+		return nil
+	})
+	personIDs := make(map[string]bool)
+	for _, person := range persons {
+		if personIDs[person.ID] {
+			continue
+		}
+		personIDs[person.ID] = true
+		result.Persons++
+		if inserted[person.ID] {
+			result.Matched++
+		} else {
+			result.UnmatchedPersons = append(result.UnmatchedPersons, person.ID)
+		}
+	}
+	return result
+}
+
+// Summary returns a single line that summarizes the matching.
+func (m Matching) Summary() string {
+	return fmt.Sprintf("%d of %d persons matched, %d samples without person data",
+		m.Matched, m.Persons, len(m.UnmatchedSamples))
+}
+
+// String returns a report that lists all persons that matched
+// no sample and all samples without a person.
+func (m Matching) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(m.Summary() + "\r\n")
+	if len(m.UnmatchedPersons) > 0 {
+		buffer.WriteString("Persons without sample:\r\n")
+		for _, id := range m.UnmatchedPersons {
+			buffer.WriteString(id + "\r\n")
+		}
+	}
+	if len(m.UnmatchedSamples) > 0 {
+		buffer.WriteString("Samples without person data:\r\n")
+		for i, id := range m.UnmatchedSamples {
+			buffer.WriteString(fmt.Sprintf("id:%s, clade: %s\r\n", id, m.unmatchedClades[i]))
+		}
+	}
+	return buffer.String()
+}
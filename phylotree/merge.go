@@ -0,0 +1,92 @@
+package phylotree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Merge merges the tree other into this tree. The root of other must
+// be this clade or one of it's subclades. The samples of other are
+// added to this clade. Subclades with identical names are merged,
+// all other subclades are added. If an SNP of other appears at a
+// different position in this tree, an error is returned. Subclades
+// of other are moved into this tree.
+func (c *Clade) Merge(other *Clade) error {
+	target, err := c.FindSubclade(other.SNPs[0])
+	if err != nil {
+		return errors.New(fmt.Sprintf("root of merged tree, %v", err))
+	}
+	snps := make(map[string]string)
+	c.addSNPs(snps)
+	return target.merge(other, snps)
+}
+
+// addSNPs adds all SNPs of this clade and all subclades to snps.
+// The keys are the lower case names of the aliases. The values are
+// the names as written in the input.
+func (c *Clade) addSNPs(snps map[string]string) {
+	for _, snp := range c.SNPs {
+		for _, key := range aliasKeys(snp) {
+			snps[key] = snp
+		}
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].addSNPs(snps)
+	}
+}
+
+// duplicateSNP returns the first SNP of this clade and all
+// subclades in tree order that is already contained in snps.
+// An SNP is contained if one of it's aliases is contained.
+// The SNP is returned as written in the input.
+func (c *Clade) duplicateSNP(snps map[string]string) (string, bool) {
+	for _, snp := range c.SNPs {
+		for _, key := range aliasKeys(snp) {
+			if _, exists := snps[key]; exists {
+				return snp, true
+			}
+		}
+	}
+	for i, _ := range c.Subclades {
+		if snp, exists := c.Subclades[i].duplicateSNP(snps); exists {
+			return snp, true
+		}
+	}
+	return "", false
+}
+
+// merge merges other into this clade. Both clades must have
+// the same name. snps contains the SNPs of the whole tree.
+func (c *Clade) merge(other *Clade, snps map[string]string) error {
+	for i, _ := range other.Samples {
+		c.AddSample(other.Samples[i])
+	}
+	for i, _ := range other.Subclades {
+		subclade := other.Subclades[i]
+		if existing := c.directSubclade(subclade.SNPs[0]); existing != nil {
+			if err := existing.merge(subclade, snps); err != nil {
+				return err
+			}
+			continue
+		}
+		// Check that the SNPs of the new subclade
+		// do not appear elsewhere in the tree.
+		if snp, exists := subclade.duplicateSNP(snps); exists {
+			return errors.New(fmt.Sprintf("SNP %s appears at different positions", snp))
+		}
+		subclade.addSNPs(snps)
+		c.AttachSubclade(subclade)
+	}
+	return nil
+}
+
+// directSubclade returns the direct subclade of this clade
+// with the name cladeName or nil if there is none.
+func (c *Clade) directSubclade(cladeName string) *Clade {
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].contains(cladeName) {
+			return c.Subclades[i]
+		}
+	}
+	return nil
+}
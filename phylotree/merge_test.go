@@ -0,0 +1,20 @@
+package phylotree
+
+import "testing"
+
+// TestMergeConflict checks that a conflicting SNP is reported
+// as written in the input.
+func TestMergeConflict(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tFGC123\r\n\tB\r\n")
+	other := mustParse(t, "B\r\n\tC\r\n\t\tfgC123\r\n")
+	err := tree.Merge(other)
+	if err == nil || err.Error() != "SNP fgC123 appears at different positions" {
+		t.Errorf("error = %v", err)
+	}
+
+	graft := mustParse(t, "D\r\n\tFgc123\r\n")
+	err = mustParse(t, "R\r\n\tA\r\n\t\tFGC123\r\n\tB\r\n").Graft("B", graft)
+	if err == nil || err.Error() != "SNP Fgc123 of grafted tree is already part of the tree" {
+		t.Errorf("error = %v", err)
+	}
+}
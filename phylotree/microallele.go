@@ -0,0 +1,87 @@
+package phylotree
+
+import (
+	"math"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// Microalleles are marker values with a partial repeat, like
+// DYS458 = 17.2. The fractional part is treated as a label of the
+// allele: 17.2 and 18.2 differ by one step, 17.2 and 17 are
+// different alleles that also differ by one step.
+
+// microFraction returns the fractional part of a marker value
+// as a whole number, 2 for 17.2 and 0 for 17.
+func microFraction(value float64) float64 {
+	return math.Round((value - math.Floor(value)) * 10)
+}
+
+// isMicroallele returns true if value has a partial repeat.
+func isMicroallele(value float64) bool {
+	return value > 0 && microFraction(value) != 0
+}
+
+// alleleSteps returns the number of mutation steps from one marker
+// value to another. The number is negative if the value decreases.
+// The repeats are counted like for integer values and a different
+// partial repeat counts as one additional step.
+func alleleSteps(from, to float64) float64 {
+	steps := math.Abs(math.Floor(to) - math.Floor(from))
+	if microFraction(from) != microFraction(to) {
+		steps++
+	}
+	if to < from {
+		return -steps
+	}
+	return steps
+}
+
+// pluralityLabel returns the partial repeat of values, as returned by
+// microFraction, that has the largest sum of weights. If labels have
+// the same weight, the smallest label is returned, so whole numbers
+// are preferred.
+func pluralityLabel(values, weights []float64) float64 {
+	sums := make(map[float64]float64)
+	for i, value := range values {
+		sums[microFraction(value)] += weights[i]
+	}
+	result := 0.0
+	best := -1.0
+	for label, sum := range sums {
+		if sum > best || (sum == best && label < result) {
+			result = label
+			best = sum
+		}
+	}
+	return result
+}
+
+// MicroalleleDistance returns a distance function that replaces
+// the differences of microalleles by the number of steps from
+// alleleSteps before the distance is calculated by distance.
+func MicroalleleDistance(distance genetic.DistanceFunc) genetic.DistanceFunc {
+	return func(ystr1, ystr2 genetic.YstrMarkers, mutationRates genetic.YstrMarkers) float64 {
+		for i, _ := range ystr1 {
+			if ystr1[i] > 0 && ystr2[i] > 0 && (isMicroallele(ystr1[i]) || isMicroallele(ystr2[i])) {
+				ystr1[i] = ystr2[i] + alleleSteps(ystr2[i], ystr1[i])
+			}
+		}
+		return distance(ystr1, ystr2, mutationRates)
+	}
+}
+
+// RoundMicroalleles rounds all microallele values of persons to
+// whole numbers. It returns the number of rounded values.
+func RoundMicroalleles(persons []*genetic.Person) int {
+	count := 0
+	for _, person := range persons {
+		for i, value := range person.YstrMarkers {
+			if isMicroallele(value) {
+				person.YstrMarkers[i] = math.Round(value)
+				count++
+			}
+		}
+	}
+	return count
+}
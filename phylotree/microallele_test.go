@@ -0,0 +1,55 @@
+package phylotree
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestAverageMicroalleles checks that microalleles are only averaged
+// with values of the same partial repeat.
+func TestAverageMicroalleles(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []float64
+		weights []float64
+		average float64
+	}{
+		{"same microallele", []float64{17.2, 17.2}, []float64{1, 1}, 17.2},
+		{"microallele majority", []float64{17.2, 17.2, 17}, []float64{1, 1, 1}, 17.2},
+		{"whole number majority", []float64{17, 18, 17.2}, []float64{1, 1, 1}, 17.5},
+		{"different repeats", []float64{17.2, 18.2}, []float64{1, 1}, 17.7},
+		{"weighted microallele", []float64{17.2, 17, 18}, []float64{3, 1, 1}, 17.2},
+		{"tie", []float64{17.2, 17}, []float64{1, 1}, 17},
+	}
+	for _, test := range tests {
+		var persons []*genetic.Person
+		for i, value := range test.values {
+			persons = append(persons, newPerson(t, strconv.Itoa(i+1), map[string]float64{"DYS458": value}))
+		}
+		modal := averageHaplotype(persons, test.weights)
+		if average := modal.YstrMarkers[mustIndex(t, "DYS458")]; math.Abs(average-test.average) > 1e-9 {
+			t.Errorf("%s: DYS458 = %g, want %g", test.name, average, test.average)
+		}
+	}
+}
+
+// TestMultiCopyMicroalleles checks that the copies of DYS385 are
+// sorted before microalleles are averaged.
+func TestMultiCopyMicroalleles(t *testing.T) {
+	persons := []*genetic.Person{
+		newPerson(t, "1", map[string]float64{"DYS385a": 11, "DYS385b": 14.2}),
+		newPerson(t, "2", map[string]float64{"DYS385a": 14.2, "DYS385b": 11}),
+		newPerson(t, "3", map[string]float64{"DYS385a": 11, "DYS385b": 15}),
+	}
+	modal := averageHaplotype(persons, []float64{1, 1, 1})
+	a, b := modal.YstrMarkers[mustIndex(t, "DYS385a")], modal.YstrMarkers[mustIndex(t, "DYS385b")]
+	if a != 11 || math.Abs(b-14.2) > 1e-9 {
+		t.Errorf("DYS385 = %g-%g, want 11-14.2", a, b)
+	}
+	if steps := alleleSteps(14.2, 15); steps != 2 {
+		t.Errorf("steps from 14.2 to 15 = %g, want 2", steps)
+	}
+}
@@ -42,7 +42,25 @@ const (
 //     nearest and smallest real mutation neighbor.
 //     Recalculate the tree top down to find values for previously
 //     uncertain values.
+//
+// The number of mutation steps is not limited and a single
+// sample supports a marker value.
 func (c *Clade) CalculateModalHaplotypesParsimony(statistics *genetic.MarkerStatistics, processingStage int, isInfiniteAlleles bool) {
+	c.CalculateModalHaplotypesParsimonyLimited(statistics, processingStage, isInfiniteAlleles, StepLimit{}, 1)
+}
+
+// CalculateModalHaplotypesParsimonyLimited works like
+// CalculateModalHaplotypesParsimony.
+//
+// limit limits the number of mutation steps that are counted
+// for a single marker.
+//
+// minSupport is the minimum number of downstream samples that must
+// have a value for a marker. Markers with less support are
+// treated as Uncertain and are later recalculated by using the
+// parent haplotype.
+func (c *Clade) CalculateModalHaplotypesParsimonyLimited(statistics *genetic.MarkerStatistics, processingStage int, isInfiniteAlleles bool, limit StepLimit, minSupport int) {
+	c.forcedMarkers = nil
 	if processingStage < 1 {
 		return
 	}
@@ -50,32 +68,39 @@ func (c *Clade) CalculateModalHaplotypesParsimony(statistics *genetic.MarkerStat
 		// Make sure all node Persons are != nil.
 		c.populateWithDummies()
 
+		// Fixed values are set before the calculation and
+		// are never overwritten.
+		c.applyFixedValues()
+
 		// Calculate haplotypes that satisfy the maximum
 		// parsimony criterion.
-		c.calculateModalHaplotypesMaxParsimony(isInfiniteAlleles)
+		c.calculateModalHaplotypesMaxParsimony(isInfiniteAlleles, limit, minSupport)
 	}
 	if processingStage >= 2 {
 		// Calculate average haplotypes using real numbers.
-		c.calculateHaplotypes(averageHaplotype)
+		c.calculateHaplotypes(averageHaplotype, minSupport)
 	}
 	if processingStage == 3 {
 		// Mark results that do not have a nearest neighbor among
 		// real mutation values as Uncertain.
 		// This stage is only for visualization and debugging.
 		c.constrainHaplotypes(statistics, markUncertain)
+		c.applyFixedValues()
 	}
 	if processingStage >= 4 {
 		// Map all markers with certain nearest neighbors to real world marker values.
 		c.constrainHaplotypes(statistics, mapCertain)
 
 		// Force a haplotype without uncertain values for the top node.
+		c.forcedMarkers = ambiguousMarkers(c.Person, statistics)
 		constrainHaplotype(c.Person, statistics, mapAll)
+		c.applyFixedValues()
 
 		// Recalculate values for uncertain values
 		// using child and parent haplotypes.
-		for i, _ := range c.Subclades {
-			c.Subclades[i].recalculateModalHaplotypes(c, statistics)
-		}
+		// The top node has no parent, so it's remaining Uncertain
+		// values are calculated from the child haplotypes only.
+		c.recalculateModalHaplotypes(nil, statistics)
 	}
 }
 
@@ -95,21 +120,26 @@ func (c *Clade) populateWithDummies() {
 // and all subclades, using the function haplotype to perform
 // the calculation.
 // Only Uncertain values are replaced in the nodes of the Clade.
-func (c *Clade) calculateHaplotypes(haplotype func(persons []*genetic.Person) *genetic.Person) {
+// Values that are supported by less than minSupport downstream
+// samples remain Uncertain.
+func (c *Clade) calculateHaplotypes(haplotype func(persons []*genetic.Person, weights []float64) *genetic.Person, minSupport int) {
 	// Create a list of haplotypes from samples and subclades.
 	persons := make([]*genetic.Person, 0)
+	weights := make([]float64, 0)
 	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
+		if c.Samples[i].hasInfluence() {
 			persons = append(persons, c.Samples[i].Person)
+			weights = append(weights, c.Samples[i].Weight)
 		}
 	}
 	for i, _ := range c.Subclades {
-		c.Subclades[i].calculateHaplotypes(haplotype)
+		c.Subclades[i].calculateHaplotypes(haplotype, minSupport)
 		persons = append(persons, c.Subclades[i].Person)
+		weights = append(weights, 1)
 	}
 	// Calculate result and replace Uncertain values in this Clade.
-	modal := haplotype(persons)
-	replaceUncertains(c.Person, modal)
+	modal := haplotype(persons, weights)
+	replaceUncertains(c.Person, modal, c.Completeness().Support, minSupport)
 }
 
 // averageHaplotype calculates the average haplotype for a group of persons.
@@ -123,7 +153,16 @@ func (c *Clade) calculateHaplotypes(haplotype func(persons []*genetic.Person) *g
 //		single haplotype is the haplotype itself.
 //
 //  >2 values: return the average of all values > 0.
-func averageHaplotype(persons []*genetic.Person) *genetic.Person {
+//		If the weight of the Null values is greater than
+//		the weight of the other values, return Null.
+//		Microalleles are only averaged with values of the same
+//		partial repeat. The partial repeat with the largest
+//		weight is used, see pluralityLabel.
+//
+// weights contains a weight for each person. The average is
+// weighted accordingly. The values of multi-copy markers are
+// sorted before the average is calculated.
+func averageHaplotype(persons []*genetic.Person, weights []float64) *genetic.Person {
 	modal := new(genetic.Person)
 	switch len(persons) {
 	case 0:
@@ -138,14 +177,29 @@ func averageHaplotype(persons []*genetic.Person) *genetic.Person {
 		for marker := 0; marker < len(persons[0].YstrMarkers); marker++ {
 			count := 0.0
 			sum := 0.0
-			for _, person := range persons {
-				value := person.YstrMarkers[marker]
-				if value > 0 {
-					sum += value
-					count++
+			nulls := 0.0
+			var values, valueWeights []float64
+			for i, person := range persons {
+				value := markerValue(&person.YstrMarkers, marker)
+				switch {
+				case value > 0:
+					values = append(values, value)
+					valueWeights = append(valueWeights, weights[i])
+				case value == Null:
+					nulls += weights[i]
+				}
+			}
+			label := pluralityLabel(values, valueWeights)
+			for i, value := range values {
+				if microFraction(value) == label {
+					sum += value * valueWeights[i]
+					count += valueWeights[i]
 				}
 			}
-			if count > 0 {
+			switch {
+			case nulls > count:
+				modal.YstrMarkers[marker] = Null
+			case count > 0:
 				modal.YstrMarkers[marker] = sum / count
 			}
 		}
@@ -189,20 +243,53 @@ func constrainHaplotype(person *genetic.Person, statistics *genetic.MarkerStatis
 	}
 }
 
+// ambiguousMarkers returns the markers of person that do not have
+// a unique nearest real world marker value.
+func ambiguousMarkers(person *genetic.Person, statistics *genetic.MarkerStatistics) []int {
+	var result []int
+	for i, _ := range person.YstrMarkers {
+		if _, isUnique := closestKey(person.YstrMarkers[i], statistics.Markers[i].ValuesOccurrences); !isUnique {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
 // closest Key returns the key of the mutations map that is closest
 // to the target value. If two keys are equally close, isUnique = false.
 // The keys of the mutations map must hold the mutational values.
 // if target <= 0, the target value itself is returned, because
 // a valid mutational value must always be positive.
+// Microalleles are only returned if they are equal to the target,
+// so that no partial repeats are made up by averages. If the map
+// contains only microalleles, all keys are used.
 func closestKey(target float64, mutations map[float64]int) (closest float64, isUnique bool) {
 	if target <= 0 {
 		return target, true
 	}
+	closest, isUnique, found := closestKeyOf(target, mutations, false)
+	if !found {
+		closest, isUnique, _ = closestKeyOf(target, mutations, true)
+	}
+	return closest, isUnique
+}
+
+// closestKeyOf does the work for closestKey. If withMicroalleles is
+// false, microalleles that are not equal to the target are skipped.
+// found is false if no key has been used.
+func closestKeyOf(target float64, mutations map[float64]int, withMicroalleles bool) (closest float64, isUnique, found bool) {
 	var smallestMutation float64
 	var greatestMutation float64
 	lowDist := math.Inf(1)
 	highDist := math.Inf(1)
 	for mutation, _ := range mutations {
+		if mutation <= 0 {
+			continue
+		}
+		if !withMicroalleles && isMicroallele(mutation) && math.Abs(mutation-target) > 1e-9 {
+			continue
+		}
+		found = true
 		dist := mutation - target
 		switch {
 		case dist < 0:
@@ -226,16 +313,16 @@ func closestKey(target float64, mutations map[float64]int) (closest float64, isU
 	}
 	switch {
 	case smallestMutation == greatestMutation:
-		return smallestMutation, true
+		return smallestMutation, true, found
 	case lowDist == highDist:
 		// Prefer to return the smallest mutation that is
 		// close to the target.
-		return smallestMutation, false
+		return smallestMutation, false, found
 	default:
 		if lowDist < highDist {
-			return smallestMutation, true
+			return smallestMutation, true, found
 		} else {
-			return greatestMutation, true
+			return greatestMutation, true, found
 		}
 	}
 }
@@ -247,21 +334,26 @@ func closestKey(target float64, mutations map[float64]int) (closest float64, isU
 func (c *Clade) recalculateModalHaplotypes(parent *Clade, statistics *genetic.MarkerStatistics) {
 	// Create a list of haplotypes for calculation.
 	persons := make([]*genetic.Person, 0)
+	weights := make([]float64, 0)
 	if parent != nil && parent.Person != nil {
 		persons = append(persons, parent.Person)
+		weights = append(weights, 1)
 	}
 	for i, _ := range c.Subclades {
 		if c.Subclades[i].Person != nil {
 			persons = append(persons, c.Subclades[i].Person)
+			weights = append(weights, 1)
 		}
 	}
 	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
+		if c.Samples[i].hasInfluence() {
 			persons = append(persons, c.Samples[i].Person)
+			weights = append(weights, c.Samples[i].Weight)
 		}
 	}
-	recalc := averageHaplotype(persons)
+	recalc := averageHaplotype(persons, weights)
 	replaceUncertainsWithMapping(c.Person, recalc, statistics)
+	c.applyOwnFixedValues()
 
 	for i, _ := range c.Subclades {
 		c.Subclades[i].recalculateModalHaplotypes(c, statistics)
@@ -269,10 +361,12 @@ func (c *Clade) recalculateModalHaplotypes(parent *Clade, statistics *genetic.Ma
 }
 
 // replaceUncertains replaces all uncertain marker values in target
-// with values from source.
-func replaceUncertains(target, source *genetic.Person) {
+// with values from source. support is the number of samples that
+// have a value for each marker. Markers with a support less than
+// minSupport are not replaced.
+func replaceUncertains(target, source *genetic.Person, support []int, minSupport int) {
 	for i, _ := range target.YstrMarkers {
-		if target.YstrMarkers[i] == Uncertain {
+		if target.YstrMarkers[i] == Uncertain && !isUnsupported(support[i], minSupport) {
 			target.YstrMarkers[i] = source.YstrMarkers[i]
 		}
 	}
@@ -284,7 +378,7 @@ func replaceUncertains(target, source *genetic.Person) {
 func replaceUncertainsWithMapping(target, source *genetic.Person, statistics *genetic.MarkerStatistics) {
 	for i, _ := range target.YstrMarkers {
 		_, isUnique := closestKey(target.YstrMarkers[i], statistics.Markers[i].ValuesOccurrences)
-		if isUnique == false {
+		if isUnique == false || target.YstrMarkers[i] == Uncertain {
 			newValue, _ := closestKey(source.YstrMarkers[i], statistics.Markers[i].ValuesOccurrences)
 			target.YstrMarkers[i] = newValue
 		}
@@ -0,0 +1,105 @@
+package phylotree
+
+import (
+	"sort"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// multiCopyNames are the names of the multi-copy markers. The
+// copies of these markers are reported as unordered sets of values,
+// so a result of 11-14 is the same as 14-11.
+var multiCopyNames = []string{"DYS385", "DYS459", "DYS464", "CDY", "YCAII", "DYF395S1", "DYS413"}
+
+// multiCopyGroups contains the marker indices of the copies
+// of each multi-copy marker.
+var multiCopyGroups = newMultiCopyGroups()
+
+// multiCopyGroup maps the index of a marker to the group of
+// copies it belongs to.
+var multiCopyGroup = newMultiCopyGroup()
+
+// newMultiCopyGroups creates the marker groups for multiCopyGroups.
+func newMultiCopyGroups() [][]int {
+	var result [][]int
+	for _, name := range multiCopyNames {
+		if indices := MarkerIndices(name); len(indices) > 1 {
+			sort.Ints(indices)
+			result = append(result, indices)
+		}
+	}
+	return result
+}
+
+// newMultiCopyGroup creates the map for multiCopyGroup.
+func newMultiCopyGroup() map[int][]int {
+	result := make(map[int][]int)
+	for _, group := range multiCopyGroups {
+		for _, index := range group {
+			result[index] = group
+		}
+	}
+	return result
+}
+
+// SortMultiCopy sorts the values of the copies of each multi-copy
+// marker in ascending order. Groups with missing or Uncertain
+// values are not changed, because the copy a value belongs to
+// is not known.
+func SortMultiCopy(ystr *genetic.YstrMarkers) {
+	for _, group := range multiCopyGroups {
+		values, ok := groupValues(ystr, group)
+		if !ok {
+			continue
+		}
+		sort.Float64s(values)
+		for i, index := range group {
+			ystr[index] = values[i]
+		}
+	}
+}
+
+// groupValues returns the values of the copies in group. ok is false
+// if a copy has no value or an Uncertain value.
+func groupValues(ystr *genetic.YstrMarkers, group []int) (values []float64, ok bool) {
+	values = make([]float64, len(group))
+	for i, index := range group {
+		if ystr[index] <= 0 {
+			return nil, false
+		}
+		values[i] = ystr[index]
+	}
+	return values, true
+}
+
+// markerValue returns the value of marker from ystr. For a copy of
+// a multi-copy marker the value of the same position is returned
+// after the values of all copies have been sorted.
+func markerValue(ystr *genetic.YstrMarkers, marker int) float64 {
+	group, exists := multiCopyGroup[marker]
+	if !exists {
+		return ystr[marker]
+	}
+	values, ok := groupValues(ystr, group)
+	if !ok {
+		return ystr[marker]
+	}
+	sort.Float64s(values)
+	for i, index := range group {
+		if index == marker {
+			return values[i]
+		}
+	}
+	return ystr[marker]
+}
+
+// MultiCopyDistance returns a distance function that sorts the
+// values of multi-copy markers before the distance is calculated
+// by distance. So 11-14 and 14-11 have a distance of 0.
+func MultiCopyDistance(distance genetic.DistanceFunc) genetic.DistanceFunc {
+	return func(ystr1, ystr2 genetic.YstrMarkers, mutationRates genetic.YstrMarkers) float64 {
+		SortMultiCopy(&ystr1)
+		SortMultiCopy(&ystr2)
+		return distance(ystr1, ystr2, mutationRates)
+	}
+}
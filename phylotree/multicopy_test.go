@@ -0,0 +1,49 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestMultiCopyOrder checks that the order of the values of a
+// multi-copy marker does not count as mutations. The clades A and
+// B differ only in the order of DYS385, 11-14 and 14-11.
+func TestMultiCopyOrder(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14, "DYS391": 10, "DYS385a": 11, "DYS385b": 14}
+	swapped := withValue(withValue(base, "DYS385a", 14), "DYS385b", 11)
+	persons := []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", base),
+		newPerson(t, "3", swapped),
+		newPerson(t, "4", swapped),
+	}
+	calculate := func(distance genetic.DistanceFunc) (tree *Clade, count float64) {
+		tree = mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n")
+		tree.InsertPersons(persons)
+		tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(tree.SamplePersons()), 4, false)
+		tree.CalculateDistances(genetic.DefaultMutationRates(), distance)
+		tree.WalkSamples(func(path []*Clade, s *Sample) error {
+			count += s.STRCount
+			return nil
+		})
+		return tree, count
+	}
+	if _, before := calculate(genetic.DistanceHybrid); before <= 0 {
+		t.Errorf("STR count without sorting = %g, want > 0", before)
+	}
+	tree, after := calculate(MultiCopyDistance(genetic.DistanceHybrid))
+	if after != 0 {
+		t.Errorf("STR count with sorting = %g, want 0", after)
+	}
+	if markers := tree.MutatingMarkers(); len(markers) != 0 {
+		t.Errorf("mutating markers %v, want none", markers)
+	}
+	if mutations := tree.Mutations(); len(mutations) != 0 {
+		t.Errorf("mutations %v, want none", mutations)
+	}
+	if changes := tree.TraceChanges(); strings.Contains(changes, "DYS385") {
+		t.Errorf("trace changes:\n%s", changes)
+	}
+}
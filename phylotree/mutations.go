@@ -0,0 +1,156 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// Mutation is a mutation event on the edge between a parent clade
+// and a child, which is a subclade or a sample. It is inferred by
+// comparing the modal haplotype of the parent with the modal
+// haplotype or the values of the child.
+type Mutation struct {
+	// Parent is the name of the parent clade.
+	Parent string
+	// Child is the name of the subclade or id:ID for a sample.
+	Child string
+	// IsUnknown is true if the parent or the child has no
+	// Y-STR data. Then no other fields are set.
+	IsUnknown bool
+	// Marker is the index of the marker.
+	Marker int
+	From   float64
+	To     float64
+	// Steps is the number of mutation steps. It is negative
+	// if the value has decreased. A change to or from Null is
+	// one step, see nullSteps.
+	Steps float64
+	// IsReversal is true if the child returns to the value that
+	// the marker had in an ancestor before the parent's value.
+	IsReversal bool
+}
+
+// Direction returns + for an increased value and - for a
+// decreased value.
+func (m Mutation) Direction() string {
+	if m.Steps < 0 {
+		return "-"
+	}
+	return "+"
+}
+
+// String returns the mutation as a single line of text.
+func (m Mutation) String() string {
+	if m.IsUnknown {
+		return fmt.Sprintf("%s > %s: unknown", m.Parent, m.Child)
+	}
+	result := fmt.Sprintf("%s > %s: %s %s > %s, %s%g",
+		m.Parent, m.Child, genetic.YstrMarkerTable[m.Marker].InternalName,
+		formatValue(m.From), formatValue(m.To), m.Direction(), math.Abs(m.Steps))
+	if m.IsReversal {
+		result += ", reversal"
+	}
+	return result
+}
+
+// Mutations returns all mutation events on the edges of this tree
+// in tree order. Edges without Y-STR data on either side are
+// returned as unknown. Uncertain and missing marker values are
+// not counted as mutations, changes to or from Null values are.
+// The values of multi-copy markers are sorted before they are
+// compared.
+// The modal haplotypes must be calculated before.
+func (c *Clade) Mutations() []Mutation {
+	var result []Mutation
+	edge := func(path []*Clade, parent *Clade, child string, person *genetic.Person) {
+		if parent.Person == nil || person == nil {
+			result = append(result, Mutation{Parent: parent.SNPs[0], Child: child, IsUnknown: true})
+			return
+		}
+		from, to := parent.Person.YstrMarkers, person.YstrMarkers
+		SortMultiCopy(&from)
+		SortMultiCopy(&to)
+		for i, _ := range from {
+			if isAllele(from[i]) && isAllele(to[i]) && from[i] != to[i] {
+				result = append(result, Mutation{
+					Parent:     parent.SNPs[0],
+					Child:      child,
+					Marker:     i,
+					From:       from[i],
+					To:         to[i],
+					Steps:      nullSteps(from[i], to[i]),
+					IsReversal: isReversal(path, i, from[i], to[i])})
+			}
+		}
+	}
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		for i, _ := range clade.Samples {
+			edge(path, clade, "id:"+clade.Samples[i].ID, clade.Samples[i].Person)
+		}
+		for i, _ := range clade.Subclades {
+			edge(path, clade, clade.Subclades[i].SNPs[0], clade.Subclades[i].Person)
+		}
+		return nil
+	})
+	return result
+}
+
+// isReversal checks if a mutation of marker from the parent's value
+// to value returns to an earlier value. path contains the ancestors
+// of the parent. The earlier value is the value of the nearest
+// ancestor that differs from the parent's value.
+func isReversal(path []*Clade, marker int, parent, value float64) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Person == nil {
+			continue
+		}
+		ancestor := path[i].Person.YstrMarkers[marker]
+		if isAllele(ancestor) && ancestor != parent {
+			return ancestor == value
+		}
+	}
+	return false
+}
+
+// MutationsReport returns all mutation events returned by
+// Mutations, one per line.
+func (c *Clade) MutationsReport() string {
+	var buffer bytes.Buffer
+	for _, m := range c.Mutations() {
+		buffer.WriteString(m.String() + "\r\n")
+	}
+	return buffer.String()
+}
+
+// MutationsCSV returns all mutation events returned by Mutations
+// as a table in CSV format. The marker cells of unknown edges
+// are empty.
+func (c *Clade) MutationsCSV() (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	writer.Write([]string{"Parent", "Child", "Marker", "From", "To", "Direction", "Steps", "Reversal", "Unknown"})
+	for _, m := range c.Mutations() {
+		if m.IsUnknown {
+			writer.Write([]string{m.Parent, m.Child, "", "", "", "", "", "", "true"})
+			continue
+		}
+		writer.Write([]string{m.Parent, m.Child,
+			genetic.YstrMarkerTable[m.Marker].InternalName,
+			formatValue(m.From),
+			formatValue(m.To),
+			m.Direction(),
+			fmt.Sprintf("%g", math.Abs(m.Steps)),
+			fmt.Sprintf("%t", m.IsReversal),
+			"false"})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
@@ -0,0 +1,85 @@
+package phylotree
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultNegativePrefix marks a negative SNP call in the input tree,
+// for example xZ301 means that the sample has been tested
+// negative for Z301.
+const DefaultNegativePrefix = "x"
+
+// negativeSNP returns the SNP name of a negative call and true
+// if token is a negative call. A negative call starts with
+// prefix followed by an upper case letter or a digit, so that
+// ordinary SNP names are not mistaken for negative calls.
+// An empty prefix turns off negative calls.
+func negativeSNP(token, prefix string) (string, bool) {
+	if prefix == "" || !strings.HasPrefix(token, prefix) {
+		return "", false
+	}
+	name := token[len(prefix):]
+	r, _ := utf8.DecodeRuneInString(name)
+	if name == "" || !(unicode.IsUpper(r) || unicode.IsDigit(r)) {
+		return "", false
+	}
+	return name, true
+}
+
+// addToken adds token to the SNPs of this element or to the
+// negative SNPs if token is a negative call that starts
+// with prefix. The prefix is kept to write the negative SNPs.
+func (e *Element) addToken(token, prefix string) {
+	if name, ok := negativeSNP(token, prefix); ok {
+		e.NegativeSNPs = append(e.NegativeSNPs, name)
+		e.negativePrefix = prefix
+	} else {
+		e.AddSNP(token)
+	}
+}
+
+// writtenNegativePrefix returns the prefix that is used to write
+// the negative SNPs of this element. It is the prefix of the input
+// tree or DefaultNegativePrefix for negative SNPs added otherwise.
+func (e *Element) writtenNegativePrefix() string {
+	if e.negativePrefix == "" {
+		return DefaultNegativePrefix
+	}
+	return e.negativePrefix
+}
+
+// IsNegative checks if this element has been tested negative
+// for searchTerm.
+func (e *Element) IsNegative(searchTerm string) bool {
+	for _, snp := range e.NegativeSNPs {
+		if snpMatches(snp, searchTerm) {
+			return true
+		}
+	}
+	return false
+}
+
+// InspectNegative looks at this clade and all subclades and
+// lists all clades and samples that have been tested negative
+// for one of the search terms.
+func (c *Clade) InspectNegative(searchTerms []string) string {
+	var buffer bytes.Buffer
+	for _, term := range searchTerms {
+		c.Walk(func(path []*Clade, clade *Clade) error {
+			if clade.IsNegative(term) {
+				buffer.WriteString("Negative for " + term + ": " + clade.Element.String() + "\r\n")
+			}
+			for i, _ := range clade.Samples {
+				if clade.Samples[i].IsNegative(term) {
+					buffer.WriteString("Negative for " + term + ": " + clade.Samples[i].String() +
+						" in " + clade.SNPs[0] + "\r\n")
+				}
+			}
+			return nil
+		})
+	}
+	return buffer.String()
+}
@@ -0,0 +1,38 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNegativePrefix checks that negative calls are read with the
+// prefix of the read options and written with the same prefix.
+func TestNegativePrefix(t *testing.T) {
+	text := "R\r\n\tA, xZ301, neg-Z302\r\n\t\tid:1, neg-Z303\r\n"
+	tests := []struct {
+		prefix   string
+		negative []string
+		written  string
+	}{
+		{DefaultNegativePrefix, []string{"Z301"}, "A, neg-Z302, xZ301"},
+		{"neg-", []string{"Z302", "Z303"}, "A, xZ301, neg-Z302"},
+		{"", nil, "A, xZ301, neg-Z302"},
+	}
+	for _, test := range tests {
+		options := DefaultReadOptions()
+		options.NegativePrefix = test.prefix
+		tree, err := NewFromReaderOptions(strings.NewReader(text), options)
+		if err != nil {
+			t.Fatalf("prefix %q, %v", test.prefix, err)
+		}
+		for _, snp := range test.negative {
+			if tree.InspectNegative([]string{snp}) == "" {
+				t.Errorf("prefix %q, %s is not negative", test.prefix, snp)
+			}
+		}
+		clade := tree.Subclades[0]
+		if written := clade.Element.String(); !strings.HasPrefix(written, test.written) {
+			t.Errorf("prefix %q, written %q, expected %q", test.prefix, written, test.written)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Newick returns this tree in Newick format. Samples are leaves,
+// labeled by their IDs. Clades are internal nodes, labeled by their
+// first SNPs. The branch lengths are the differences of the TMRCAs
+// in years. If no ages have been calculated for the root, the STR
+// counts are used instead. The unit is the same for the whole tree,
+// see inYears.
+func (c *Clade) Newick() string {
+	var buffer bytes.Buffer
+	c.newickPrint(&buffer, nil, c.inYears(), func(s *Sample) string { return newickLabel(s.ID) })
+	buffer.WriteString(";\r\n")
+	return buffer.String()
+}
+
+// newickPrint writes this clade and all of it's descendants in Newick
+// format into buffer. parent is the parent clade, nil for the root.
+// years specifies the unit of the branch lengths, see inYears.
+// sampleLabel returns the label of a sample.
+func (c *Clade) newickPrint(buffer *bytes.Buffer, parent *Clade, years bool, sampleLabel func(s *Sample) string) {
+	if len(c.Samples) > 0 || len(c.Subclades) > 0 {
+		buffer.WriteString("(")
+		n := 0
+		for i, _ := range c.Samples {
+			if n > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(sampleLabel(&c.Samples[i]))
+			buffer.WriteString(newickLength(c.sampleBranchLength(&c.Samples[i], years)))
+			n++
+		}
+		for i, _ := range c.Subclades {
+			if n > 0 {
+				buffer.WriteString(",")
+			}
+			c.Subclades[i].newickPrint(buffer, c, years, sampleLabel)
+			n++
+		}
+		buffer.WriteString(")")
+	}
+	buffer.WriteString(newickLabel(c.SNPs[0]))
+	if parent != nil {
+		buffer.WriteString(newickLength(parent.subcladeBranchLength(c, years)))
+	}
+}
+
+// hasAges returns true if the ages of this clade have been calculated.
+func (c *Clade) hasAges() bool {
+	return c.STRCountDownstream >= 0 && c.TMRCA_STR > 0
+}
+
+// inYears returns true if the branch lengths of this tree are
+// given in years. This is the case if the ages of the root have
+// been calculated. Otherwise the branch lengths are STR counts.
+// Years and STR counts are never mixed in one tree.
+func (c *Clade) inYears() bool {
+	return c.hasAges()
+}
+
+// sampleBranchLength returns the length of the branch from this
+// clade to sample in years or as STR count, see inYears. The branch
+// of an ancient sample ends at it's sampling age. The result is
+// Uncertain if it is unknown.
+func (c *Clade) sampleBranchLength(sample *Sample, years bool) float64 {
+	if !years {
+		return sample.STRCount
+	}
+	if c.hasAges() {
+		return c.TMRCA_STR - sample.Sampled
+	}
+	return Uncertain
+}
+
+// subcladeBranchLength returns the length of the branch from this
+// clade to subclade in years or as STR count, see inYears. The
+// result is Uncertain if it is unknown.
+func (c *Clade) subcladeBranchLength(subclade *Clade, years bool) float64 {
+	if !years {
+		return subclade.STRCount
+	}
+	if c.hasAges() && subclade.hasAges() {
+		return c.TMRCA_STR - subclade.TMRCA_STR
+	}
+	return Uncertain
+}
+
+// newickLength returns the Newick representation of a branch length.
+// The result is empty if length is unknown.
+func newickLength(length float64) string {
+	if length < 0 {
+		return ""
+	}
+	return fmt.Sprintf(":%.0f", length)
+}
+
+// newickLabel returns label quoted according to the Newick
+// specification, if it contains special characters.
+func newickLabel(label string) string {
+	if strings.ContainsAny(label, " \t()[]':;,") {
+		return "'" + strings.Replace(label, "'", "''", -1) + "'"
+	}
+	return label
+}
+
+// NewFromNewick reads a tree in Newick format from a file.
+// Leaves become samples, labeled by their IDs. Internal nodes
+// become clades, named by their labels. Unlabeled internal nodes
+// get placeholder names like NODE_17. If useLengths is true, the
+// branch lengths are used as STR counts.
+func NewFromNewick(filename string, useLengths bool) (*Clade, error) {
+	infile, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+	text, err := ioutil.ReadAll(infile)
+	if err != nil {
+		return nil, err
+	}
+	return parseNewick(decodeText(text), useLengths)
+}
+
+// isNewick returns true if text looks like a tree in Newick format.
+func isNewick(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "(")
+}
+
+// newickParser parses a tree in Newick format.
+type newickParser struct {
+	text string
+	pos  int
+	// nodes is the number of internal nodes so far.
+	nodes      int
+	useLengths bool
+}
+
+// newickNode is a node of a Newick tree. It is either a clade
+// or a sample, if it is a leaf.
+type newickNode struct {
+	clade  *Clade
+	sample *Sample
+}
+
+// parseNewick creates a tree from text in Newick format.
+func parseNewick(text string, useLengths bool) (*Clade, error) {
+	p := newickParser{text: text, useLengths: useLengths}
+	node, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos >= len(p.text) || p.text[p.pos] != ';' {
+		return nil, p.error("missing ;")
+	}
+	if node.clade == nil {
+		return nil, errors.New("Newick tree contains no clade")
+	}
+	return node.clade, nil
+}
+
+// error returns an error that contains the current position.
+func (p *newickParser) error(msg string) error {
+	return errors.New(fmt.Sprintf("invalid Newick format at position %d: %s", p.pos, msg))
+}
+
+// skipSpace skips white space and comments in square brackets.
+func (p *newickParser) skipSpace() {
+	for p.pos < len(p.text) {
+		switch {
+		case unicode.IsSpace(rune(p.text[p.pos])):
+			p.pos++
+		case p.text[p.pos] == '[':
+			end := strings.IndexByte(p.text[p.pos:], ']')
+			if end < 0 {
+				p.pos = len(p.text)
+			} else {
+				p.pos += end + 1
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next character that is not white space
+// or 0 at the end of the text.
+func (p *newickParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.text) {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+// parseNode parses a subtree:
+// ["(" node {"," node} ")"] [label] [":" length]
+func (p *newickParser) parseNode() (newickNode, error) {
+	var children []newickNode
+	if p.peek() == '(' {
+		p.pos++
+		for {
+			child, err := p.parseNode()
+			if err != nil {
+				return newickNode{}, err
+			}
+			children = append(children, child)
+			c := p.peek()
+			p.pos++
+			if c == ')' {
+				break
+			}
+			if c != ',' {
+				p.pos--
+				return newickNode{}, p.error("expected , or )")
+			}
+		}
+	}
+	label, err := p.parseLabel()
+	if err != nil {
+		return newickNode{}, err
+	}
+	length := float64(Uncertain)
+	if p.peek() == ':' {
+		p.pos++
+		length, err = p.parseLength()
+		if err != nil {
+			return newickNode{}, err
+		}
+	}
+
+	// Create sample for leaves.
+	if children == nil {
+		if label == "" {
+			return newickNode{}, p.error("leaf without label")
+		}
+		sample := newSample()
+		sample.ID = label
+		if p.useLengths {
+			sample.STRCount = length
+		}
+		return newickNode{sample: &sample}, nil
+	}
+
+	// Create clade for internal nodes.
+	p.nodes++
+	if label == "" {
+		label = fmt.Sprintf("NODE_%d", p.nodes)
+	}
+	clade, err := newClade(label, DefaultNegativePrefix)
+	if err != nil {
+		return newickNode{}, err
+	}
+	if p.useLengths {
+		clade.STRCount = length
+	}
+	for _, child := range children {
+		if child.sample != nil {
+			clade.AddSample(*child.sample)
+		} else {
+			clade.AttachSubclade(child.clade)
+		}
+	}
+	return newickNode{clade: &clade}, nil
+}
+
+// parseLabel parses a quoted or unquoted label. The result is
+// empty if there is no label.
+func (p *newickParser) parseLabel() (string, error) {
+	if p.peek() == '\'' {
+		var buffer bytes.Buffer
+		p.pos++
+		for {
+			if p.pos >= len(p.text) {
+				return "", p.error("missing closing quote")
+			}
+			c := p.text[p.pos]
+			p.pos++
+			if c == '\'' {
+				if p.pos < len(p.text) && p.text[p.pos] == '\'' {
+					// Escaped quote.
+					p.pos++
+				} else {
+					break
+				}
+			}
+			buffer.WriteByte(c)
+		}
+		return buffer.String(), nil
+	}
+	start := p.pos
+	for p.pos < len(p.text) && !strings.ContainsRune("(),:;[", rune(p.text[p.pos])) && !unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	return p.text[start:p.pos], nil
+}
+
+// parseLength parses the length of a branch.
+func (p *newickParser) parseLength() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.text) && strings.ContainsRune("0123456789.eE+-", rune(p.text[p.pos])) {
+		p.pos++
+	}
+	length, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+	if err != nil {
+		return 0, p.error("invalid branch length")
+	}
+	return length, nil
+}
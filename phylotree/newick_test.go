@@ -0,0 +1,66 @@
+package phylotree
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNewickRoundTrip writes a tree without ages in Newick format
+// and reads it back. The STR counts must be preserved.
+func TestNewickRoundTrip(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 12\r\n\tid:3, STR-Count: 40\r\n")
+	tree.Subclade("A").STRCount = 25
+	text := tree.Newick()
+	if want := "(3:40,(1:10,2:12)A:25)R;\r\n"; text != want {
+		t.Fatalf("Newick() = %q, want %q", text, want)
+	}
+
+	dir, err := ioutil.TempDir("", "newick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "tree.nwk")
+	if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewFromNewick(filename, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Newick() != text {
+		t.Errorf("round trip = %q, want %q", got.Newick(), text)
+	}
+	a := got.Subclade("A")
+	if a == nil {
+		t.Fatal("clade A not found")
+	}
+	if a.STRCount != 25 {
+		t.Errorf("STR count of A = %g, want 25", a.STRCount)
+	}
+	for _, s := range a.Samples {
+		if want := map[string]float64{"1": 10, "2": 12}[s.ID]; s.STRCount != want {
+			t.Errorf("STR count of id:%s = %g, want %g", s.ID, s.STRCount, want)
+		}
+	}
+}
+
+// TestNewickYears checks that the branch lengths are given in years
+// for the whole tree if the root has ages, and that the branches of
+// ancient samples end at their sampling age.
+func TestNewickYears(t *testing.T) {
+	tree := threeLevelTree(t)
+	// B has no ages, so it's branch length is unknown. The STR count
+	// must not be mixed into a tree measured in years.
+	b := tree.Subclade("B")
+	b.STRCountDownstream, b.TMRCA_STR = Uncertain, 0
+	tree.Samples[0].Sampled = 320
+
+	got := tree.Newick()
+	want := "(4:1000,(1:500,(2,3)B)A:820)R;\r\n"
+	if got != want {
+		t.Errorf("Newick() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Nexus returns this tree in NEXUS format. The TAXA block lists all
+// samples. The TREES block contains the tree in Newick format and a
+// translate table, that maps the numbers of the leaves to the sample
+// IDs. The branch lengths are the same as for the Newick format.
+// Samples that are listed more than once are an error.
+func (c *Clade) Nexus() (string, error) {
+	ids := c.sampleIDs()
+	numbers := make(map[string]int)
+	var duplicates []string
+	for i, id := range ids {
+		if _, exists := numbers[id]; exists {
+			duplicates = append(duplicates, id)
+		}
+		numbers[id] = i + 1
+	}
+	if len(duplicates) > 0 {
+		return "", errors.New("duplicate sample IDs: " + strings.Join(duplicates, ", "))
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("#NEXUS\r\n\r\n")
+	buffer.WriteString("BEGIN TAXA;\r\n")
+	buffer.WriteString(fmt.Sprintf("\tDIMENSIONS NTAX=%d;\r\n", len(ids)))
+	buffer.WriteString("\tTAXLABELS\r\n")
+	for _, id := range ids {
+		buffer.WriteString(fmt.Sprintf("\t\t%s\r\n", newickLabel(id)))
+	}
+	buffer.WriteString("\t;\r\nEND;\r\n\r\n")
+
+	buffer.WriteString("BEGIN TREES;\r\n")
+	buffer.WriteString("\tTRANSLATE\r\n")
+	for i, id := range ids {
+		separator := ","
+		if i == len(ids)-1 {
+			separator = ""
+		}
+		buffer.WriteString(fmt.Sprintf("\t\t%d %s%s\r\n", i+1, newickLabel(id), separator))
+	}
+	buffer.WriteString("\t;\r\n")
+	buffer.WriteString("\tTREE phyloage = ")
+	c.newickPrint(&buffer, nil, c.inYears(), func(s *Sample) string { return fmt.Sprintf("%d", numbers[s.ID]) })
+	buffer.WriteString(";\r\nEND;\r\n")
+	return buffer.String(), nil
+}
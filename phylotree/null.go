@@ -0,0 +1,94 @@
+package phylotree
+
+import (
+	"fmt"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// Null is used for null alleles. The marker has been tested,
+// but no value could be determined, for example DYS425 = 0.
+// A value of 0 means that the marker has not been tested.
+const Null = -2
+
+// MarkNulls sets the markers specified by indices to Null for all
+// persons that have tested the marker, but have no value for it.
+// A marker has been tested if it is part of the panel returned by
+// TestedPanel. The result is the number of Null values.
+func MarkNulls(persons []*genetic.Person, indices []int) int {
+	count := 0
+	for _, person := range persons {
+		tested := testedMarkers(TestedPanel(person))
+		for _, i := range indices {
+			if i < tested && person.YstrMarkers[i] == 0 {
+				person.YstrMarkers[i] = Null
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// isAllele returns true if value is a marker value that can be
+// compared with other values, a real value or Null.
+func isAllele(value float64) bool {
+	return value > 0 || value == Null
+}
+
+// nullSteps returns the number of mutation steps from one marker
+// value to another like alleleSteps. A change to Null counts as
+// one step down, a change from Null as one step up.
+func nullSteps(from, to float64) float64 {
+	switch {
+	case from == to:
+		return 0
+	case to == Null:
+		return -1
+	case from == Null:
+		return 1
+	}
+	return alleleSteps(from, to)
+}
+
+// testedMarkers returns the number of markers of the panel with
+// the given name. For Y500 tests all markers are tested.
+func testedMarkers(panelName string) int {
+	if panelName == panelY500 {
+		return len(genetic.YstrMarkers{})
+	}
+	for _, p := range panels {
+		if p.name == panelName {
+			return p.last
+		}
+	}
+	return 0
+}
+
+// NullDistance returns a distance function that counts Null
+// values as separate alleles before the distance is calculated
+// by distance. A Null value and a real value differ by one
+// mutation, two Null values are equal.
+func NullDistance(distance genetic.DistanceFunc) genetic.DistanceFunc {
+	return func(ystr1, ystr2 genetic.YstrMarkers, mutationRates genetic.YstrMarkers) float64 {
+		for i, _ := range ystr1 {
+			switch {
+			case ystr1[i] == Null && ystr2[i] == Null:
+				ystr1[i], ystr2[i] = 1, 1
+			case ystr1[i] == Null && ystr2[i] > 0:
+				ystr1[i] = ystr2[i] + 1
+			case ystr2[i] == Null && ystr1[i] > 0:
+				ystr2[i] = ystr1[i] + 1
+			}
+		}
+		return distance(ystr1, ystr2, mutationRates)
+	}
+}
+
+// formatValue returns a marker value as text. Null values
+// are returned as null.
+func formatValue(value float64) string {
+	if value == Null {
+		return "null"
+	}
+	return fmt.Sprintf("%g", value)
+}
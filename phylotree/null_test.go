@@ -0,0 +1,35 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestNullChanges checks that changes to and from null alleles are
+// mutations that are traced and printed as null.
+func TestNullChanges(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS425": 12}
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		newPerson(t, "1", withValue(base, "DYS425", Null)),
+		newPerson(t, "2", withValue(base, "DYS425", Uncertain)),
+	})
+	tree.Person = newPerson(t, "R", base)
+	a := tree.Subclade("A")
+	a.Person = newPerson(t, "A", base)
+
+	dys425 := mustIndex(t, "DYS425")
+	if markers := tree.MutatingMarkers(); len(markers) != 1 || markers[0] != dys425 {
+		t.Errorf("mutating markers %v, want [%d]", markers, dys425)
+	}
+	mutations := tree.Mutations()
+	if len(mutations) != 1 || mutations[0].String() != "A > id:1: DYS425 12 > null, -1" {
+		t.Errorf("mutations %v", mutations)
+	}
+	changes := tree.TraceChanges()
+	if !strings.Contains(changes, "id:1, DYS425: null*,") || !strings.Contains(changes, "id:2, DYS425: ?,") {
+		t.Errorf("trace changes:\n%s", changes)
+	}
+}
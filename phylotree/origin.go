@@ -0,0 +1,76 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+)
+
+// unknownOrigin is used for samples without origin.
+const unknownOrigin = "unknown"
+
+// originCounts returns the number of downstream samples
+// per origin and the total number of samples.
+func (c *Clade) originCounts(counts map[string]int) int {
+	total := 0
+	for i, _ := range c.Samples {
+		origin := c.Samples[i].Origin
+		if origin == "" {
+			origin = unknownOrigin
+		}
+		counts[origin]++
+		total++
+	}
+	for i, _ := range c.Subclades {
+		total += c.Subclades[i].originCounts(counts)
+	}
+	return total
+}
+
+// OriginReport returns a table in CSV format that contains the
+// number of downstream samples per origin for each clade.
+// maxDepth limits the depth of the tree. 0 means no limit.
+// Clades with less than minSamples samples are omitted.
+func (c *Clade) OriginReport(maxDepth, minSamples int) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	writer.Write([]string{"Clade", "Origin", "Samples", "Percentage"})
+	c.originPrint(writer, 1, maxDepth, minSamples)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// originPrint writes the rows for OriginReport.
+func (c *Clade) originPrint(writer *csv.Writer, depth, maxDepth, minSamples int) {
+	if maxDepth > 0 && depth > maxDepth {
+		return
+	}
+	counts := make(map[string]int)
+	total := c.originCounts(counts)
+	if total > 0 && total >= minSamples {
+		origins := make([]string, 0, len(counts))
+		for origin, _ := range counts {
+			origins = append(origins, origin)
+		}
+		// Sort by number of samples, then by name.
+		sort.Slice(origins, func(i, j int) bool {
+			if counts[origins[i]] != counts[origins[j]] {
+				return counts[origins[i]] > counts[origins[j]]
+			}
+			return origins[i] < origins[j]
+		})
+		for _, origin := range origins {
+			writer.Write([]string{c.SNPs[0], origin,
+				fmt.Sprintf("%d", counts[origin]),
+				fmt.Sprintf("%.1f", 100*float64(counts[origin])/float64(total))})
+		}
+	}
+	for i, _ := range c.Subclades {
+		c.Subclades[i].originPrint(writer, depth+1, maxDepth, minSamples)
+	}
+}
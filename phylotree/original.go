@@ -0,0 +1,27 @@
+package phylotree
+
+import (
+	"fmt"
+	"math"
+)
+
+// TMRCAComparison compares the calculated TMRCAs with the TMRCAs
+// from the input tree, for example values published by YFull.
+// The result is a summary with the mean absolute deviation of
+// all clades that have both values.
+func (c *Clade) TMRCAComparison() string {
+	n := 0
+	sum := 0.0
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.TMRCAoriginal >= 0 && clade.STRCountDownstream >= 0 {
+			n++
+			sum += math.Abs(clade.TMRCA_STR - clade.TMRCAoriginal)
+		}
+		return nil
+	})
+	if n == 0 {
+		return "No clades with input TMRCAs to compare.\r\n"
+	}
+	return fmt.Sprintf("Compared TMRCAs with input values: clades: %d, mean absolute deviation: %.0f\r\n",
+		n, sum/float64(n))
+}
@@ -0,0 +1,158 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// Outlier is a sample that looks suspicious, because it's STR count
+// is far beyond the distribution of it's clade or because it is
+// closer to the modal haplotype of a sibling clade.
+type Outlier struct {
+	// ID is the kit ID of the sample.
+	ID    string
+	Clade string
+	// STRCount is the distance to the modal haplotype of the clade.
+	STRCount float64
+	// Mean and Sigma are the mean and the standard deviation of the
+	// STR counts of the other samples of the clade, see leaveOneOut.
+	Mean  float64
+	Sigma float64
+	// Z is the z-score of STRCount. It is 0 if the sample is not
+	// an outlier of the distribution.
+	Z float64
+	// Sibling is the name of the sibling clade whose modal haplotype
+	// is closer to the sample than the modal haplotype of the clade.
+	// It is empty if there is none.
+	Sibling string
+	// SiblingDistance is the distance to the modal of Sibling.
+	SiblingDistance float64
+}
+
+// String returns the outlier as a single line of text.
+func (o Outlier) String() string {
+	result := fmt.Sprintf("id:%s, clade: %s, STRs: %.2f, mean of others: %.2f", o.ID, o.Clade, o.STRCount, o.Mean)
+	if o.Z > 0 {
+		result += fmt.Sprintf(", z: %.1f", o.Z)
+	}
+	if o.Sibling != "" {
+		result += fmt.Sprintf(", closer to %s: %.2f", o.Sibling, o.SiblingDistance)
+	}
+	return result
+}
+
+// Outliers returns all samples whose STR count exceeds the mean of
+// the other samples of their clade by more than minZ standard
+// deviations and all samples
+// that are closer to the modal haplotype of a sibling clade than to
+// the modal haplotype of their own clade. distance and mutationRates
+// are used to calculate the distances to the sibling clades.
+// The result is in tree order.
+// The distances must be calculated before.
+func (c *Clade) Outliers(minZ float64, mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) []Outlier {
+	var result []Outlier
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.Person == nil {
+			return nil
+		}
+		// Distribution of the STR counts.
+		var counts []float64
+		for i, _ := range clade.Samples {
+			if clade.Samples[i].Person != nil && !clade.Samples[i].isExcluded() {
+				counts = append(counts, clade.Samples[i].STRCount)
+			}
+		}
+		// index is the index of the sample in counts.
+		index := 0
+		for i, _ := range clade.Samples {
+			sample := &clade.Samples[i]
+			if sample.Person == nil || sample.isExcluded() {
+				continue
+			}
+			mean, sigma := leaveOneOut(counts, index)
+			index++
+			outlier := Outlier{ID: sample.ID, Clade: clade.SNPs[0], STRCount: sample.STRCount, Mean: mean, Sigma: sigma}
+			if sigma > 0 && (sample.STRCount-mean)/sigma > minZ {
+				outlier.Z = (sample.STRCount - mean) / sigma
+			}
+			if len(path) > 0 {
+				outlier.Sibling, outlier.SiblingDistance = closerSibling(path[len(path)-1], clade, sample, mutationRates, distance)
+			}
+			if outlier.Z > 0 || outlier.Sibling != "" {
+				result = append(result, outlier)
+			}
+		}
+		return nil
+	})
+	return result
+}
+
+// closerSibling returns the sibling of clade within parent whose
+// modal haplotype is closest to the sample, if it is closer than
+// the modal haplotype of clade.
+func closerSibling(parent, clade *Clade, sample *Sample, mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) (name string, dist float64) {
+	dist = sample.STRCount
+	for _, sibling := range parent.Subclades {
+		if sibling == clade || sibling.Person == nil {
+			continue
+		}
+		d := distance(sample.Person.YstrMarkers, sibling.Person.YstrMarkers, mutationRates)
+		if d < dist {
+			name, dist = sibling.SNPs[0], d
+		}
+	}
+	return name, dist
+}
+
+// leaveOneOut returns the mean and the standard deviation of values
+// without the value at index skip, so that an outlier does not hide
+// itself by raising the mean and the standard deviation. The standard
+// deviation is at least the one of a Poisson distribution with the
+// same mean, because the STR counts of a few samples may be identical.
+// sigma is 0 if there are no other values.
+func leaveOneOut(values []float64, skip int) (mean, sigma float64) {
+	others := make([]float64, 0, len(values))
+	others = append(others, values[:skip]...)
+	others = append(others, values[skip+1:]...)
+	if len(others) == 0 {
+		return 0, 0
+	}
+	mean, sigma = meanSigma(others)
+	if poisson := math.Sqrt(mean); poisson > sigma {
+		sigma = poisson
+	}
+	return mean, sigma
+}
+
+// meanSigma returns the mean and the sample standard deviation
+// of values. sigma is 0 for fewer than two values.
+func meanSigma(values []float64) (mean, sigma float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	sum2 := 0.0
+	for _, v := range values {
+		sum2 += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sum2 / float64(len(values)-1))
+}
+
+// OutliersReport returns all outliers returned by Outliers,
+// one per line.
+func (c *Clade) OutliersReport(minZ float64, mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) string {
+	var buffer bytes.Buffer
+	for _, o := range c.Outliers(minZ, mutationRates, distance) {
+		buffer.WriteString(o.String() + "\r\n")
+	}
+	return buffer.String()
+}
@@ -0,0 +1,34 @@
+package phylotree
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestOutliers checks that the single outlier of a small clade is
+// found, although it raises the mean and the standard deviation of
+// all samples so much that it's z-score would be below 2.
+func TestOutliers(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tid:1, STR-Count: 10\r\n\tid:2, STR-Count: 11\r\n\tid:3, STR-Count: 12\r\n"+
+		"\tid:4, STR-Count: 10\r\n\tid:5, STR-Count: 60\r\n")
+	values := map[string]float64{"DYS393": 13, "DYS390": 24}
+	var persons []*genetic.Person
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		persons = append(persons, newPerson(t, id, values))
+	}
+	tree.InsertPersons(persons)
+	tree.Person = newPerson(t, "R", values)
+
+	outliers := tree.Outliers(3, genetic.DefaultMutationRates(), genetic.DistanceHybrid)
+	if len(outliers) != 1 {
+		t.Fatalf("outliers %v, want id:5", outliers)
+	}
+	o := outliers[0]
+	if o.ID != "5" || o.Mean != 10.75 || o.Z < 10 {
+		t.Errorf("outlier %s", o)
+	}
+	if mean, sigma := meanSigma([]float64{10, 11, 12, 10, 60}); (60-mean)/sigma > 2 {
+		t.Errorf("z-score including the sample = %g", (60-mean)/sigma)
+	}
+}
@@ -8,10 +8,12 @@ import (
 // for this clade and all of it's sublcades that satisfy the maximum
 // parsimony criterion. Because this is often not possible for all
 // values, those values are set to Uncertain.
-func (c *Clade) calculateModalHaplotypesMaxParsimony(isInfiniteAlleles bool) {
+// Values that are supported by less than minSupport downstream
+// samples are also set to Uncertain.
+func (c *Clade) calculateModalHaplotypesMaxParsimony(isInfiniteAlleles bool, limit StepLimit, minSupport int) {
 	// Calculate maximum parsimony for each marker.
 	for i, _ := range c.Person.YstrMarkers {
-		c.calculateMaxParsimony(i, isInfiniteAlleles)
+		c.calculateMaxParsimony(i, isInfiniteAlleles, limit, minSupport)
 	}
 }
 
@@ -19,26 +21,40 @@ func (c *Clade) calculateModalHaplotypesMaxParsimony(isInfiniteAlleles bool) {
 // of a marker for this clade and all of it's subclades.
 // If the method does not yield a clear result for a specific
 // marker value, that value is set to Uncertain.
-func (c *Clade) calculateMaxParsimony(marker int, isInfiniteAlleles bool) {
+// The values of multi-copy markers are sorted before they
+// are compared.
+// The return value is the number of downstream samples that
+// have a value for the marker.
+func (c *Clade) calculateMaxParsimony(marker int, isInfiniteAlleles bool, limit StepLimit, minSupport int) (support int) {
 	// Calculate modal value using only downstream samples
 	// and subclades.
 	var values []float64
+	var weights []float64
 	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
-			value := c.Samples[i].Person.YstrMarkers[marker]
+		if c.Samples[i].hasInfluence() {
+			value := markerValue(&c.Samples[i].Person.YstrMarkers, marker)
 			if value != 0 {
 				values = append(values, value)
+				weights = append(weights, c.Samples[i].Weight)
+				support++
 			}
 		}
 	}
 	for i, _ := range c.Subclades {
-		c.Subclades[i].calculateMaxParsimony(marker, isInfiniteAlleles)
-		value := c.Subclades[i].Person.YstrMarkers[marker]
+		support += c.Subclades[i].calculateMaxParsimony(marker, isInfiniteAlleles, limit, minSupport)
+		value := markerValue(&c.Subclades[i].Person.YstrMarkers, marker)
 		if value != 0 {
 			values = append(values, value)
+			weights = append(weights, 1)
 		}
 	}
-	modal := maxParsimony(values, isInfiniteAlleles)
+	modal := maxParsimony(values, weights, isInfiniteAlleles, limit)
+	if isUnsupported(support, minSupport) {
+		modal = Uncertain
+	}
+	if fixed, exists := c.FixedValues[marker]; exists {
+		modal = fixed
+	}
 	c.Person.YstrMarkers[marker] = modal
 
 	// If we got a clear result, recalculate Uncertain values
@@ -47,10 +63,11 @@ func (c *Clade) calculateMaxParsimony(marker int, isInfiniteAlleles bool) {
 	if modal != Uncertain && modal != 0 {
 		for i, _ := range c.Subclades {
 			if c.Subclades[i].Person.YstrMarkers[marker] == Uncertain {
-				c.Subclades[i].recalculateMaxParsimony(marker, isInfiniteAlleles, c)
+				c.Subclades[i].recalculateMaxParsimony(marker, isInfiniteAlleles, limit, c)
 			}
 		}
 	}
+	return support
 }
 
 // recalculateMaxParsimony does nearly the same as calculateMaxParsimony,
@@ -58,24 +75,28 @@ func (c *Clade) calculateMaxParsimony(marker int, isInfiniteAlleles bool) {
 // This can yield to a clear result, if the the child value can not
 // be calculated from it's own child values, but the parent value is
 // clear because of parallel subclades.
-func (c *Clade) recalculateMaxParsimony(marker int, isInfiniteAlleles bool, parent *Clade) {
+func (c *Clade) recalculateMaxParsimony(marker int, isInfiniteAlleles bool, limit StepLimit, parent *Clade) {
 	var values []float64
-	values = append(values, parent.Person.YstrMarkers[marker])
+	var weights []float64
+	values = append(values, markerValue(&parent.Person.YstrMarkers, marker))
+	weights = append(weights, 1)
 	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
-			value := c.Samples[i].Person.YstrMarkers[marker]
+		if c.Samples[i].hasInfluence() {
+			value := markerValue(&c.Samples[i].Person.YstrMarkers, marker)
 			if value != 0 {
 				values = append(values, value)
+				weights = append(weights, c.Samples[i].Weight)
 			}
 		}
 	}
 	for i, _ := range c.Subclades {
-		value := c.Subclades[i].Person.YstrMarkers[marker]
+		value := markerValue(&c.Subclades[i].Person.YstrMarkers, marker)
 		if value != 0 {
 			values = append(values, value)
+			weights = append(weights, 1)
 		}
 	}
-	modal := maxParsimony(values, isInfiniteAlleles)
+	modal := maxParsimony(values, weights, isInfiniteAlleles, limit)
 	c.Person.YstrMarkers[marker] = modal
 
 	// If we got a clear result, recalculate Uncertain values
@@ -83,16 +104,27 @@ func (c *Clade) recalculateMaxParsimony(marker int, isInfiniteAlleles bool, pare
 	if modal != Uncertain && modal != 0 {
 		for i, _ := range c.Subclades {
 			if c.Subclades[i].Person.YstrMarkers[marker] == Uncertain {
-				c.Subclades[i].recalculateMaxParsimony(marker, isInfiniteAlleles, c)
+				c.Subclades[i].recalculateMaxParsimony(marker, isInfiniteAlleles, limit, c)
 			}
 		}
 	}
 }
 
+// isUnsupported returns true if a marker has been observed in
+// some downstream samples, but in less than minSupport.
+func isUnsupported(support, minSupport int) bool {
+	return support > 0 && support < minSupport
+}
+
 // maxParsimony returns the value from values that satisfies
 // the maximum parsimony criterion.
 // To calculate the distance, the stepwise mutation model is used.
+// Null values are treated like alleles of the infinite alleles
+// model, they differ from all other values by one mutation.
 // If no unique result can be found, the result is Uncertain.
+// weights contains a weight for each value. The distance to a
+// value is multiplied by it's weight. limit limits the number of
+// steps for the stepwise mutation model.
 //
 // I have compared multiple variations of this function using
 // YFull tree 4.03 and data from the M343 xU106 xP312 project.
@@ -100,11 +132,18 @@ func (c *Clade) recalculateMaxParsimony(marker int, isInfiniteAlleles bool, pare
 // method, using the stepwise mutation model, yielded the best
 // results. I have checked TMRCA and formed estimates for a
 // selection off different clades.
-func maxParsimony(values []float64, isInfiniteAlleles bool) float64 {
+func maxParsimony(values, weights []float64, isInfiniteAlleles bool, limit StepLimit) float64 {
 	// stepwiseDist is the distance between two mutational values
 	// using the stepwise mutation model.
+	// The number of steps is limited by limit.
 	var stepwiseDist = func(a, b float64) float64 {
-		return math.Abs(a - b)
+		if a == Null || b == Null {
+			if a == b {
+				return 0
+			}
+			return 1
+		}
+		return limit.steps(math.Abs(alleleSteps(a, b)))
 	}
 
 	// infiniteDist is the distance between two mutational values
@@ -125,21 +164,23 @@ func maxParsimony(values []float64, isInfiniteAlleles bool) float64 {
 		singleDist = stepwiseDist
 	}
 
-	// totalDist is the number of mutations neccessary to reach
-	// all values from x.
-	var totalDist = func(x float64, values []float64) float64 {
+	// totalDist is the weighted number of mutations neccessary
+	// to reach all values from x.
+	var totalDist = func(x float64, values, weights []float64) float64 {
 		dist := 0.0
-		for _, v := range values {
-			dist += singleDist(x, v)
+		for i, v := range values {
+			dist += singleDist(x, v) * weights[i]
 		}
 		return dist
 	}
 
-	// Use only positive values for calculation.
+	// Use only positive and Null values for calculation.
 	vals := make([]float64, 0, len(values))
-	for _, v := range values {
-		if v > 0 {
+	wghts := make([]float64, 0, len(values))
+	for i, v := range values {
+		if v > 0 || v == Null {
 			vals = append(vals, v)
+			wghts = append(wghts, weights[i])
 		}
 	}
 	// Test all values if one of them satisfies the minimum
@@ -147,7 +188,7 @@ func maxParsimony(values []float64, isInfiniteAlleles bool) float64 {
 	var result float64 = 0
 	minDist := math.Inf(1)
 	for _, x := range vals {
-		distance := totalDist(x, vals)
+		distance := totalDist(x, vals, wghts)
 		if distance < minDist {
 			minDist = distance
 			result = x
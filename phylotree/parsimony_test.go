@@ -0,0 +1,49 @@
+package phylotree
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestMinSupport checks that a value that has been observed in only
+// one of six kits does not define the modal value if the minimum
+// support is 2.
+func TestMinSupport(t *testing.T) {
+	base := map[string]float64{"DYS390": 24, "DYS19": 14}
+	persons := make([]*genetic.Person, 0)
+	for _, id := range []string{"1", "2", "3", "4", "5", "6"} {
+		persons = append(persons, newPerson(t, id, base))
+	}
+	persons[0] = newPerson(t, "1", withValue(base, "DYS393", 13))
+	marker := mustIndex(t, "DYS393")
+
+	tests := []struct {
+		minSupport int
+		want       float64
+	}{
+		{1, 13},
+		{2, Uncertain},
+	}
+	for _, test := range tests {
+		tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\t\tid:3\r\n\t\tid:4\r\n\t\tid:5\r\n\t\tid:6\r\n")
+		tree.InsertPersons(persons)
+		statistics := genetic.NewStatistics(persons)
+		tree.CalculateModalHaplotypesParsimonyLimited(statistics, 1, false, StepLimit{}, test.minSupport)
+		got := tree.Subclade("A").Person.YstrMarkers[marker]
+		if got != test.want {
+			t.Errorf("min support %d: modal DYS393 = %g, want %g", test.minSupport, got, test.want)
+		}
+		if got := tree.Subclade("A").Person.YstrMarkers[mustIndex(t, "DYS390")]; got != 24 {
+			t.Errorf("min support %d: modal DYS390 = %g, want 24", test.minSupport, got)
+		}
+	}
+
+	// The defaults use a minimum support of 1.
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\t\tid:3\r\n\t\tid:4\r\n\t\tid:5\r\n\t\tid:6\r\n")
+	tree.InsertPersons(persons)
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(persons), 1, false)
+	if got := tree.Subclade("A").Person.YstrMarkers[marker]; got != 13 {
+		t.Errorf("defaults: modal DYS393 = %g, want 13", got)
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"io/ioutil"
+	"sort"
 	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/yogischogi/phylofriend/genetic"
 )
@@ -22,10 +23,37 @@ type Element struct {
 	SNPs []string
 	// STRCount is the number of unique STR mutations for this element.
 	STRCount float64
+	// MarkersCompared is the number of markers that were used to
+	// calculate STRCount. Both haplotypes have values for these markers.
+	MarkersCompared int
+	// MarkersSkipped is the number of markers that could not be
+	// compared, because only one of the haplotypes has a value.
+	MarkersSkipped int
+	// lineNo is the line number in the input tree. 0 if unknown.
+	lineNo int
+	// RateCompared is the sum of the mutation rates of the
+	// markers that were used to calculate STRCount.
+	RateCompared float64
+	// coverage is RateCompared relative to the largest RateCompared
+	// in the tree. 0 if unknown.
+	coverage float64
 	// Person may be a real person from sample data
 	// or a virtual ancestor (modal haplotype).
 	// This may be nil.
 	Person *genetic.Person
+	// Comment is the comment at the end of the element's line
+	// in the input tree without the leading //. This may be empty.
+	Comment string
+	// Comments are the comment lines in front of the element's
+	// line in the input tree without the leading //.
+	Comments []string
+	// NegativeSNPs are the SNPs for which the element has been
+	// tested negative. They are written with the negative prefix
+	// of the input tree.
+	NegativeSNPs []string
+	// negativePrefix is the prefix of the negative SNPs in
+	// the input tree.
+	negativePrefix string
 }
 
 func newElement() Element {
@@ -33,21 +61,41 @@ func newElement() Element {
 	return Element{SNPs: snps, STRCount: Uncertain}
 }
 
+// clone returns a copy of this element including it's person.
+func (e *Element) clone() Element {
+	result := *e
+	result.SNPs = make([]string, len(e.SNPs))
+	copy(result.SNPs, e.SNPs)
+	if e.Comments != nil {
+		result.Comments = make([]string, len(e.Comments))
+		copy(result.Comments, e.Comments)
+	}
+	if e.NegativeSNPs != nil {
+		result.NegativeSNPs = make([]string, len(e.NegativeSNPs))
+		copy(result.NegativeSNPs, e.NegativeSNPs)
+	}
+	if e.Person != nil {
+		person := *e.Person
+		result.Person = &person
+	}
+	return result
+}
+
 func (e *Element) AddSNP(name string) {
 	e.SNPs = append(e.SNPs, name)
 }
 
 // Contains checks if one of this element's SNPs
-// equals searchTerm.
+// equals searchTerm. Equivalent SNP names that are written
+// as a single token, for example FGC12345/Y98765, are
+// compared separately.
 func (e *Element) Contains(searchTerm string) bool {
-	result := false
 	for _, snp := range e.SNPs {
-		if strings.ToLower(snp) == strings.ToLower(searchTerm) {
-			result = true
-			break
+		if snpMatches(snp, searchTerm) {
+			return true
 		}
 	}
-	return result
+	return false
 }
 
 // Details returns a detailed string representation of this element.
@@ -74,6 +122,14 @@ func (e *Element) String() string {
 		buffer.WriteString(e.SNPs[n-1])
 		hasWritten = true
 	}
+	// Write negative SNPs.
+	for _, snp := range e.NegativeSNPs {
+		if hasWritten {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(e.writtenNegativePrefix() + snp)
+		hasWritten = true
+	}
 	// Write STR-Count.
 	if e.STRCount >= 0 {
 		if hasWritten {
@@ -87,13 +143,22 @@ func (e *Element) String() string {
 
 // strDetails returns a textual representation (names and values)
 // of the Y-STR markers specified by indices.
-func (e *Element) strDetails(indices []int) string {
+// If parent is not nil, only markers with values different
+// from the parent's values are included. Then the values of
+// multi-copy markers are compared and shown sorted.
+func (e *Element) strDetails(indices []int, parent *genetic.Person) string {
 	var buffer bytes.Buffer
 	for _, i := range indices {
 		if e.Person != nil {
 			name := genetic.YstrMarkerTable[i].InternalName
 			value := e.Person.YstrMarkers[i]
-			buffer.WriteString(fmt.Sprintf(" %s: %g,", name, value))
+			if parent != nil {
+				value = markerValue(&e.Person.YstrMarkers, i)
+				if markerValue(&parent.YstrMarkers, i) == value {
+					continue
+				}
+			}
+			buffer.WriteString(fmt.Sprintf(" %s: %s,", name, formatValue(value)))
 		}
 	}
 	return buffer.String()
@@ -105,16 +170,38 @@ type Sample struct {
 	Element
 	// ID od this sample, usually the kit number.
 	ID string
+	// Weight is the reliability of this sample's data.
+	// The default is 1. Samples with a weight of 0 are only
+	// displayed and never influence modal haplotypes or ages.
+	Weight float64
+	// Name is the name of the sample's owner, usually a surname.
+	// This may be empty.
+	Name string
+	// Origin is the country or region of origin. This may be empty.
+	Origin string
+	// Tentative marks a sample that is listed under a candidate
+	// subclade while it's confirmed position is somewhere else.
+	// Tentative samples are excluded from all calculations.
+	Tentative bool
+	// Excluded marks a sample that is displayed but permanently
+	// excluded from all calculations.
+	Excluded bool
+	// ExcludeReason is the reason for the exclusion. This may be empty.
+	ExcludeReason string
+	// Sampled is the age of an ancient sample in years before
+	// present. It is 0 for living people.
+	Sampled float64
 }
 
 func newSample() Sample {
-	return Sample{Element: newElement()}
+	return Sample{Element: newElement(), Weight: 1}
 }
 
 // newSample creates a new Sample from a textual representation.
-// Format: id:SampleID, SNP1, SNP2, STR-Count: 11
-// Only the "id:" field is mandatory.
-func newSampleFromText(text string) (Sample, error) {
+// Format: id:SampleID, SNP1, SNP2, STR-Count: 11, weight: 0.5, sampled: 3800, name: Sørensen, origin: Germany, tentative, exclude: reason
+// Only the "id:" field is mandatory. Negative SNP calls start
+// with negativePrefix.
+func newSampleFromText(text, negativePrefix string) (Sample, error) {
 	result := newSample()
 	tokens := strings.Split(text, ",")
 	for _, token := range tokens {
@@ -130,13 +217,55 @@ func newSampleFromText(text string) (Sample, error) {
 				return result, errors.New(msg)
 			}
 			result.STRCount = count
+		case strings.HasPrefix(token, "weight:"):
+			weightStr := strings.TrimSpace(token[7:])
+			weight, err := strconv.ParseFloat(weightStr, 64)
+			if err != nil || weight < 0 {
+				msg := fmt.Sprintf("invalid weight: %s", weightStr)
+				return result, errors.New(msg)
+			}
+			result.Weight = weight
+		case strings.HasPrefix(token, "sampled:"):
+			sampledStr := strings.TrimSpace(token[8:])
+			sampled, err := strconv.ParseFloat(sampledStr, 64)
+			if err != nil || sampled < 0 {
+				msg := fmt.Sprintf("invalid sampling age: %s", sampledStr)
+				return result, errors.New(msg)
+			}
+			result.Sampled = sampled
+		case strings.HasPrefix(token, "name:"):
+			result.Name = strings.TrimSpace(token[5:])
+		case strings.HasPrefix(token, "origin:"):
+			result.Origin = strings.TrimSpace(token[7:])
+		case token == "tentative":
+			result.Tentative = true
+		case token == "exclude" || token == excludedMark:
+			result.Excluded = true
+		case strings.HasPrefix(token, "exclude:"):
+			result.Excluded = true
+			result.ExcludeReason = strings.TrimSpace(token[8:])
+		case strings.HasPrefix(token, excludedMark+":"):
+			result.Excluded = true
+			result.ExcludeReason = strings.TrimSpace(token[len(excludedMark)+1:])
 		default:
-			result.AddSNP(token)
+			result.addToken(token, negativePrefix)
 		}
 	}
 	return result, nil
 }
 
+// isExcluded returns true if this sample must not be used
+// for any calculations.
+func (s *Sample) isExcluded() bool {
+	return s.Weight == 0 || s.Tentative || s.Excluded
+}
+
+// hasInfluence returns true if this sample has Y-STR data
+// that may be used to calculate modal haplotypes and ages.
+func (s *Sample) hasInfluence() bool {
+	return s.Person != nil && !s.isExcluded()
+}
+
 // Contains checks if one of this sample's SNPs or the ID
 // equals searchTerm.
 func (s *Sample) Contains(searchTerm string) bool {
@@ -144,11 +273,34 @@ func (s *Sample) Contains(searchTerm string) bool {
 }
 
 func (s *Sample) String() string {
+	var result string
 	if s.Element.String() != "" {
-		return fmt.Sprintf("id:%s, %s", s.ID, s.Element.String())
+		result = fmt.Sprintf("id:%s, %s", s.ID, s.Element.String())
 	} else {
-		return fmt.Sprintf("id:%s", s.ID)
+		result = fmt.Sprintf("id:%s", s.ID)
+	}
+	if s.Weight != 1 {
+		result += fmt.Sprintf(", weight: %g", s.Weight)
+	}
+	if s.Sampled > 0 {
+		result += fmt.Sprintf(", sampled: %g", s.Sampled)
 	}
+	if s.Name != "" {
+		result += fmt.Sprintf(", name: %s", s.Name)
+	}
+	if s.Origin != "" {
+		result += fmt.Sprintf(", origin: %s", s.Origin)
+	}
+	if s.Tentative {
+		result += ", tentative"
+	}
+	if s.Excluded {
+		result += ", " + excludedMark
+		if s.ExcludeReason != "" {
+			result += ": " + s.ExcludeReason
+		}
+	}
+	return result
 }
 
 func (s *Sample) Details() string {
@@ -160,7 +312,10 @@ func (s *Sample) Details() string {
 type Clade struct {
 	Element
 	Samples   []Sample
-	Subclades []Clade
+	Subclades []*Clade
+	// Parent is the clade that contains this clade as a subclade.
+	// It is nil for the root of the tree.
+	Parent *Clade
 	// AgeSTR shows when this Clade has formed ybp
 	// according to a calculation using Y-STR mutations.
 	AgeSTR float64
@@ -168,6 +323,12 @@ type Clade struct {
 	STRCountDownstream float64
 	// Sigma2 is the squared standard deviation of STRCountDownstream.
 	Sigma2 float64
+	// Trimmed is the number of samples whose STR counts have been
+	// trimmed by CalculateAge.
+	Trimmed int
+	// PanelSize is the average number of markers that were compared
+	// for the samples and subclades of this clade.
+	PanelSize float64
 	// TMRCA_STR is the time to the most recent common Ancestor
 	// for all downstream samples.
 	TMRCA_STR float64
@@ -175,20 +336,100 @@ type Clade struct {
 	// of the 95% confidence interval.
 	TMRCAlower float64
 	TMRCAupper float64
+	// Sampled is the average age of the downstream ancient samples
+	// in years, which is included in the time estimates. It is 0 if
+	// all samples are from living persons.
+	Sampled float64
+	// TMRCAoriginal is a TMRCA from the input tree, for example
+	// a value published by YFull. Uncertain if there is none.
+	TMRCAoriginal float64
+	// AgeAnchor is a known TMRCA from the input tree that may be
+	// used for the calibration. Uncertain if there is none.
+	AgeAnchor float64
+	// ModelTMRCAs are TMRCA estimates that were calculated by using
+	// other mutation models. The keys are the names of the models.
+	ModelTMRCAs map[string]float64
+	// SNPCount is the number of SNP mutations on the branch that
+	// leads to this clade. Uncertain if unknown.
+	SNPCount float64
+	// Prior is an age estimate for the TMRCA from other sources,
+	// for example archaeology or SNP counting. This may be nil.
+	Prior *Estimate
+	// Posterior is the combination of the STR based TMRCA and
+	// the prior. This may be nil.
+	Posterior *Estimate
+	// InputAges are the time estimates from the input tree, if the
+	// input is a previously calculated tree. This may be nil.
+	InputAges *Ages
+	// isExcluded is true if this clade is excluded from the
+	// age calculation of it's parent.
+	isExcluded bool
+	// isChanged is true if the samples of this clade or it's
+	// subclades have changed since a previous calculation.
+	isChanged bool
+	// previousAges are the time estimates of this clade from a
+	// previously calculated tree, see ApplyPrevious. This may be nil.
+	previousAges *Ages
+	// parseWarnings are problems of the input tree that did not
+	// stop the parsing.
+	parseWarnings []string
+	// endComments are the comment lines after the last element
+	// of the input tree.
+	endComments []string
+	// collapsed counts the subclades and samples that have been
+	// removed by Truncate.
+	collapsed collapsedCount
+	// forcedMarkers are the markers of the modal haplotype of the
+	// root that had no unique nearest real world value and have
+	// been forced to the smallest nearest value.
+	forcedMarkers []int
+	// FixedValues are marker values of the modal haplotype that are
+	// known from external evidence. The keys are marker indices.
+	// Fixed values are never changed by the modal calculation.
+	FixedValues map[int]float64
+}
+
+// Ages holds the time estimates of a clade as they are written
+// to the output tree.
+type Ages struct {
+	STRCountDownstream float64
+	AgeSTR             float64
+	TMRCA              float64
+	TMRCAlower         float64
+	TMRCAupper         float64
 }
 
 // newClade creates a new Clade from a textual representation.
-// Format: SNP1, SNP2, STR-Count: 11
-// "STR-Count:" is optional.
-func newClade(text string) (Clade, error) {
+// Format: SNP1, SNP2, STR-Count: 11, SNP-Count: 12, fix:DYS393=13, prior: 4500 300, Age: 1500
+// "STR-Count:", "SNP-Count:", "fix:", "prior:" and "Age:" are optional.
+// Time estimates from a previously calculated tree are stored
+// in InputAges. Negative SNP calls start with negativePrefix.
+func newClade(text, negativePrefix string) (Clade, error) {
 	result := Clade{
 		Element:            newElement(),
 		AgeSTR:             Uncertain,
 		STRCountDownstream: Uncertain,
-		TMRCA_STR:          Uncertain}
+		TMRCA_STR:          Uncertain,
+		TMRCAoriginal:      Uncertain,
+		AgeAnchor:          Uncertain,
+		SNPCount:           Uncertain}
+	ages := Ages{
+		STRCountDownstream: Uncertain,
+		AgeSTR:             Uncertain,
+		TMRCA:              Uncertain,
+		TMRCAlower:         Uncertain,
+		TMRCAupper:         Uncertain}
+	hasAges := false
+	hasDownstream := false
+	// isUpperCI is true if the next token is the upper
+	// bound of the confidence interval.
+	isUpperCI := false
+	var err error
 	tokens := strings.Split(text, ",")
 	for _, token := range tokens {
 		token = strings.TrimSpace(token)
+		isCI := isUpperCI
+		isUpperCI = false
 		switch {
 		case strings.HasPrefix(token, "STR-Count:"):
 			strCount := strings.TrimSpace(token[10:])
@@ -198,36 +439,197 @@ func newClade(text string) (Clade, error) {
 				return result, errors.New(msg)
 			}
 			result.STRCount = count
-		case strings.HasPrefix(token, "TMRCA:"):
+		case strings.HasPrefix(token, "SNP-Count:"):
+			result.SNPCount, err = parseAge("SNP-Count", token[10:])
+		case strings.HasPrefix(token, "Age:"):
+			result.AgeAnchor, err = parseAge("Age", token[4:])
+			if err == nil && result.AgeAnchor <= 0 {
+				err = errors.New(fmt.Sprintf("Age must be positive: %g", result.AgeAnchor))
+			}
+		case strings.HasPrefix(token, "TMRCA ("):
 			// Ignore because this TMRCA has to be newly calculated.
-		default:
+		case strings.HasPrefix(token, "STRs Downstream:"):
+			hasAges = true
+			hasDownstream = true
+			ages.STRCountDownstream, err = parseAge("STRs Downstream", token[16:])
+		case strings.HasPrefix(token, "formed:"):
+			hasAges = true
+			ages.AgeSTR, err = parseAge("formed", token[7:])
+		case strings.HasPrefix(token, "TMRCA:"):
+			// TMRCA has to be newly calculated. The value from
+			// the input is kept separately.
+			// Format: TMRCA: 4300 (input 4500, Δ -200)
+			hasAges = true
+			value := token[6:]
+			if pos := strings.Index(value, "(input"); pos >= 0 {
+				result.TMRCAoriginal, err = parseAge("TMRCA", value[pos+6:])
+				value = value[:pos]
+			}
+			if err == nil {
+				ages.TMRCA, err = parseAge("TMRCA", value)
+			}
+		case strings.HasPrefix(token, "Δ"):
+			// Ignore because the difference has to be newly calculated.
+		case strings.HasPrefix(token, "CI:["):
+			// Format: CI:[4000, 4600]
+			hasAges = true
+			isUpperCI = true
+			ages.TMRCAlower, err = parseAge("CI", token[4:])
+		case isCI && strings.HasSuffix(token, "]"):
+			hasAges = true
+			ages.TMRCAupper, err = parseAge("CI", token[:len(token)-1])
+		case strings.HasPrefix(token, "prior:"):
+			result.Prior, err = parsePrior(token[6:])
+		case strings.HasPrefix(token, "posterior:"):
+			// Ignore because the posterior has to be newly calculated.
+		case strings.HasPrefix(token, "n="):
+			// Ignore because the sample counts are newly calculated.
+		case strings.HasPrefix(token, "fix:"):
+			marker, value, err := parseFixedValue(token[4:])
+			if err != nil {
+				return result, err
+			}
+			if result.FixedValues == nil {
+				result.FixedValues = make(map[int]float64)
+			}
+			result.FixedValues[marker] = value
+		case len(result.SNPs) == 0:
+			// The first token is always the clade's name.
 			result.AddSNP(token)
+		default:
+			result.addToken(token, negativePrefix)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	if hasAges {
+		result.InputAges = &ages
+		// A TMRCA without calculated ages is from another source.
+		if !hasDownstream && result.TMRCAoriginal < 0 {
+			result.TMRCAoriginal = ages.TMRCA
 		}
 	}
 	return result, nil
 }
 
+// parseAge converts the text of a time estimate into a float value.
+// name is the name of the estimate and is used for error messages.
+func parseAge(name, text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not convert %s to float: %s", name, text)
+		return value, errors.New(msg)
+	}
+	return value, nil
+}
+
+// ReadOptions specifies how a tree in text format is read.
+type ReadOptions struct {
+	// Strict turns problems with the indentation of the tree
+	// into errors.
+	Strict bool
+	// TabWidth is the number of spaces that a tab counts for the
+	// indentation. Tabs advance the indentation to the next multiple
+	// of TabWidth.
+	TabWidth int
+	// NegativePrefix marks negative SNP calls. An empty prefix
+	// turns off negative calls.
+	NegativePrefix string
+}
+
+// DefaultReadOptions returns the options that are used by NewFromFile.
+func DefaultReadOptions() ReadOptions {
+	return ReadOptions{TabWidth: 4, NegativePrefix: DefaultNegativePrefix}
+}
+
 // NewFromFile parses a text file to create a tree.
+// Files in Newick format or in the JSON format of the YFull
+// haplotree are detected automatically.
+// Files that are compressed by gzip are decompressed.
 // The return value Clade is the root node of the tree.
+// Problems with the indentation of the tree are available
+// by ParseWarnings.
 func NewFromFile(filename string) (*Clade, error) {
-	lines := make([]lineInfo, 0)
+	return NewFromFileOptions(filename, DefaultReadOptions())
+}
 
-	// Open file
-	infile, err := os.Open(filename)
+// NewFromFileStrict works like NewFromFile, but problems with
+// the indentation of the tree are errors.
+func NewFromFileStrict(filename string) (*Clade, error) {
+	options := DefaultReadOptions()
+	options.Strict = true
+	return NewFromFileOptions(filename, options)
+}
+
+// NewFromFileOptions works like NewFromFile, but the tree is read
+// as specified by options.
+func NewFromFileOptions(filename string, options ReadOptions) (*Clade, error) {
+	infile, err := OpenFile(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer infile.Close()
+	return NewFromReaderOptions(infile, options)
+}
+
+// NewFromReader reads a tree from reader, see NewFromFile.
+func NewFromReader(reader io.Reader) (*Clade, error) {
+	return NewFromReaderOptions(reader, DefaultReadOptions())
+}
+
+// NewFromReaderStrict reads a tree from reader, see NewFromFileStrict.
+func NewFromReaderStrict(reader io.Reader) (*Clade, error) {
+	options := DefaultReadOptions()
+	options.Strict = true
+	return NewFromReaderOptions(reader, options)
+}
+
+// NewFromReaderOptions reads a tree from reader, see NewFromFileOptions.
+func NewFromReaderOptions(reader io.Reader, options ReadOptions) (*Clade, error) {
+	lines := make([]lineInfo, 0)
+
+	// Read input
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	text := decodeText(content)
+	if isNewick(text) {
+		return parseNewick(text, false)
+	}
+	if isYFullJSON(text) {
+		return parseYFullJSON([]byte(text))
+	}
 
 	// Read lines
 	lineNo := 0
-	scanner := bufio.NewScanner(infile)
+	// comments are the comment lines in front of the next element.
+	var comments []string
+	isHeader := false
+	scanner := bufio.NewScanner(strings.NewReader(text))
 	for scanner.Scan() {
 		lineNo++
-		text := stripComments(scanner.Text())
-		if text != "" {
-			indent := countSpaces(text)
-			lines = append(lines, lineInfo{lineNo: lineNo, indent: indent, text: text})
+		line := scanner.Text()
+		// Skip the header of a results tree.
+		if len(lines) == 0 && len(comments) == 0 && strings.HasPrefix(line, headerMark) {
+			isHeader = true
+		}
+		if isHeader {
+			isHeader = strings.HasPrefix(strings.TrimSpace(line), "//")
+			if isHeader {
+				continue
+			}
+		}
+		text, comment, hasComment := splitComment(line)
+		switch {
+		case text != "":
+			indent := countSpaces(text, options.TabWidth)
+			lines = append(lines, lineInfo{lineNo: lineNo, indent: indent, text: text, comment: comment, comments: comments})
+			comments = nil
+		case hasComment:
+			comments = append(comments, comment)
 		}
 	}
 	if scanner.Err() != nil {
@@ -236,16 +638,26 @@ func NewFromFile(filename string) (*Clade, error) {
 	if len(lines) == 0 {
 		return nil, errors.New("empty file, nothing to do")
 	}
+	warnings := checkIndentation(lines, options.TabWidth)
+	if options.Strict && len(warnings) > 0 {
+		return nil, errors.New(fmt.Sprintf("parsing tree, %s", strings.Join(warnings, "; ")))
+	}
 
 	// Build tree by parsing lines.
-	root, err := newClade(lines[0].text)
+	root, err := newClade(lines[0].text, options.NegativePrefix)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("invalid root element, %s", err))
 	}
-	err = parseTree(&root, lines[0].indent, lines[1:])
+	root.lineNo = lines[0].lineNo
+	root.Comment = lines[0].comment
+	root.Comments = lines[0].comments
+	err = parseTree(&root, lines[0].indent, lines[1:], options)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("parsing tree, %s", err))
 	}
+	// Comments at the end of the file.
+	root.endComments = comments
+	root.parseWarnings = warnings
 	return &root, nil
 }
 
@@ -256,50 +668,180 @@ func (c *Clade) AddSample(sample Sample) {
 	c.Samples = append(c.Samples, sample)
 }
 
+// AddSubclade adds a copy of clade as a subclade to this clade
+// and sets it's parent. The subclades of the copy are the same as
+// the subclades of clade, their parent becomes the copy.
 func (c *Clade) AddSubclade(clade Clade) {
+	for i, _ := range clade.Subclades {
+		clade.Subclades[i].Parent = &clade
+	}
+	c.AttachSubclade(&clade)
+}
+
+// AttachSubclade adds clade itself as a subclade to this clade
+// and sets it's parent.
+func (c *Clade) AttachSubclade(clade *Clade) {
 	if c.Subclades == nil {
-		c.Subclades = make([]Clade, 0)
+		c.Subclades = make([]*Clade, 0)
 	}
+	clade.Parent = c
 	c.Subclades = append(c.Subclades, clade)
 }
 
+// Clone returns a deep copy of this clade and all of it's samples
+// and subclades, including the modal haplotypes and the persons
+// of the samples. Calculations on the copy never change the
+// original. The copy has no parent.
+func (c *Clade) Clone() *Clade {
+	result := c.clone()
+	result.Parent = nil
+	return result
+}
+
+// clone returns a copy of this clade, see Clone.
+func (c *Clade) clone() *Clade {
+	result := *c
+	result.Element = c.Element.clone()
+	// A copy is always calculated from scratch.
+	result.isChanged = false
+	result.previousAges = nil
+	if c.FixedValues != nil {
+		result.FixedValues = make(map[int]float64)
+		for marker, value := range c.FixedValues {
+			result.FixedValues[marker] = value
+		}
+	}
+	if c.InputAges != nil {
+		ages := *c.InputAges
+		result.InputAges = &ages
+	}
+	if c.Prior != nil {
+		prior := *c.Prior
+		result.Prior = &prior
+	}
+	if c.Posterior != nil {
+		posterior := *c.Posterior
+		result.Posterior = &posterior
+	}
+	if c.ModelTMRCAs != nil {
+		result.ModelTMRCAs = make(map[string]float64)
+		for model, tmrca := range c.ModelTMRCAs {
+			result.ModelTMRCAs[model] = tmrca
+		}
+	}
+	if c.forcedMarkers != nil {
+		result.forcedMarkers = append([]int(nil), c.forcedMarkers...)
+	}
+	if c.Samples != nil {
+		result.Samples = make([]Sample, len(c.Samples))
+		for i, _ := range c.Samples {
+			result.Samples[i] = c.Samples[i]
+			result.Samples[i].Element = c.Samples[i].Element.clone()
+		}
+	}
+	if c.Subclades != nil {
+		result.Subclades = make([]*Clade, len(c.Subclades))
+		for i, _ := range c.Subclades {
+			result.Subclades[i] = c.Subclades[i].clone()
+			result.Subclades[i].Parent = &result
+		}
+	}
+	return &result
+}
+
 // Persons returns a list of all persons who belong to this clade.
 // This includes the calculated modal haplotypes.
 func (c *Clade) Persons() []*genetic.Person {
+	persons := make([]*genetic.Person, 0, 50)
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.Person != nil {
+			persons = append(persons, clade.Person)
+		}
+		for i, _ := range clade.Samples {
+			if clade.Samples[i].Person != nil {
+				persons = append(persons, clade.Samples[i].Person)
+			}
+		}
+		return nil
+	})
+	return persons
+}
+
+// ModalPersons returns the modal haplotypes of this clade and all
+// subclades. Their IDs are the first SNPs of the clades.
+func (c *Clade) ModalPersons() []*genetic.Person {
 	persons := make([]*genetic.Person, 0, 50)
 	if c.Person != nil {
 		persons = append(persons, c.Person)
 	}
-	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
-			persons = append(persons, c.Samples[i].Person)
-		}
-	}
 	for i, _ := range c.Subclades {
-		persons = append(persons, c.Subclades[i].Persons()...)
+		persons = append(persons, c.Subclades[i].ModalPersons()...)
 	}
 	return persons
 }
 
 // InsertPersons traverses the tree and adds the appropriate
 // person to a leaf if the ID of the sample and the person's ID
-// are identical.
-func (c *Clade) InsertPersons(persons []*genetic.Person) {
+// are identical after normalization, see DefaultIDMatch.
+// If the person has no name, the name of the sample is used.
+// If persons contains the same ID multiple times, the last
+// occurrence is used. The result contains a warning for each
+// of these IDs.
+func (c *Clade) InsertPersons(persons []*genetic.Person) []string {
+	return c.InsertPersonsMatching(persons, DefaultIDMatch)
+}
+
+// InsertPersonsMatching works like InsertPersons, but the IDs are
+// compared as specified by match. If different IDs of persons become
+// identical by normalization, a sample gets the person whose ID
+// matches exactly. If there is none, the sample gets no person
+// and a warning is returned.
+func (c *Clade) InsertPersonsMatching(persons []*genetic.Person, match IDMatch) []string {
 	// Create hash map of persons' IDs.
 	personsMap := make(map[string]*genetic.Person)
+	counts := make(map[string]int)
+	// normalized maps normalized IDs to the original IDs.
+	normalized := make(map[string][]string)
+	var ids []string
 	for i, _ := range persons {
-		personsMap[persons[i].ID] = persons[i]
-	}
-	// Search samples matching person IDs.
-	for i, _ := range c.Samples {
-		if person, exists := personsMap[c.Samples[i].ID]; exists {
-			c.Samples[i].Person = person
+		id := persons[i].ID
+		if counts[id] == 0 {
+			ids = append(ids, id)
+			key := match.normalize(id)
+			normalized[key] = append(normalized[key], id)
 		}
+		counts[id]++
+		personsMap[id] = persons[i]
 	}
-	// Search subclades for matching person IDs.
-	for i, _ := range c.Subclades {
-		c.Subclades[i].InsertPersons(persons)
+	var warnings []string
+	for _, id := range ids {
+		if counts[id] > 1 {
+			warnings = append(warnings, fmt.Sprintf("person %s is listed %d times in the persons data, the last occurrence is used",
+				id, counts[id]))
+		}
 	}
+	// Search samples matching person IDs.
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		person, exists := personsMap[s.ID]
+		if !exists {
+			candidates := normalized[match.normalize(s.ID)]
+			switch {
+			case len(candidates) == 1:
+				person, exists = personsMap[candidates[0]], true
+			case len(candidates) > 1:
+				warnings = append(warnings, fmt.Sprintf("sample %s matches the persons %s, no person is used",
+					s.ID, strings.Join(candidates, ", ")))
+			}
+		}
+		if exists {
+			s.Person = person
+			if person.Name == "" {
+				person.Name = s.Name
+			}
+		}
+		return nil
+	})
+	return warnings
 }
 
 // CalculateModalHaplotypes calculates the modal haplotype for
@@ -308,7 +850,7 @@ func (c *Clade) CalculateModalHaplotypes() {
 	// Create a list of haplotypes from samples and subclades.
 	persons := make([]*genetic.Person, 0)
 	for i, _ := range c.Samples {
-		if c.Samples[i].Person != nil {
+		if c.Samples[i].hasInfluence() {
 			persons = append(persons, c.Samples[i].Person)
 		}
 	}
@@ -324,11 +866,20 @@ func (c *Clade) CalculateModalHaplotypes() {
 	modal.Name = c.SNPs[0]
 	modal.Label = c.SNPs[0]
 	c.Person = modal
+	c.applyOwnFixedValues()
 }
 
 // CalculateDistances calculated the genetic distances between
 // the modal haplotype of this clade and it's downstream members.
+// It also determines the marker coverage of each distance, which
+// is used for the confidence intervals of the ages.
 func (c *Clade) CalculateDistances(mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) {
+	c.calculateDistances(mutationRates, distance)
+	c.setCoverage(c.maxRateCompared())
+}
+
+// calculateDistances calculates the distances for CalculateDistances.
+func (c *Clade) calculateDistances(mutationRates genetic.YstrMarkers, distance genetic.DistanceFunc) {
 	if c.Person == nil {
 		return
 	}
@@ -340,16 +891,38 @@ func (c *Clade) CalculateDistances(mutationRates genetic.YstrMarkers, distance g
 			ystr1 := c.Samples[i].Person.YstrMarkers
 			ystr2 := c.Person.YstrMarkers
 			c.Samples[i].STRCount = distance(ystr1, ystr2, mutationRates)
+			c.Samples[i].MarkersCompared, c.Samples[i].MarkersSkipped = compareMarkers(ystr1, ystr2)
+			c.Samples[i].RateCompared = comparedRate(ystr1, ystr2, mutationRates)
 		}
 	}
 	for i, _ := range c.Subclades {
-		c.Subclades[i].CalculateDistances(mutationRates, distance)
+		c.Subclades[i].calculateDistances(mutationRates, distance)
 		if c.Subclades[i].Person != nil {
 			ystr1 := c.Subclades[i].Person.YstrMarkers
 			ystr2 := c.Person.YstrMarkers
 			c.Subclades[i].STRCount = distance(ystr1, ystr2, mutationRates)
+			c.Subclades[i].MarkersCompared, c.Subclades[i].MarkersSkipped = compareMarkers(ystr1, ystr2)
+			c.Subclades[i].RateCompared = comparedRate(ystr1, ystr2, mutationRates)
+		}
+	}
+}
+
+// compareMarkers counts the markers that can be compared between
+// two haplotypes. compared is the number of markers for which both
+// haplotypes have values. skipped is the number of markers for which
+// only one of the haplotypes has a value.
+func compareMarkers(ystr1, ystr2 genetic.YstrMarkers) (compared, skipped int) {
+	for i, _ := range ystr1 {
+		has1 := ystr1[i] > 0
+		has2 := ystr2[i] > 0
+		switch {
+		case has1 && has2:
+			compared++
+		case has1 || has2:
+			skipped++
 		}
 	}
+	return compared, skipped
 }
 
 // CalculateAge calculates the age and TMRCA for this Clade.
@@ -360,44 +933,109 @@ func (c *Clade) CalculateDistances(mutationRates genetic.YstrMarkers, distance g
 // to the result.
 // offset is added to all calculated ages to account for the ages
 // of living persons. YFull currently uses an offset of 60 years.
+// Distances that are based on fewer markers than others have larger
+// variances and thus wider confidence intervals.
+// The sampling ages of ancient samples are averaged with the same
+// weights and added in years, see Sampled. They are not scaled by
+// the calibration factor.
+// The STR counts of the samples are averaged by a weighted mean.
 func (c *Clade) CalculateAge(gentime, calibration, offset float64) {
+	c.CalculateAgeAveraging(gentime, calibration, offset, Averaging{})
+}
+
+// CalculateAgeAveraging works like CalculateAge, but averaging
+// specifies how the STR counts of the samples are averaged.
+// Subclades are always combined by a weighted average.
+func (c *Clade) CalculateAgeAveraging(gentime, calibration, offset float64, averaging Averaging) {
 	var avgCalc avgCalculator
+	var panel panelAverage
+	c.Trimmed = 0
 	// Count STR mutations for samples.
+	// Samples are weighted by their Weight.
 	// average value
 	avgSamples := 0.0
 	// sigma squared
 	sigma2Samples := 0.0
-	nSamples := float64(len(c.Samples))
-	if nSamples > 0 {
+	// sum of weights and sum of squared weights
+	sumWeights := 0.0
+	sumWeights2 := 0.0
+	// average factor for the variance due to marker coverage
+	coverageSamples := 0.0
+	// average age of ancient samples in years
+	sampledSamples := 0.0
+	for i, _ := range c.Samples {
+		if !c.Samples[i].isExcluded() {
+			weight := c.Samples[i].Weight
+			sumWeights += weight
+			sumWeights2 += weight * weight
+			coverageSamples += weight * c.Samples[i].coverageFactor()
+			panel.add(&c.Samples[i].Element)
+		}
+	}
+	if sumWeights > 0 {
+		var counts, weights []float64
 		for i, _ := range c.Samples {
+			if c.Samples[i].isExcluded() {
+				continue
+			}
+			count := 0.0
 			if c.Samples[i].STRCount > 0 {
-				avgSamples += c.Samples[i].STRCount
+				count += c.Samples[i].STRCount
+			}
+			avgSamples += c.Samples[i].Weight * count
+			sampledSamples += c.Samples[i].Weight * c.Samples[i].Sampled
+			counts = append(counts, count)
+			weights = append(weights, c.Samples[i].Weight)
+		}
+		avgSamples /= sumWeights
+		sampledSamples /= sumWeights
+		coverageSamples /= sumWeights
+		// The effective number of samples equals the number
+		// of samples if all weights are 1.
+		nSamples := sumWeights * sumWeights / sumWeights2
+		sigma2Samples = avgSamples / nSamples * coverageSamples
+		switch {
+		case averaging.Median:
+			avgSamples, sigma2Samples = medianSigma2(counts, weights, nSamples, coverageSamples)
+		case averaging.Trim > 0:
+			avgSamples, nSamples, c.Trimmed = trimmedMean(counts, weights, averaging.Trim, averaging.Winsorize)
+			if nSamples > 0 {
+				sigma2Samples = avgSamples / nSamples * coverageSamples
 			}
 		}
-		avgSamples /= nSamples
-		sigma2Samples = avgSamples / nSamples
 		if sigma2Samples > 0 {
-			avgCalc.add(avgSamples, sigma2Samples)
+			avgCalc.addYears(avgSamples, sigma2Samples, sampledSamples)
 		}
 	}
 	// Count STR mutations for subclades.
 	for i, _ := range c.Subclades {
-		c.Subclades[i].CalculateAge(gentime, calibration, offset)
+		c.Subclades[i].CalculateAgeAveraging(gentime, calibration, offset, averaging)
+		// Empty subclades have no ages, for example
+		// if all samples have been removed.
+		if c.Subclades[i].isExcluded || c.Subclades[i].isEmpty() {
+			continue
+		}
 		subcladeSTRs := c.Subclades[i].STRCount + c.Subclades[i].STRCountDownstream
-		subcladeSigma2 := c.Subclades[i].STRCount + c.Subclades[i].Sigma2
+		subcladeSigma2 := c.Subclades[i].STRCount*c.Subclades[i].coverageFactor() + c.Subclades[i].Sigma2
+		panel.add(&c.Subclades[i].Element)
 		if subcladeSigma2 > 0 {
-			avgCalc.add(subcladeSTRs, subcladeSigma2)
+			avgCalc.addYears(subcladeSTRs, subcladeSigma2, c.Subclades[i].Sampled)
 		}
 	}
+	c.PanelSize = panel.avg()
 	// Calculate average number of mutations.
+	// The ages of ancient samples are added after the mutations
+	// have been converted into years.
 	if avgCalc.size > 0 {
 		c.STRCountDownstream, c.Sigma2 = avgCalc.avg()
-		c.TMRCA_STR = c.STRCountDownstream*gentime*calibration + offset
-		c.AgeSTR = (c.STRCount+c.STRCountDownstream)*gentime*calibration + offset
+		c.Sampled = avgCalc.avgYears()
+		c.TMRCA_STR = c.STRCountDownstream*gentime*calibration + c.Sampled + offset
+		c.AgeSTR = (c.STRCount+c.STRCountDownstream)*gentime*calibration + c.Sampled + offset
 		lower, upper := avgCalc.confidenceIntervals(c.STRCountDownstream, c.Sigma2)
-		c.TMRCAlower = lower*gentime*calibration + offset
-		c.TMRCAupper = upper*gentime*calibration + offset
+		c.TMRCAlower = lower*gentime*calibration + c.Sampled + offset
+		c.TMRCAupper = upper*gentime*calibration + c.Sampled + offset
 	}
+	c.keepPrevious()
 }
 
 // RecalculateAge performs a top town recalculation for the
@@ -406,134 +1044,290 @@ func (c *Clade) CalculateAge(gentime, calibration, offset float64) {
 // each subclade so that the age of the subclade equals the TMRCA value
 // of it's parent. This way a sublcade can never be older than it's parent.
 func (c *Clade) RecalculateAge(gentime, calibration, offset float64) {
+	c.RecalculateAgeAveraging(gentime, calibration, offset, Averaging{})
+}
+
+// RecalculateAgeAveraging works like RecalculateAge, but averaging
+// is passed to CalculateAgeAveraging.
+func (c *Clade) RecalculateAgeAveraging(gentime, calibration, offset float64, averaging Averaging) {
 	for i, _ := range c.Subclades {
+		if c.Subclades[i].isEmpty() {
+			continue
+		}
 		// Get new estimate for calibration factor based on the age of this clade.
-		newcal := (c.TMRCA_STR - offset) / ((c.Subclades[i].STRCount + c.Subclades[i].STRCountDownstream) * gentime)
+		// The age of ancient samples is not scaled.
+		newcal := (c.TMRCA_STR - offset - c.Subclades[i].Sampled) / ((c.Subclades[i].STRCount + c.Subclades[i].STRCountDownstream) * gentime)
 		// Recalculate age and TMRCA for subclade
-		c.Subclades[i].CalculateAge(gentime, newcal, offset)
-		c.Subclades[i].RecalculateAge(gentime, newcal, offset)
+		c.Subclades[i].CalculateAgeAveraging(gentime, newcal, offset, averaging)
+		c.Subclades[i].RecalculateAgeAveraging(gentime, newcal, offset, averaging)
 	}
 }
 
+// PrintOptions specifies what is written by Format.
+type PrintOptions struct {
+	// SampleCounts writes the number of samples on the line
+	// of each clade, for example n=2/37.
+	SampleCounts bool
+}
+
 func (c *Clade) String() string {
+	return c.Format(PrintOptions{})
+}
+
+// Format returns the tree in text format as specified by options.
+func (c *Clade) Format(options PrintOptions) string {
 	var buffer bytes.Buffer
-	c.prettyPrint(&buffer, 0)
+	c.prettyPrint(&buffer, 0, options)
+	writeComments(&buffer, c.endComments, 0)
 	return buffer.String()
 }
 
 // prettyPrint prints a formatted version of the clade c
 // into buffer. indent is the indentation for the root node.
-func (c *Clade) prettyPrint(buffer *bytes.Buffer, indent int) {
+func (c *Clade) prettyPrint(buffer *bytes.Buffer, indent int, options PrintOptions) {
 	// Write this Element.
+	writeComments(buffer, c.Comments, indent)
 	for i := 0; i < indent; i++ {
 		buffer.WriteString("\t")
 	}
 	buffer.WriteString(c.Element.String())
+	if c.SNPCount >= 0 {
+		buffer.WriteString(fmt.Sprintf(", SNP-Count: %g", c.SNPCount))
+	}
+	buffer.WriteString(c.fixedString())
+	if c.AgeAnchor > 0 {
+		buffer.WriteString(fmt.Sprintf(", Age: %g", c.AgeAnchor))
+	}
 
 	// Write time estimates.
-	if c.STRCountDownstream >= 0 {
-		buffer.WriteString(
-			fmt.Sprintf(", STRs Downstream: %.0f, formed: %.0f, TMRCA: %.0f, CI:[%.0f, %.0f]",
-				c.STRCountDownstream, c.AgeSTR, c.TMRCA_STR, c.TMRCAlower, c.TMRCAupper))
+	buffer.WriteString(c.ageString())
+	buffer.WriteString(c.priorString())
+	if options.SampleCounts {
+		buffer.WriteString(c.countString())
 	}
+	buffer.WriteString(c.collapsed.String())
+	buffer.WriteString(c.commentString())
 	buffer.WriteString("\r\n")
 
 	// Write Samples.
 	for _, sample := range c.Samples {
+		writeComments(buffer, sample.Comments, indent+1)
 		for i := 0; i < indent+1; i++ {
 			buffer.WriteString("\t")
 		}
 		buffer.WriteString(sample.String())
+		buffer.WriteString(sample.commentString())
 		buffer.WriteString("\r\n")
 	}
 	// Write Subclades.
 	for _, clade := range c.Subclades {
-		clade.prettyPrint(buffer, indent+1)
+		clade.prettyPrint(buffer, indent+1, options)
+	}
+}
+
+// ageString returns the time estimates of this clade.
+// If no estimates have been calculated, the result is empty.
+func (c *Clade) ageString() string {
+	if c.STRCountDownstream < 0 {
+		// Keep the TMRCA from the input.
+		if c.TMRCAoriginal >= 0 {
+			return fmt.Sprintf(", TMRCA: %.0f", c.TMRCAoriginal)
+		}
+		return ""
+	}
+	tmrca := fmt.Sprintf("%.0f", c.TMRCA_STR)
+	if c.TMRCAoriginal >= 0 {
+		tmrca += fmt.Sprintf(" (input %.0f, Δ %+.0f)", c.TMRCAoriginal, c.TMRCA_STR-c.TMRCAoriginal)
+	}
+	result := fmt.Sprintf(", STRs Downstream: %.0f, formed: %.0f, TMRCA: %s, CI:[%.0f, %.0f]",
+		c.STRCountDownstream, c.AgeSTR, tmrca, c.TMRCAlower, c.TMRCAupper)
+	models := make([]string, 0, len(c.ModelTMRCAs))
+	for model, _ := range c.ModelTMRCAs {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		result += fmt.Sprintf(", TMRCA (%s): %.0f", model, c.ModelTMRCAs[model])
+	}
+	return result
+}
+
+// AddModelTMRCAs adds the TMRCA estimates of other, which have been
+// calculated by using the mutation model named model, to this clade
+// and all subclades. other must have the same structure as this clade.
+func (c *Clade) AddModelTMRCAs(model string, other *Clade) {
+	if c.ModelTMRCAs == nil {
+		c.ModelTMRCAs = make(map[string]float64)
+	}
+	c.ModelTMRCAs[model] = other.TMRCA_STR
+	for i := 0; i < len(c.Subclades) && i < len(other.Subclades); i++ {
+		c.Subclades[i].AddModelTMRCAs(model, other.Subclades[i])
 	}
 }
 
 // Inspect looks at this clade and all subclades.
 // If any of the tree nodes' SNPs match one of the search
 // terms, a string representation of the element is added
-// to the result.
+// to the result together with the path from the root and the
+// ages of the clade. The results are in the order of the search
+// terms. If a search term matches several elements, the first
+// match in tree order is reported followed by a warning that lists
+// all clades that contain the search term. Search terms may contain
+// the wildcards * and ?. Then all matching elements are reported.
 func (c *Clade) Inspect(searchTerms []string) string {
-	// Create hash map containing results.
-	results := make(map[string]string)
+	// Wildcard patterns are always valid.
+	result, _ := c.InspectMatching(searchTerms, false)
+	return result
+}
+
+// InspectMatching works like Inspect. If isRegex is true, the
+// search terms are regular expressions and all matching elements
+// are reported. Invalid patterns are reported before the search
+// starts.
+func (c *Clade) InspectMatching(searchTerms []string, isRegex bool) (string, error) {
+	// Create hash maps containing matchers and results.
+	matchers := make(map[string]termMatcher)
+	results := make(map[string][]string)
 	for _, term := range searchTerms {
-		results[term] = ""
+		matcher, err := newTermMatcher(term, isRegex)
+		if err != nil {
+			return "", err
+		}
+		matchers[term] = matcher
+		results[term] = nil
 	}
 
-	results = c.searchFor(results)
+	results = c.searchFor(matchers, results)
 
 	// Return representation of the findings.
 	var buffer bytes.Buffer
 	for _, term := range searchTerms {
-		buffer.WriteString(results[term])
+		for _, result := range results[term] {
+			buffer.WriteString(result)
+		}
+		if !matchers[term].isPattern() {
+			buffer.WriteString(c.ambiguityWarning(matchers[term]))
+		}
 	}
-	return buffer.String()
+	return buffer.String(), nil
 }
 
-// searchFor searches for SNPs in this clade and it's subclades.
-// The SNPs are defined as keys in the results map.
-// The values of the results map are string representations of
-// the matching clades.
-func (c *Clade) searchFor(results map[string]string) map[string]string {
-	// Search for searchTerms.
-	for key, _ := range results {
-		if c.Contains(key) {
-			results[key] = c.Details()
+// ambiguityWarning returns a warning if more than one clade
+// matches the search term of matcher. Otherwise the result
+// is empty.
+func (c *Clade) ambiguityWarning(matcher termMatcher) string {
+	var positions []string
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if matcher.matchesElement(&clade.Element) {
+			positions = append(positions, clade.cladePosition())
 		}
+		return nil
+	})
+	if len(positions) < 2 {
+		return ""
 	}
-	for i, _ := range c.Samples {
-		for key, _ := range results {
-			if c.Samples[i].Contains(key) {
-				results[key] = c.Samples[i].Details()
+	return fmt.Sprintf("Warning, %s appears on multiple clades: %s.\r\n", matcher.term, strings.Join(positions, ", "))
+}
+
+// searchFor searches for SNPs in this clade and it's subclades.
+// The search terms are defined as keys in the matchers and results
+// maps. The values of the results map are string representations of
+// the matching clades or samples. A key that is not a pattern gets
+// only the first match in tree order: a clade comes before it's
+// samples and the samples come before the subclades.
+func (c *Clade) searchFor(matchers map[string]termMatcher, results map[string][]string) map[string][]string {
+	isDone := func(key string) bool {
+		return !matchers[key].isPattern() && len(results[key]) > 0
+	}
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		for key, matcher := range matchers {
+			if !isDone(key) && matcher.matchesElement(&clade.Element) {
+				results[key] = append(results[key], inspectHit(path, clade, nil))
 			}
 		}
-	}
-	for i, _ := range c.Subclades {
-		for key, _ := range results {
-			if c.Subclades[i].Contains(key) {
-				results[key] = c.Subclades[i].Element.Details()
+		for i, _ := range clade.Samples {
+			for key, matcher := range matchers {
+				if !isDone(key) && matcher.matchesSample(&clade.Samples[i]) {
+					results[key] = append(results[key], inspectHit(path, clade, &clade.Samples[i]))
+				}
 			}
 		}
-		results = c.Subclades[i].searchFor(results)
-	}
+		return nil
+	})
 	return results
 }
 
+// MarkerIndex returns the index of the Y-STR marker with the
+// specified name. The name may be the internal name or the
+// name used by FTDNA or YFull. If no marker is found, the
+// result is -1.
+func MarkerIndex(name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, marker := range genetic.YstrMarkerTable {
+		if name == strings.ToLower(marker.InternalName) ||
+			name == strings.ToLower(marker.FTDNAName) ||
+			name == strings.ToLower(marker.YFullName) {
+			return marker.Index
+		}
+	}
+	return -1
+}
+
 // Trace returns a nicely formatted tree containing information
 // (names and values) about the Y-STR markers specified by STRs.
+// If STRs contains "auto", all markers are traced that vary
+// among the samples of this clade. The names are resolved by
+// TraceIndices.
 func (c *Clade) Trace(STRs []string) string {
-	// Look for STR indices.
-	var indices []int
-	for _, str := range STRs {
-		str := strings.ToLower(str)
-		for _, marker := range genetic.YstrMarkerTable {
-			if str == strings.ToLower(marker.InternalName) ||
-				str == strings.ToLower(marker.FTDNAName) ||
-				str == strings.ToLower(marker.YFullName) {
-				indices = append(indices, marker.Index)
-				break
-			}
-		}
-	}
+	return c.trace(STRs, false)
+}
+
+// TraceChangesOnly works like Trace, but only values that are
+// different from the parent's values are shown. The values of
+// multi-copy markers are sorted before they are compared.
+func (c *Clade) TraceChangesOnly(STRs []string) string {
+	return c.trace(STRs, true)
+}
+
+// trace returns the tree for Trace and TraceChangesOnly.
+func (c *Clade) trace(STRs []string, changesOnly bool) string {
+	// Unknown marker names are skipped.
+	indices := c.traceIndices(STRs)
 
 	// Build tree with STR values.
 	var buffer bytes.Buffer
-	c.tracePrint(&buffer, 0, indices)
+	c.tracePrint(&buffer, 0, func(e *Element, parent *genetic.Person) string {
+		if !changesOnly {
+			parent = nil
+		}
+		return e.strDetails(indices, parent)
+	}, nil)
 	return buffer.String()
 }
 
+// traceIndices returns the indices of the Y-STR markers specified
+// by STRs like TraceIndices. Unknown names are skipped.
+func (c *Clade) traceIndices(STRs []string) []int {
+	indices, _ := c.TraceIndices(STRs)
+	return indices
+}
+
+// traceDetailsFunc returns the marker values of an element for the
+// trace output. parent is the modal haplotype of the parent clade.
+// It is nil for the root.
+type traceDetailsFunc func(e *Element, parent *genetic.Person) string
+
 // tracePrint creates the formatted tree for Trace.
-func (c *Clade) tracePrint(buffer *bytes.Buffer, indent int, STRindices []int) {
+// details creates the marker values of each element.
+// parent is the modal haplotype of the parent clade.
+func (c *Clade) tracePrint(buffer *bytes.Buffer, indent int, details traceDetailsFunc, parent *genetic.Person) {
 	// Write this Element.
 	for i := 0; i < indent; i++ {
 		buffer.WriteString("\t")
 	}
 	buffer.WriteString(c.Element.String())
 	buffer.WriteString(",")
-	buffer.WriteString(c.Element.strDetails(STRindices))
+	buffer.WriteString(details(&c.Element, parent))
 	buffer.WriteString("\r\n")
 
 	// Write Samples.
@@ -543,16 +1337,76 @@ func (c *Clade) tracePrint(buffer *bytes.Buffer, indent int, STRindices []int) {
 		}
 		buffer.WriteString(sample.String())
 		buffer.WriteString(",")
-		buffer.WriteString(sample.Element.strDetails(STRindices))
+		buffer.WriteString(details(&sample.Element, c.Person))
 		buffer.WriteString("\r\n")
 	}
 	// Write Subclades.
 	for _, clade := range c.Subclades {
-		clade.tracePrint(buffer, indent+1, STRindices)
+		clade.tracePrint(buffer, indent+1, details, c.Person)
 	}
 }
 
+// VaryingMarkers returns the indices of all markers that have
+// different values among the samples of this clade.
+func (c *Clade) VaryingMarkers() []int {
+	persons := c.samplePersons()
+	var result []int
+	nMarkers := len(genetic.YstrMarkers{})
+	for i := 0; i < nMarkers; i++ {
+		first := 0.0
+		for _, person := range persons {
+			value := person.YstrMarkers[i]
+			if value <= 0 {
+				continue
+			}
+			if first == 0 {
+				first = value
+			} else if value != first {
+				result = append(result, i)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Path returns the clades from the root of the tree
+// down to this clade.
+func (c *Clade) Path() []*Clade {
+	n := 0
+	for clade := c; clade != nil; clade = clade.Parent {
+		n++
+	}
+	path := make([]*Clade, n)
+	for clade := c; clade != nil; clade = clade.Parent {
+		n--
+		path[n] = clade
+	}
+	return path
+}
+
+// FindSample returns the sample with the specified ID and the
+// clade that contains it. If the sample is not found, the results
+// are nil. If the ID appears multiple times, the first sample
+// is returned.
+func (c *Clade) FindSample(id string) (*Sample, *Clade) {
+	var sample *Sample
+	var parent *Clade
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		if s.ID == id {
+			sample = s
+			parent = path[len(path)-1]
+			return errFound
+		}
+		return nil
+	})
+	return sample, parent
+}
+
 // Subclade returns the subclade that contains searchTerm.
+// If multiple clades contain searchTerm, the first one is
+// returned. Use FindSubclade if ambiguous search terms must
+// be reported.
 func (c *Clade) Subclade(cladeName string) *Clade {
 	var result *Clade
 	if c.contains(cladeName) {
@@ -560,7 +1414,7 @@ func (c *Clade) Subclade(cladeName string) *Clade {
 	} else {
 		for i, _ := range c.Subclades {
 			if c.Subclades[i].contains(cladeName) {
-				result = &c.Subclades[i]
+				result = c.Subclades[i]
 				break
 			} else {
 				result = c.Subclades[i].Subclade(cladeName)
@@ -579,12 +1433,7 @@ func (c *Clade) Subclade(cladeName string) *Clade {
 // terminated by a non digit. This should make sure that the
 // found cladeName is not part of a longer SNP name.
 func (c *Clade) contains(cladeName string) bool {
-	for _, snp := range c.SNPs {
-		if strings.ToLower(snp) == strings.ToLower(cladeName) {
-			return true
-		}
-	}
-	return false
+	return c.Element.Contains(cladeName)
 }
 
 // lineInfo is a helper struct for parsing a tree in text format.
@@ -592,13 +1441,17 @@ type lineInfo struct {
 	lineNo int
 	indent int
 	text   string
+	// comment is the comment at the end of the line.
+	comment string
+	// comments are the comment lines in front of the line.
+	comments []string
 }
 
 // parseTree parses a tree in text format with white space indentations.
 // The function works recursively and adds all new subclades and samples
 // to the parent clade. indent is the indentation of the parent clade
-// in the text file.
-func parseTree(parent *Clade, indent int, lines []lineInfo) error {
+// in the text file. The elements are read as specified by options.
+func parseTree(parent *Clade, indent int, lines []lineInfo, options ReadOptions) error {
 	childIndent := -1
 	for i, _ := range lines {
 		switch {
@@ -613,63 +1466,31 @@ func parseTree(parent *Clade, indent int, lines []lineInfo) error {
 			// Parse child elements.
 			if strings.Contains(lines[i].text, "id:") {
 				// Child is Sample element.
-				sample, err := newSampleFromText(lines[i].text)
+				sample, err := newSampleFromText(lines[i].text, options.NegativePrefix)
 				if err != nil {
 					msg := fmt.Sprintf("line: %d, %s", lines[i].lineNo, err)
 					return errors.New(msg)
 				}
+				sample.lineNo = lines[i].lineNo
+				sample.Comment = lines[i].comment
+				sample.Comments = lines[i].comments
 				parent.AddSample(sample)
 			} else {
 				// Child is Clade element.
-				clade, err := newClade(lines[i].text)
+				clade, err := newClade(lines[i].text, options.NegativePrefix)
 				if err != nil {
 					msg := fmt.Sprintf("line: %d, %s", lines[i].lineNo, err)
 					return errors.New(msg)
 				}
-				parseTree(&clade, lines[i].indent, lines[i+1:])
-				parent.AddSubclade(clade)
+				clade.lineNo = lines[i].lineNo
+				clade.Comment = lines[i].comment
+				clade.Comments = lines[i].comments
+				if err := parseTree(&clade, lines[i].indent, lines[i+1:], options); err != nil {
+					return err
+				}
+				parent.AttachSubclade(&clade)
 			}
 		}
 	}
 	return nil
 }
-
-// stripComments removes comments from a line of text.
-// A comment starts with // like in many programming languages.
-// If a line contains only a comment or only whitespace characters
-// an empty string is returned.
-func stripComments(line string) string {
-	result := line
-	// Remove comment from line.
-	idxComment := strings.Index(line, "//")
-	if idxComment >= 0 {
-		result = line[0:idxComment]
-	}
-	// Check if line contains only whitespaces.
-	isEmpty := true
-	for _, c := range result {
-		if unicode.IsSpace(c) == false {
-			isEmpty = false
-			break
-		}
-	}
-	if isEmpty {
-		return ""
-	} else {
-		return result
-	}
-}
-
-// countSpaces counts the white spaces at the beginning
-// of a line.
-func countSpaces(line string) int {
-	result := 0
-	for _, c := range line {
-		if unicode.IsSpace(c) {
-			result++
-		} else {
-			break
-		}
-	}
-	return result
-}
@@ -0,0 +1,121 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// calculate calculates modal haplotypes, distances and ages for tree.
+func calculate(tree *Clade, persons []*genetic.Person) {
+	tree.InsertPersons(persons)
+	tree.CalculateModalHaplotypesParsimony(genetic.NewStatistics(tree.SamplePersons()), 4, false)
+	tree.CalculateDistances(genetic.DefaultMutationRates(), genetic.DistanceHybrid)
+	tree.CalculateAge(33, 1, 0)
+}
+
+// TestWeightZero checks that a sample with a weight of 0 does not
+// change any modal haplotype or age, even if it's values are wild.
+func TestWeightZero(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14, "DYS391": 10}
+	persons := []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+		newPerson(t, "3", withValue(base, "DYS19", 15)),
+		newPerson(t, "4", base),
+		newPerson(t, "5", map[string]float64{"DYS393": 20, "DYS390": 30, "DYS19": 9, "DYS391": 15}),
+	}
+	reference := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n")
+	weighted := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\t\tid:5, weight: 0\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n")
+	calculate(reference, persons)
+	calculate(weighted, persons)
+
+	for _, name := range []string{"R", "A", "B"} {
+		want, got := reference.Subclade(name), weighted.Subclade(name)
+		if got.Person.YstrMarkers != want.Person.YstrMarkers {
+			t.Errorf("%s: modal haplotype = %v, want %v", name, got.Person.YstrMarkers, want.Person.YstrMarkers)
+		}
+		if got.TMRCA_STR != want.TMRCA_STR || got.STRCount != want.STRCount {
+			t.Errorf("%s: TMRCA = %g, STR count = %g, want %g, %g", name, got.TMRCA_STR, got.STRCount, want.TMRCA_STR, want.STRCount)
+		}
+	}
+}
+
+// panelPerson returns a person that has tested the first n markers.
+func panelPerson(id string, n int) *genetic.Person {
+	person := &genetic.Person{ID: id, Name: id, Label: id}
+	for i := 0; i < n; i++ {
+		person.YstrMarkers[i] = float64(10 + i%7)
+	}
+	return person
+}
+
+// TestMarkersCompared checks the number of compared and skipped
+// markers for samples with different panels.
+func TestMarkersCompared(t *testing.T) {
+	tests := []struct {
+		id       string
+		markers  int
+		compared int
+		skipped  int
+	}{
+		{"Y37", 37, 37, 74},
+		{"Y67", 67, 67, 44},
+		{"Y111", 111, 111, 0},
+	}
+	tree := mustParse(t, "R\r\n\tid:Y37\r\n\tid:Y67\r\n\tid:Y111\r\n")
+	var persons []*genetic.Person
+	for _, test := range tests {
+		persons = append(persons, panelPerson(test.id, test.markers))
+	}
+	tree.InsertPersons(persons)
+	tree.Person = panelPerson("modal", 111)
+	tree.CalculateDistances(genetic.DefaultMutationRates(), genetic.DistanceHybrid)
+	for i, test := range tests {
+		sample := tree.Samples[i]
+		if sample.MarkersCompared != test.compared || sample.MarkersSkipped != test.skipped {
+			t.Errorf("%s: compared %d, skipped %d, want %d, %d",
+				test.id, sample.MarkersCompared, sample.MarkersSkipped, test.compared, test.skipped)
+		}
+	}
+}
+
+// TestSampledAge checks that the age of ancient samples is added in
+// years and is not scaled with the mutations.
+func TestSampledAge(t *testing.T) {
+	modern := mustParse(t, "R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 10\r\n")
+	ancient := mustParse(t, "R\r\n\tA\r\n\t\tid:1, STR-Count: 10, sampled: 3000\r\n\t\tid:2, STR-Count: 10, sampled: 3000\r\n")
+	for _, calibration := range []float64{1, 2} {
+		modern.CalculateAge(33, calibration, 0)
+		ancient.CalculateAge(33, calibration, 0)
+		for _, name := range []string{"R", "A"} {
+			want, got := modern.Subclade(name), ancient.Subclade(name)
+			if got.STRCountDownstream != want.STRCountDownstream {
+				t.Errorf("calibration %g, %s: STR count = %g, want %g", calibration, name, got.STRCountDownstream, want.STRCountDownstream)
+			}
+			if got.Sampled != 3000 || got.TMRCA_STR != want.TMRCA_STR+3000 {
+				t.Errorf("calibration %g, %s: TMRCA = %g, sampled %g, want %g", calibration, name, got.TMRCA_STR, got.Sampled, want.TMRCA_STR+3000)
+			}
+		}
+	}
+}
+
+// TestNestedParseErrors checks that invalid tokens are reported
+// with their line numbers, also in deeply nested clades.
+func TestNestedParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		line string
+	}{
+		{"R\r\n\tA\r\n\t\tB, Age: abc\r\n\t\t\tid:1\r\n", "line: 3, "},
+		{"R\r\n\tA\r\n\t\tid:1, weight: xyz\r\n", "line: 3, "},
+		{"R\r\n\tA\r\n\t\tB\r\n\t\t\tC\r\n\t\t\t\tid:1, weight: xyz\r\n", "line: 5, "},
+	}
+	for _, test := range tests {
+		_, err := NewFromReader(strings.NewReader(test.text))
+		if err == nil || !strings.Contains(err.Error(), test.line) {
+			t.Errorf("%q: error = %v, want %s...", test.text, err, test.line)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package phylotree
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// phyloXML is the root element of a phyloXML document.
+type phyloXML struct {
+	XMLName        xml.Name     `xml:"phyloxml"`
+	Xmlns          string       `xml:"xmlns,attr"`
+	XmlnsXsi       string       `xml:"xmlns:xsi,attr"`
+	SchemaLocation string       `xml:"xsi:schemaLocation,attr"`
+	Phylogeny      xmlPhylogeny `xml:"phylogeny"`
+}
+
+// xmlPhylogeny is a phylogenetic tree in phyloXML.
+type xmlPhylogeny struct {
+	Rooted bool     `xml:"rooted,attr"`
+	Clade  xmlClade `xml:"clade"`
+}
+
+// xmlClade is a clade or a sample in phyloXML.
+type xmlClade struct {
+	Name         string          `xml:"name,omitempty"`
+	BranchLength *float64        `xml:"branch_length,omitempty"`
+	Confidences  []xmlConfidence `xml:"confidence"`
+	Taxonomy     *xmlTaxonomy    `xml:"taxonomy,omitempty"`
+	Clades       []xmlClade      `xml:"clade"`
+}
+
+// xmlConfidence is a confidence value in phyloXML.
+type xmlConfidence struct {
+	Type  string  `xml:"type,attr"`
+	Value float64 `xml:",chardata"`
+}
+
+// xmlTaxonomy is the taxonomy of a clade in phyloXML.
+type xmlTaxonomy struct {
+	ScientificName string `xml:"scientific_name"`
+}
+
+// PhyloXML returns this tree in phyloXML format. Clades are named
+// by their SNPs. The confidence intervals of the TMRCAs are stored
+// as confidence elements. Samples are terminal clades, whose
+// scientific names are the sample IDs. The branch lengths are the
+// same as for the Newick format.
+func (c *Clade) PhyloXML() (string, error) {
+	doc := phyloXML{
+		Xmlns:          "http://www.phyloxml.org",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: "http://www.phyloxml.org http://www.phyloxml.org/1.10/phyloxml.xsd",
+		Phylogeny:      xmlPhylogeny{Rooted: true, Clade: c.xmlClade(nil, c.inYears())}}
+	result, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(result) + "\r\n", nil
+}
+
+// xmlClade converts this clade and all of it's descendants into
+// phyloXML clades. parent is the parent clade, nil for the root.
+// years specifies the unit of the branch lengths, see inYears.
+func (c *Clade) xmlClade(parent *Clade, years bool) xmlClade {
+	result := xmlClade{Name: strings.Join(c.SNPs, ", ")}
+	if parent != nil {
+		result.BranchLength = xmlLength(parent.subcladeBranchLength(c, years))
+	}
+	if c.STRCountDownstream >= 0 {
+		result.Confidences = []xmlConfidence{
+			{Type: "TMRCA lower", Value: c.TMRCAlower},
+			{Type: "TMRCA upper", Value: c.TMRCAupper}}
+	}
+	for i, _ := range c.Samples {
+		result.Clades = append(result.Clades, xmlClade{
+			BranchLength: xmlLength(c.sampleBranchLength(&c.Samples[i], years)),
+			Taxonomy:     &xmlTaxonomy{ScientificName: c.Samples[i].ID}})
+	}
+	for i, _ := range c.Subclades {
+		result.Clades = append(result.Clades, c.Subclades[i].xmlClade(c, years))
+	}
+	return result
+}
+
+// xmlLength returns a branch length or nil if length is unknown.
+func xmlLength(length float64) *float64 {
+	if length < 0 {
+		return nil
+	}
+	return &length
+}
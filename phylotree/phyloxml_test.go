@@ -0,0 +1,52 @@
+package phylotree
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Updates the golden files in testdata.")
+
+// threeLevelTree returns a calculated tree with three levels.
+func threeLevelTree(t *testing.T) *Clade {
+	tree := mustParse(t, "R\r\n\tA, A1\r\n\t\tid:1, STR-Count: 10\r\n\t\tB, STR-Count: 5\r\n\t\t\tid:2, STR-Count: 8\r\n\t\t\tid:3, STR-Count: 12\r\n\tid:4, STR-Count: 40\r\n")
+	ages := []struct {
+		name                      string
+		downstream, tmrca, lo, up float64
+	}{
+		{"R", 40, 1320, 1000, 1700},
+		{"A", 15, 500, 350, 700},
+		{"B", 10, 330, 200, 500},
+	}
+	for _, a := range ages {
+		clade := tree.Subclade(a.name)
+		clade.STRCountDownstream, clade.TMRCA_STR, clade.TMRCAlower, clade.TMRCAupper = a.downstream, a.tmrca, a.lo, a.up
+		clade.AgeSTR = a.tmrca
+	}
+	tree.Subclade("A").STRCount = 25
+	return tree
+}
+
+// TestPhyloXML compares the phyloXML output for a three level
+// tree with a golden file.
+func TestPhyloXML(t *testing.T) {
+	got, err := threeLevelTree(t).PhyloXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "threelevel.xml")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("phyloXML =\n%s\nwant:\n%s", got, want)
+	}
+}
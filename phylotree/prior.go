@@ -0,0 +1,126 @@
+package phylotree
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Estimate is an age estimate that is approximately normal
+// distributed.
+type Estimate struct {
+	// Age in years.
+	Age float64
+	// Sigma is the standard deviation.
+	Sigma float64
+}
+
+// lower returns the lower bound of the 95% confidence interval.
+func (e Estimate) lower() float64 {
+	return e.Age - 2*e.Sigma
+}
+
+// upper returns the upper bound of the 95% confidence interval.
+func (e Estimate) upper() float64 {
+	return e.Age + 2*e.Sigma
+}
+
+// parsePrior parses a prior from a clade annotation.
+// Format: 4500 300 (age and standard deviation).
+func parsePrior(text string) (*Estimate, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return nil, errors.New(fmt.Sprintf("invalid prior: %s", text))
+	}
+	age, err1 := strconv.ParseFloat(fields[0], 64)
+	sigma, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || sigma <= 0 {
+		return nil, errors.New(fmt.Sprintf("invalid prior: %s", text))
+	}
+	return &Estimate{Age: age, Sigma: sigma}, nil
+}
+
+// priorString returns the prior and posterior of this clade
+// in the format of the output tree.
+func (c *Clade) priorString() string {
+	if c.Prior == nil {
+		return ""
+	}
+	result := fmt.Sprintf(", prior: %.0f %.0f", c.Prior.Age, c.Prior.Sigma)
+	switch {
+	case c.Posterior != nil:
+		result += fmt.Sprintf(", posterior: %.0f %.0f", c.Posterior.Age, c.Posterior.Sigma)
+	case c.TMRCA_STR >= 0:
+		result += ", posterior: conflict"
+	}
+	return result
+}
+
+// CalculatePosteriors combines the STR based TMRCA of this clade
+// and all subclades with their priors. Both estimates are treated
+// as normal distributions. The posterior is the precision weighted
+// mean. If the 95% confidence intervals of the prior and the STR
+// estimate do not overlap, no posterior is calculated and the
+// conflict is added to the result.
+func (c *Clade) CalculatePosteriors() []string {
+	var conflicts []string
+	c.Posterior = nil
+	if c.Prior != nil && c.TMRCA_STR >= 0 && c.TMRCAupper > c.TMRCAlower {
+		if c.Prior.upper() < c.TMRCAlower || c.Prior.lower() > c.TMRCAupper {
+			conflicts = append(conflicts,
+				fmt.Sprintf("%s: prior %.0f [%.0f, %.0f] conflicts with TMRCA %.0f [%.0f, %.0f]",
+					c.SNPs[0], c.Prior.Age, c.Prior.lower(), c.Prior.upper(),
+					c.TMRCA_STR, c.TMRCAlower, c.TMRCAupper))
+		} else {
+			sigma := (c.TMRCAupper - c.TMRCAlower) / 4
+			wPrior := 1 / (c.Prior.Sigma * c.Prior.Sigma)
+			wSTR := 1 / (sigma * sigma)
+			c.Posterior = &Estimate{
+				Age:   (wPrior*c.Prior.Age + wSTR*c.TMRCA_STR) / (wPrior + wSTR),
+				Sigma: math.Sqrt(1 / (wPrior + wSTR))}
+		}
+	}
+	for i, _ := range c.Subclades {
+		conflicts = append(conflicts, c.Subclades[i].CalculatePosteriors()...)
+	}
+	return conflicts
+}
+
+// ReadPriors reads priors from a CSV file and adds them to the
+// clades of this tree.
+// Format: clade,age,sigma
+func (c *Clade) ReadPriors(filename string) error {
+	infile, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+
+	reader := csv.NewReader(infile)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	for i, record := range records {
+		prior, err := parsePrior(record[1] + " " + record[2])
+		if err != nil {
+			if i == 0 {
+				// Skip header.
+				continue
+			}
+			return errors.New(fmt.Sprintf("line: %d, %s", i+1, err))
+		}
+		clade, err := c.FindSubclade(strings.TrimSpace(record[0]))
+		if err != nil {
+			return errors.New(fmt.Sprintf("line: %d, %v", i+1, err))
+		}
+		clade.Prior = prior
+	}
+	return nil
+}
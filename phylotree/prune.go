@@ -0,0 +1,58 @@
+package phylotree
+
+// RemoveSample removes all samples with the specified ID from
+// this clade and all subclades. Clades that lose their last
+// sample remain in the tree. The result is true if a sample
+// was removed.
+func (c *Clade) RemoveSample(id string) bool {
+	isRemoved := false
+	samples := c.Samples[:0]
+	for i, _ := range c.Samples {
+		if c.Samples[i].ID == id {
+			isRemoved = true
+		} else {
+			samples = append(samples, c.Samples[i])
+		}
+	}
+	c.Samples = samples
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].RemoveSample(id) {
+			isRemoved = true
+		}
+	}
+	return isRemoved
+}
+
+// RemoveSubclade removes the subclade that is named by snp
+// together with all of it's samples and subclades. The search
+// includes all subclades but not this clade itself. The result
+// is true if a subclade was removed.
+func (c *Clade) RemoveSubclade(snp string) bool {
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].contains(snp) {
+			c.Subclades[i].Parent = nil
+			c.Subclades = append(c.Subclades[:i], c.Subclades[i+1:]...)
+			return true
+		}
+		if c.Subclades[i].RemoveSubclade(snp) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty returns true if neither this clade nor any of it's
+// subclades contain a sample that is used for the calculations.
+func (c *Clade) isEmpty() bool {
+	for i, _ := range c.Samples {
+		if !c.Samples[i].isExcluded() {
+			return false
+		}
+	}
+	for i, _ := range c.Subclades {
+		if !c.Subclades[i].isEmpty() {
+			return false
+		}
+	}
+	return true
+}
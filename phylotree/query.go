@@ -0,0 +1,147 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CladeSummary contains the time estimates of a clade and the
+// TMRCAs of it's immediate subclades.
+type CladeSummary struct {
+	SNPs []string `json:"snps"`
+	// HasAges is false if no ages could be calculated.
+	// Then all ages are Uncertain.
+	HasAges bool    `json:"hasAges"`
+	Formed  float64 `json:"formed"`
+	TMRCA   float64 `json:"tmrca"`
+	CILower float64 `json:"ciLower"`
+	CIUpper float64 `json:"ciUpper"`
+	// STRsDownstream is the average number of STR mutations
+	// downstream of the clade and Sigma it's standard deviation.
+	STRsDownstream float64 `json:"strsDownstream"`
+	Sigma          float64 `json:"sigma"`
+	// Samples is the number of samples of the clade
+	// and all subclades.
+	Samples int `json:"samples"`
+	// Completeness is the marker completeness of the downstream
+	// samples in percent per panel tier and Grade the quality
+	// grade of the modal haplotype, see Completeness.Grade.
+	Completeness map[string]float64 `json:"completeness"`
+	Grade        string             `json:"grade"`
+	Subclades    []SubcladeSummary  `json:"subclades"`
+}
+
+// SubcladeSummary contains the TMRCA of a subclade.
+type SubcladeSummary struct {
+	Name    string  `json:"name"`
+	HasAges bool    `json:"hasAges"`
+	TMRCA   float64 `json:"tmrca"`
+}
+
+// Summary returns a summary of this clade's time estimates.
+func (c *Clade) Summary() CladeSummary {
+	result := CladeSummary{
+		SNPs:           c.SNPs,
+		HasAges:        c.STRCountDownstream >= 0,
+		Formed:         Uncertain,
+		TMRCA:          Uncertain,
+		CILower:        Uncertain,
+		CIUpper:        Uncertain,
+		STRsDownstream: Uncertain,
+		Sigma:          Uncertain,
+		Subclades:      make([]SubcladeSummary, 0, len(c.Subclades))}
+	result.Samples, _ = c.DownstreamSampleCount()
+	completeness := c.Completeness()
+	result.Completeness = completeness.PanelPercentages()
+	result.Grade = completeness.Grade()
+	if result.HasAges {
+		result.Formed = c.AgeSTR
+		result.TMRCA = c.TMRCA_STR
+		result.CILower = c.TMRCAlower
+		result.CIUpper = c.TMRCAupper
+		result.STRsDownstream = c.STRCountDownstream
+		result.Sigma = math.Sqrt(c.Sigma2)
+	}
+	for _, subclade := range c.Subclades {
+		sub := SubcladeSummary{Name: subclade.SNPs[0], HasAges: subclade.STRCountDownstream >= 0, TMRCA: Uncertain}
+		if sub.HasAges {
+			sub.TMRCA = subclade.TMRCA_STR
+		}
+		result.Subclades = append(result.Subclades, sub)
+	}
+	return result
+}
+
+// String returns the summary as a short text block.
+func (s CladeSummary) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(strings.Join(s.SNPs, ", ") + "\r\n")
+	if s.HasAges {
+		buffer.WriteString(fmt.Sprintf("\tformed: %.0f, TMRCA: %.0f, CI:[%.0f, %.0f]\r\n",
+			s.Formed, s.TMRCA, s.CILower, s.CIUpper))
+		buffer.WriteString(fmt.Sprintf("\tSTRs Downstream: %.2f, sigma: %.2f, samples: %d\r\n",
+			s.STRsDownstream, s.Sigma, s.Samples))
+	} else {
+		buffer.WriteString(fmt.Sprintf("\tage unknown, samples: %d\r\n", s.Samples))
+	}
+	buffer.WriteString("\tcompleteness:")
+	for _, p := range panels {
+		buffer.WriteString(fmt.Sprintf(" %s: %.0f%%,", p.name, s.Completeness[p.name]))
+	}
+	buffer.WriteString(" grade: " + s.Grade + "\r\n")
+	for _, sub := range s.Subclades {
+		if sub.HasAges {
+			buffer.WriteString(fmt.Sprintf("\t\t%s, TMRCA: %.0f\r\n", sub.Name, sub.TMRCA))
+		} else {
+			buffer.WriteString(fmt.Sprintf("\t\t%s, TMRCA: unknown\r\n", sub.Name))
+		}
+	}
+	return buffer.String()
+}
+
+// Query returns the summaries of the clades named by names.
+// All names must be found.
+func (c *Clade) Query(names []string) ([]CladeSummary, error) {
+	var result []CladeSummary
+	var missing []string
+	for _, name := range names {
+		clade, err := c.FindSubclade(strings.TrimSpace(name))
+		if err != nil {
+			missing = append(missing, err.Error())
+			continue
+		}
+		result = append(result, clade.Summary())
+	}
+	if len(missing) > 0 {
+		return nil, errors.New(strings.Join(missing, "; "))
+	}
+	return result, nil
+}
+
+// QueryReport returns the summaries of the clades named by names
+// in the specified format: text or json.
+func (c *Clade) QueryReport(names []string, format string) (string, error) {
+	summaries, err := c.Query(names)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "text":
+		var buffer bytes.Buffer
+		for _, summary := range summaries {
+			buffer.WriteString(summary.String())
+		}
+		return buffer.String(), nil
+	case "json":
+		data, err := json.MarshalIndent(summaries, "", "\t")
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\r\n", nil
+	}
+	return "", errors.New("unknown query format: " + format)
+}
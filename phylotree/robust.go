@@ -0,0 +1,138 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Averaging specifies how the STR counts of the samples of a
+// clade are averaged by CalculateAge.
+type Averaging struct {
+	// Median uses the weighted median of the STR counts instead
+	// of the weighted mean. The median is less sensitive to single
+	// samples with many private mutations.
+	Median bool
+	// Trim is the fraction of the smallest and of the largest STR
+	// counts that are dropped before the mean is calculated. Clades
+	// with fewer than 1/Trim samples use the plain mean. 0 means
+	// no trimming.
+	Trim float64
+	// Winsorize sets the trimmed STR counts to the nearest remaining
+	// STR count instead of dropping them.
+	Winsorize bool
+}
+
+// madFactor converts the median absolute deviation into an
+// estimate of the standard deviation for normal distributions.
+const madFactor = 1.4826
+
+// weightedMedian returns the weighted median of values. weights
+// contains a weight for each value.
+func weightedMedian(values, weights []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	indices := make([]int, len(values))
+	total := 0.0
+	for i, _ := range values {
+		indices[i] = i
+		total += weights[i]
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return values[indices[a]] < values[indices[b]]
+	})
+	sum := 0.0
+	for k, i := range indices {
+		sum += weights[i]
+		switch {
+		case sum > total/2:
+			return values[i]
+		case sum == total/2 && k+1 < len(indices):
+			// Even split, use the middle of both values.
+			return (values[i] + values[indices[k+1]]) / 2
+		}
+	}
+	return values[indices[len(indices)-1]]
+}
+
+// medianSigma2 returns the weighted median of the STR counts and
+// the squared standard deviation of the median. The standard
+// deviation is estimated from the median absolute deviation (MAD).
+// The variance of the median of n normally distributed values is
+// π/2 times the variance of the mean. nSamples is the effective
+// number of samples. The result is never smaller than the variance
+// of the Poisson distribution that is used for the mean, because
+// the MAD of a few samples may be 0. coverage is the average factor
+// for the variance due to marker coverage.
+func medianSigma2(counts, weights []float64, nSamples, coverage float64) (median, sigma2 float64) {
+	median = weightedMedian(counts, weights)
+	deviations := make([]float64, len(counts))
+	for i, count := range counts {
+		deviations[i] = math.Abs(count - median)
+	}
+	sigma := madFactor * weightedMedian(deviations, weights)
+	sigma2 = math.Pi / 2 * sigma * sigma / nSamples
+	if poisson := median / nSamples * coverage; poisson > sigma2 {
+		sigma2 = poisson
+	}
+	return median, sigma2
+}
+
+// trimmedMean returns the weighted mean of the STR counts after the
+// smallest and the largest fraction trim of the counts have been
+// dropped or, if winsorize is true, set to the nearest remaining
+// count. nSamples is the effective number of the remaining samples
+// and trimmed is the number of trimmed samples. If there are fewer
+// than 1/trim counts, nothing is trimmed.
+func trimmedMean(counts, weights []float64, trim float64, winsorize bool) (mean, nSamples float64, trimmed int) {
+	indices := make([]int, len(counts))
+	for i, _ := range counts {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return counts[indices[a]] < counts[indices[b]]
+	})
+	k := 0
+	if trim > 0 && float64(len(counts)) >= 1/trim {
+		k = int(trim * float64(len(counts)))
+	}
+	// The effective number of samples only includes the samples
+	// that have not been trimmed.
+	var sumW, sum, insideW, insideW2 float64
+	for rank, i := range indices {
+		value := counts[i]
+		switch {
+		case rank >= k && rank < len(indices)-k:
+			insideW += weights[i]
+			insideW2 += weights[i] * weights[i]
+		case !winsorize:
+			continue
+		case rank < k:
+			value = counts[indices[k]]
+		default:
+			value = counts[indices[len(indices)-k-1]]
+		}
+		sum += weights[i] * value
+		sumW += weights[i]
+	}
+	if sumW == 0 || insideW2 == 0 {
+		return 0, 0, 2 * k
+	}
+	return sum / sumW, insideW * insideW / insideW2, 2 * k
+}
+
+// TrimReport returns the number of trimmed samples for all
+// clades in which samples have been trimmed by CalculateAge.
+func (c *Clade) TrimReport() string {
+	var buffer bytes.Buffer
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.Trimmed > 0 {
+			buffer.WriteString(fmt.Sprintf("%s: %d of %d samples trimmed\r\n",
+				clade.SNPs[0], clade.Trimmed, len(clade.Samples)))
+		}
+		return nil
+	})
+	return buffer.String()
+}
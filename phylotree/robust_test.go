@@ -0,0 +1,35 @@
+package phylotree
+
+import (
+	"testing"
+)
+
+// TestCalculateAgeAveraging checks that CalculateAge averages by the
+// mean like CalculateAgeAveraging without options and that the median
+// is not moved by a single sample with many mutations.
+func TestCalculateAgeAveraging(t *testing.T) {
+	text := "R\r\n\tid:1, STR-Count: 10\r\n\tid:2, STR-Count: 12\r\n\tid:3, STR-Count: 14\r\n\tid:4, STR-Count: 100\r\n"
+	tests := []struct {
+		name      string
+		averaging Averaging
+		count     float64
+	}{
+		{"mean", Averaging{}, 34},
+		{"median", Averaging{Median: true}, 13},
+	}
+	for _, test := range tests {
+		tree := mustParse(t, text)
+		tree.CalculateAgeAveraging(33, 1, 0, test.averaging)
+		tree.RecalculateAgeAveraging(33, 1, 0, test.averaging)
+		if tree.STRCountDownstream != test.count || tree.TMRCA_STR != test.count*33 {
+			t.Errorf("%s: STR count = %g, TMRCA = %g, want %g, %g", test.name,
+				tree.STRCountDownstream, tree.TMRCA_STR, test.count, test.count*33)
+		}
+	}
+
+	tree := mustParse(t, text)
+	tree.CalculateAge(33, 1, 0)
+	if tree.STRCountDownstream != 34 {
+		t.Errorf("CalculateAge: STR count = %g, want 34", tree.STRCountDownstream)
+	}
+}
@@ -0,0 +1,83 @@
+package phylotree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// snpIndex maps lower case SNP names to the clades that contain them.
+// Equivalent SNP names within a single token are indexed separately.
+func (c *Clade) snpIndex() (index map[string][]*Clade, snps []string) {
+	index = make(map[string][]*Clade)
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		keys := make(map[string]bool)
+		for _, snp := range clade.SNPs {
+			for _, alias := range strings.FieldsFunc(snp, isAliasSeparator) {
+				key := strings.ToLower(trimName(alias))
+				if key == "" || keys[key] {
+					continue
+				}
+				keys[key] = true
+				if _, exists := index[key]; !exists {
+					snps = append(snps, alias)
+				}
+				index[key] = append(index[key], clade)
+			}
+		}
+		return nil
+	})
+	return index, snps
+}
+
+// cladePosition returns the name and the line number of a clade
+// for error messages.
+func (c *Clade) cladePosition() string {
+	if c.lineNo > 0 {
+		return fmt.Sprintf("%s line %d", c.SNPs[0], c.lineNo)
+	}
+	return c.SNPs[0]
+}
+
+// DuplicateSNPs returns a list of all SNPs that appear on more than
+// one clade together with the clades and their line numbers.
+func (c *Clade) DuplicateSNPs() []string {
+	var result []string
+	index, snps := c.snpIndex()
+	for _, snp := range snps {
+		clades := index[strings.ToLower(trimName(snp))]
+		if len(clades) < 2 {
+			continue
+		}
+		positions := make([]string, len(clades))
+		for i, clade := range clades {
+			positions[i] = clade.cladePosition()
+		}
+		result = append(result, fmt.Sprintf("SNP %s appears on multiple clades: %s", snp, strings.Join(positions, ", ")))
+	}
+	return result
+}
+
+// FindSubclade returns the subclade that contains cladeName.
+// An error is returned if no clade or more than one clade
+// contains cladeName.
+func (c *Clade) FindSubclade(cladeName string) (*Clade, error) {
+	var clades []*Clade
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.contains(cladeName) {
+			clades = append(clades, clade)
+		}
+		return nil
+	})
+	switch len(clades) {
+	case 0:
+		return nil, errors.New(fmt.Sprintf("could not find clade %s", cladeName))
+	case 1:
+		return clades[0], nil
+	}
+	positions := make([]string, len(clades))
+	for i, clade := range clades {
+		positions[i] = clade.cladePosition()
+	}
+	return nil, errors.New(fmt.Sprintf("clade %s is ambiguous: %s", cladeName, strings.Join(positions, ", ")))
+}
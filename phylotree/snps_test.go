@@ -0,0 +1,62 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDuplicateSNPs checks that SNPs on an ancestor and it's
+// descendant are reported as well as SNPs on parallel branches.
+func TestDuplicateSNPs(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     string
+		expected string
+	}{
+		{"ancestor and descendant",
+			"R\r\n\tA, Y1\r\n\t\tB, y1\r\n",
+			"SNP Y1 appears on multiple clades: A line 2, B line 3"},
+		{"parallel branches",
+			"R\r\n\tA\r\n\t\tY1\r\n\tB\r\n\t\tFGC5/Y1\r\n",
+			"SNP Y1 appears on multiple clades: Y1 line 3, FGC5/Y1 line 5"},
+	}
+	for _, test := range tests {
+		tree := mustParse(t, test.tree)
+		duplicates := tree.DuplicateSNPs()
+		if len(duplicates) != 1 || duplicates[0] != test.expected {
+			t.Errorf("%s: duplicates = %q, expected %q", test.name, duplicates, test.expected)
+		}
+		if _, err := tree.FindSubclade("Y1"); err == nil {
+			t.Errorf("%s: ambiguous clade not reported", test.name)
+		}
+		result := tree.Inspect([]string{"Y1"})
+		if !strings.Contains(result, "Warning, Y1 appears on multiple clades") {
+			t.Errorf("%s: Inspect does not warn about ambiguous term:\n%s", test.name, result)
+		}
+	}
+
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tY1\r\n\tB\r\n\t\tY2\r\n")
+	if duplicates := tree.DuplicateSNPs(); len(duplicates) != 0 {
+		t.Errorf("unique SNPs reported as duplicates: %q", duplicates)
+	}
+	if strings.Contains(tree.Inspect([]string{"Y1"}), "Warning") {
+		t.Errorf("Inspect warns about unique SNP")
+	}
+}
+
+// TestMergeAliasConflict checks that an SNP of a merged or grafted
+// tree conflicts with an alias that is already part of the tree.
+func TestMergeAliasConflict(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tFGC123/Y5\r\n\tB\r\n")
+	other := mustParse(t, "B\r\n\tC\r\n\t\ty5\r\n")
+	err := tree.Merge(other)
+	if err == nil || err.Error() != "SNP y5 appears at different positions" {
+		t.Errorf("error = %v", err)
+	}
+
+	graft := mustParse(t, "D\r\n\tBY7=Fgc123\r\n")
+	err = mustParse(t, "R\r\n\tA\r\n\t\tFGC123/Y5\r\n\tB\r\n").Graft("B", graft)
+	if err == nil || err.Error() != "SNP BY7=Fgc123 of grafted tree is already part of the tree" {
+		t.Errorf("error = %v", err)
+	}
+}
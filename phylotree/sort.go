@@ -0,0 +1,122 @@
+package phylotree
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Sorted returns a copy of this clade whose subclades and samples
+// are sorted for the output. cladeOrder is the order of subclades:
+// tmrca sorts the oldest subclades first, name sorts by the first
+// SNP and input keeps the order of the input tree. Subclades without
+// a TMRCA are sorted last. sampleOrder is the order of samples:
+// id sorts by sample ID in natural order, so that N2345 comes before
+// N12345, strcount sorts by STR count and input keeps the order of the
+// input tree. Samples without a STR count are sorted last.
+// The calculation is not changed by the order.
+func (c *Clade) Sorted(cladeOrder, sampleOrder string) (*Clade, error) {
+	var lessClade func(a, b *Clade) bool
+	switch cladeOrder {
+	case "tmrca":
+		lessClade = func(a, b *Clade) bool {
+			switch {
+			case a.STRCountDownstream < 0 || b.STRCountDownstream < 0:
+				if (a.STRCountDownstream < 0) != (b.STRCountDownstream < 0) {
+					return b.STRCountDownstream < 0
+				}
+			case a.TMRCA_STR != b.TMRCA_STR:
+				return a.TMRCA_STR > b.TMRCA_STR
+			}
+			return strings.ToLower(a.SNPs[0]) < strings.ToLower(b.SNPs[0])
+		}
+	case "name":
+		lessClade = func(a, b *Clade) bool {
+			return strings.ToLower(a.SNPs[0]) < strings.ToLower(b.SNPs[0])
+		}
+	case "input":
+	default:
+		return nil, errors.New("unknown sort order for subclades: " + cladeOrder)
+	}
+	var lessSample func(a, b *Sample) bool
+	switch sampleOrder {
+	case "id":
+		lessSample = func(a, b *Sample) bool {
+			return naturalLess(a.ID, b.ID)
+		}
+	case "strcount":
+		lessSample = func(a, b *Sample) bool {
+			if (a.STRCount < 0) != (b.STRCount < 0) {
+				return b.STRCount < 0
+			}
+			return a.STRCount < b.STRCount
+		}
+	case "input":
+	default:
+		return nil, errors.New("unknown sort order for samples: " + sampleOrder)
+	}
+
+	result := c.Clone()
+	result.Walk(func(path []*Clade, clade *Clade) error {
+		if lessClade != nil {
+			subclades := clade.Subclades
+			sort.SliceStable(subclades, func(i, j int) bool {
+				return lessClade(subclades[i], subclades[j])
+			})
+		}
+		if lessSample != nil {
+			samples := clade.Samples
+			sort.SliceStable(samples, func(i, j int) bool {
+				return lessSample(&samples[i], &samples[j])
+			})
+		}
+		return nil
+	})
+	return result, nil
+}
+
+// naturalLess compares a and b in natural order. Sequences of digits
+// are compared by their numerical values and all other characters
+// are compared without case. If both are equal in this order, they
+// are compared byte by byte, so that the order is deterministic.
+func naturalLess(a, b string) bool {
+	x, y := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		if unicode.IsDigit(x[i]) && unicode.IsDigit(y[j]) {
+			// Compare numbers without leading zeros.
+			for i < len(x) && x[i] == '0' {
+				i++
+			}
+			for j < len(y) && y[j] == '0' {
+				j++
+			}
+			startX, startY := i, j
+			for i < len(x) && unicode.IsDigit(x[i]) {
+				i++
+			}
+			for j < len(y) && unicode.IsDigit(y[j]) {
+				j++
+			}
+			numX, numY := string(x[startX:i]), string(y[startY:j])
+			if len(numX) != len(numY) {
+				return len(numX) < len(numY)
+			}
+			if numX != numY {
+				return numX < numY
+			}
+			continue
+		}
+		rx, ry := unicode.ToLower(x[i]), unicode.ToLower(y[j])
+		if rx != ry {
+			return rx < ry
+		}
+		i++
+		j++
+	}
+	if len(x)-i != len(y)-j {
+		return len(x)-i < len(y)-j
+	}
+	return a < b
+}
@@ -0,0 +1,126 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// StepLimit limits the number of mutation steps that are counted
+// for the difference of a single marker. Large differences are
+// most likely a single multi-step event or a data error.
+type StepLimit struct {
+	// MaxSteps is the maximum number of steps for a single marker.
+	// A value <= 0 means unlimited.
+	MaxSteps float64
+	// IsSingle specifies that differences larger than MaxSteps
+	// count as a single mutation. Otherwise they are capped
+	// at MaxSteps.
+	IsSingle bool
+}
+
+// steps returns the number of steps that are counted for a
+// difference of marker values.
+func (s StepLimit) steps(diff float64) float64 {
+	if s.MaxSteps <= 0 || diff <= s.MaxSteps {
+		return diff
+	}
+	if s.IsSingle {
+		return 1
+	}
+	return s.MaxSteps
+}
+
+// Distance returns a distance function that applies the step
+// limit before the distance is calculated by distance.
+func (s StepLimit) Distance(distance genetic.DistanceFunc) genetic.DistanceFunc {
+	if s.MaxSteps <= 0 {
+		return distance
+	}
+	return func(ystr1, ystr2 genetic.YstrMarkers, mutationRates genetic.YstrMarkers) float64 {
+		for i, _ := range ystr1 {
+			if ystr1[i] > 0 && ystr2[i] > 0 {
+				diff := ystr1[i] - ystr2[i]
+				steps := s.steps(math.Abs(diff))
+				if diff < 0 {
+					steps = -steps
+				}
+				ystr1[i] = ystr2[i] + steps
+			}
+		}
+		return distance(ystr1, ystr2, mutationRates)
+	}
+}
+
+// DistanceStepwise calculates the genetic distance between two
+// haplotypes by using the stepwise mutation model for all markers.
+// The number of mutation steps is divided by the sum of the mutation
+// rates of the compared markers, so that the result is a number of
+// generations like for the other distance functions. Only markers
+// with values in both haplotypes and a mutation rate > 0 are compared.
+func DistanceStepwise(ystr1, ystr2 genetic.YstrMarkers, mutationRates genetic.YstrMarkers) float64 {
+	steps := 0.0
+	for i, _ := range ystr1 {
+		if ystr1[i] > 0 && ystr2[i] > 0 && mutationRates[i] > 0 {
+			steps += math.Abs(ystr1[i] - ystr2[i])
+		}
+	}
+	rate := comparedRate(ystr1, ystr2, mutationRates)
+	if rate == 0 {
+		return 0
+	}
+	return steps / rate
+}
+
+// StepLimitReport returns a list of all samples and clades, for
+// which a marker difference to the parent clade exceeds the
+// maximum number of steps.
+func (c *Clade) StepLimitReport(limit StepLimit) string {
+	var buffer bytes.Buffer
+	if limit.MaxSteps > 0 {
+		c.stepLimitPrint(&buffer, limit)
+	}
+	return buffer.String()
+}
+
+// stepLimitPrint creates the list for StepLimitReport.
+func (c *Clade) stepLimitPrint(buffer *bytes.Buffer, limit StepLimit) {
+	if c.Person == nil {
+		return
+	}
+	for i, _ := range c.Samples {
+		if c.Samples[i].Person != nil {
+			details := exceededSteps(c.Samples[i].Person.YstrMarkers, c.Person.YstrMarkers, limit)
+			if details != "" {
+				buffer.WriteString(fmt.Sprintf("id:%s:%s\r\n", c.Samples[i].ID, details))
+			}
+		}
+	}
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].Person != nil {
+			details := exceededSteps(c.Subclades[i].Person.YstrMarkers, c.Person.YstrMarkers, limit)
+			if details != "" {
+				buffer.WriteString(fmt.Sprintf("%s:%s\r\n", c.Subclades[i].SNPs[0], details))
+			}
+		}
+		c.Subclades[i].stepLimitPrint(buffer, limit)
+	}
+}
+
+// exceededSteps returns the names and differences of all markers
+// that differ by more than the maximum number of steps.
+func exceededSteps(ystr1, ystr2 genetic.YstrMarkers, limit StepLimit) string {
+	var buffer bytes.Buffer
+	for i, _ := range ystr1 {
+		if ystr1[i] > 0 && ystr2[i] > 0 {
+			diff := math.Abs(ystr1[i] - ystr2[i])
+			if diff > limit.MaxSteps {
+				name := genetic.YstrMarkerTable[i].InternalName
+				buffer.WriteString(fmt.Sprintf(" %s: %g steps, counted: %g,", name, diff, limit.steps(diff)))
+			}
+		}
+	}
+	return buffer.String()
+}
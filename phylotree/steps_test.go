@@ -0,0 +1,43 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestStepLimit checks a CDY difference of 6 steps under both
+// modes of the step limit.
+func TestStepLimit(t *testing.T) {
+	cdy := mustIndex(t, "CDYa")
+	var rates genetic.YstrMarkers
+	rates[cdy] = 0.01
+	var ystr1, ystr2 genetic.YstrMarkers
+	ystr1[cdy], ystr2[cdy] = 36, 42
+
+	tests := []struct {
+		name    string
+		limit   StepLimit
+		want    float64
+		counted string
+	}{
+		{"unlimited", StepLimit{}, 600, ""},
+		{"cap", StepLimit{MaxSteps: 2}, 200, "counted: 2"},
+		{"single", StepLimit{MaxSteps: 2, IsSingle: true}, 100, "counted: 1"},
+		{"below limit", StepLimit{MaxSteps: 6}, 600, ""},
+	}
+	for _, test := range tests {
+		got := test.limit.Distance(DistanceStepwise)(ystr1, ystr2, rates)
+		if got < test.want-1e-9 || got > test.want+1e-9 {
+			t.Errorf("%s: distance = %g, want %g", test.name, got, test.want)
+		}
+		report := exceededSteps(ystr1, ystr2, test.limit)
+		if test.counted == "" && test.limit.MaxSteps > 0 && report != "" {
+			t.Errorf("%s: unexpected report %q", test.name, report)
+		}
+		if test.counted != "" && !strings.Contains(report, "CDYa: 6 steps, "+test.counted) {
+			t.Errorf("%s: report = %q, want %s", test.name, report, test.counted)
+		}
+	}
+}
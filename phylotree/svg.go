@@ -0,0 +1,167 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math"
+)
+
+// svgMargin is the margin around the drawing in pixels.
+const svgMargin = 20
+
+// SVGOptions are the parameters for drawing a tree as SVG.
+type SVGOptions struct {
+	// Scale is the number of pixels per 100 years.
+	Scale float64
+	// FontSize is the font size in pixels.
+	FontSize float64
+	// ShowSamples specifies if the sample IDs are drawn.
+	ShowSamples bool
+	// Offset is the age of the samples in years.
+	Offset float64
+}
+
+// svgLayout holds the state while drawing a tree.
+type svgLayout struct {
+	options SVGOptions
+	// maxAge is the age at the left border of the drawing.
+	maxAge float64
+	// rows is the number of rows so far. Each leaf gets it's own row.
+	rows   int
+	buffer bytes.Buffer
+}
+
+// x returns the horizontal position of age.
+func (l *svgLayout) x(age float64) float64 {
+	return svgMargin + (l.maxAge-age)*l.options.Scale/100
+}
+
+// rowHeight returns the height of a row.
+func (l *svgLayout) rowHeight() float64 {
+	return 1.5 * l.options.FontSize
+}
+
+// nextRow returns the vertical position of a new row.
+func (l *svgLayout) nextRow() float64 {
+	y := svgMargin + (float64(l.rows)+0.5)*l.rowHeight()
+	l.rows++
+	return y
+}
+
+// line draws a line from (x1, y1) to (x2, y2).
+func (l *svgLayout) line(x1, y1, x2, y2 float64, attributes string) {
+	l.buffer.WriteString(fmt.Sprintf("<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"black\"%s/>\r\n",
+		x1, y1, x2, y2, attributes))
+}
+
+// text draws text at position (x, y).
+func (l *svgLayout) text(x, y float64, text, attributes string) {
+	l.buffer.WriteString(fmt.Sprintf("<text x=\"%.1f\" y=\"%.1f\" font-size=\"%g\"%s>%s</text>\r\n",
+		x, y, l.options.FontSize, attributes, html.EscapeString(text)))
+}
+
+// SVG draws this tree on a time axis in SVG format.
+// Clades are drawn at their TMRCAs with whiskers for the confidence
+// intervals. Clades without ages are drawn at the age of their parents.
+func (c *Clade) SVG(options SVGOptions) string {
+	layout := svgLayout{options: options, maxAge: math.Max(c.maxAge(), options.Offset+1)}
+	c.svgDraw(&layout, layout.maxAge)
+	width := layout.x(0) + 10*options.FontSize
+	axisY := svgMargin + float64(layout.rows)*layout.rowHeight() + options.FontSize
+	layout.svgAxis(axisY)
+	height := axisY + 3*options.FontSize + svgMargin
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\r\n")
+	buffer.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" font-family=\"sans-serif\">\r\n",
+		width, height))
+	buffer.Write(layout.buffer.Bytes())
+	buffer.WriteString("</svg>\r\n")
+	return buffer.String()
+}
+
+// maxAge returns the largest age of this clade and all of it's
+// subclades, including the confidence intervals.
+func (c *Clade) maxAge() float64 {
+	max := 0.0
+	if c.STRCountDownstream >= 0 {
+		max = math.Max(c.TMRCA_STR, c.TMRCAupper)
+	}
+	for i, _ := range c.Subclades {
+		max = math.Max(max, c.Subclades[i].maxAge())
+	}
+	return max
+}
+
+// svgDraw draws this clade and all of it's descendants.
+// parentAge is the age of the parent clade. The result is
+// the vertical position of this clade.
+func (c *Clade) svgDraw(l *svgLayout, parentAge float64) float64 {
+	age := parentAge
+	if c.STRCountDownstream >= 0 {
+		age = c.TMRCA_STR
+	}
+	x := l.x(age)
+
+	// Draw children.
+	var ys []float64
+	for i, _ := range c.Samples {
+		y := l.nextRow()
+		xSample := l.x(l.options.Offset)
+		l.line(x, y, xSample, y, "")
+		l.line(xSample, y-l.options.FontSize/3, xSample, y+l.options.FontSize/3, "")
+		if l.options.ShowSamples {
+			l.text(xSample+4, y+l.options.FontSize/3, c.Samples[i].ID, "")
+		}
+		ys = append(ys, y)
+	}
+	for i, _ := range c.Subclades {
+		ys = append(ys, c.Subclades[i].svgDraw(l, age))
+	}
+	var y float64
+	if len(ys) == 0 {
+		y = l.nextRow()
+	} else {
+		y = (ys[0] + ys[len(ys)-1]) / 2
+		l.line(x, ys[0], x, ys[len(ys)-1], "")
+	}
+
+	// Draw this clade.
+	l.line(l.x(parentAge), y, x, y, "")
+	if c.STRCountDownstream >= 0 {
+		lower, upper := l.x(c.TMRCAlower), l.x(c.TMRCAupper)
+		tick := l.options.FontSize / 4
+		l.line(upper, y, lower, y, " stroke-opacity=\"0.5\" stroke-width=\"3\"")
+		l.line(upper, y-tick, upper, y+tick, "")
+		l.line(lower, y-tick, lower, y+tick, "")
+		l.buffer.WriteString(fmt.Sprintf("<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\"/>\r\n", x, y, tick))
+	}
+	l.text(x+3, y-3, c.SNPs[0], " font-weight=\"bold\"")
+	return y
+}
+
+// svgAxis draws the time axis at the vertical position y.
+func (l *svgLayout) svgAxis(y float64) {
+	l.line(l.x(l.maxAge), y, l.x(0), y, "")
+	step := svgStep(l.maxAge)
+	for age := 0.0; age <= l.maxAge; age += step {
+		x := l.x(age)
+		l.line(x, y, x, y+l.options.FontSize/2, "")
+		l.text(x, y+1.5*l.options.FontSize, fmt.Sprintf("%.0f", age), " text-anchor=\"middle\"")
+	}
+	l.text(l.x(0), y+2.8*l.options.FontSize, "years before present", " text-anchor=\"end\"")
+}
+
+// svgStep returns a step size for the ticks of a time axis
+// of length maxAge, so that there are about 10 ticks.
+func svgStep(maxAge float64) float64 {
+	step := 100.0
+	for _, s := range []float64{100, 200, 500, 1000, 2000, 5000, 10000, 20000, 50000} {
+		step = s
+		if maxAge/s <= 10 {
+			break
+		}
+	}
+	return step
+}
@@ -0,0 +1,31 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSVGHiddenSamples checks that hidden sample IDs keep the
+// sample ticks.
+func TestSVGHiddenSamples(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:K1\r\n\t\tid:K2\r\n\tid:K3\r\n")
+	tree.STRCountDownstream, tree.TMRCA_STR, tree.TMRCAlower, tree.TMRCAupper = 30, 1000, 800, 1200
+	a := tree.Subclade("A")
+	a.STRCountDownstream, a.TMRCA_STR, a.TMRCAlower, a.TMRCAupper = 15, 500, 400, 600
+	options := SVGOptions{Scale: 10, FontSize: 12, ShowSamples: true}
+
+	shown := tree.SVG(options)
+	options.ShowSamples = false
+	hidden := tree.SVG(options)
+	for _, id := range []string{"K1", "K2", "K3"} {
+		if !strings.Contains(shown, ">"+id+"<") {
+			t.Errorf("sample %s missing", id)
+		}
+		if strings.Contains(hidden, ">"+id+"<") {
+			t.Errorf("hidden sample %s is drawn", id)
+		}
+	}
+	if n, m := strings.Count(shown, "<line"), strings.Count(hidden, "<line"); n != m {
+		t.Errorf("lines with hidden samples = %d, want %d", m, n)
+	}
+}
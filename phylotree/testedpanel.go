@@ -0,0 +1,149 @@
+package phylotree
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// panelY500 is the name of tests with more than 111 markers,
+// for example from YFull or Big Y.
+const panelY500 = "Y500"
+
+// minExtraMarkers is the number of tested markers beyond the
+// Y111 panel that makes a test a Y500 test.
+const minExtraMarkers = 100
+
+// panelGap is the number of panel tiers between the smallest and
+// the largest panel of a clade that are not comparable anymore.
+const panelGap = 3
+
+// panelNames returns the names of all panels that may be
+// returned by TestedPanel in ascending order.
+func panelNames() []string {
+	names := make([]string, 0, len(panels)+1)
+	for _, p := range panels {
+		names = append(names, p.name)
+	}
+	return append(names, panelY500)
+}
+
+// TestedPanel infers the panel that has been tested for person,
+// Y12, Y25, Y37, Y67, Y111 or Y500. The panel is the highest tier
+// of a contiguous block of tiers, starting at Y12, in which at least
+// half of the markers have values. Occasional null values do not
+// change the result. Tests with at least 100 markers beyond Y111
+// are Y500 tests. The result is empty if not even Y12 has been tested.
+func TestedPanel(person *genetic.Person) string {
+	result := ""
+	for _, p := range panels {
+		tested := 0
+		for i := p.first; i < p.last && i < len(person.YstrMarkers); i++ {
+			if person.YstrMarkers[i] > 0 {
+				tested++
+			}
+		}
+		if 2*tested < p.last-p.first {
+			return result
+		}
+		result = p.name
+	}
+	extra := 0
+	for i := panels[len(panels)-1].last; i < len(person.YstrMarkers); i++ {
+		if person.YstrMarkers[i] > 0 {
+			extra++
+		}
+	}
+	if extra >= minExtraMarkers {
+		result = panelY500
+	}
+	return result
+}
+
+// PanelCounts returns the number of downstream samples of this clade
+// for each tested panel. Samples without a panel are counted with an
+// empty name.
+func (c *Clade) PanelCounts() map[string]int {
+	result := make(map[string]int)
+	for _, person := range c.samplePersons() {
+		result[TestedPanel(person)]++
+	}
+	return result
+}
+
+// isMixed checks if the smallest and the largest panel of counts
+// are so far apart that the TMRCAs are not comparable.
+func isMixed(counts map[string]int) (smallest, largest string, mixed bool) {
+	first, last := -1, -1
+	names := panelNames()
+	for i, name := range names {
+		if counts[name] > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return "", "", false
+	}
+	return names[first], names[last], last-first >= panelGap
+}
+
+// PanelsReport returns a nicely formatted tree that shows for each
+// clade the number of downstream samples per tested panel. Clades
+// whose samples mix very small and very large panels are marked.
+func (c *Clade) PanelsReport() string {
+	var buffer bytes.Buffer
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		for i := 0; i < len(path); i++ {
+			buffer.WriteString("\t")
+		}
+		buffer.WriteString(clade.Element.String())
+		counts := clade.PanelCounts()
+		for _, name := range panelNames() {
+			if counts[name] > 0 {
+				buffer.WriteString(fmt.Sprintf(", %s: %d", name, counts[name]))
+			}
+		}
+		if counts[""] > 0 {
+			buffer.WriteString(fmt.Sprintf(", less than Y12: %d", counts[""]))
+		}
+		if _, _, mixed := isMixed(counts); mixed {
+			buffer.WriteString(", mixed panels")
+		}
+		buffer.WriteString("\r\n")
+		return nil
+	})
+	return buffer.String()
+}
+
+// PanelWarnings returns a warning for each clade whose samples
+// mix very small and very large panels, because the TMRCAs of
+// such clades are not comparable with other clades. Only the
+// deepest of such clades are reported, because their parents
+// always contain the same mix.
+func (c *Clade) PanelWarnings() []string {
+	var result []string
+	c.panelWarnings(&result)
+	return result
+}
+
+// panelWarnings adds the warnings of PanelWarnings to result
+// and reports if this clade mixes panels.
+func (c *Clade) panelWarnings(result *[]string) bool {
+	hasMixedSubclade := false
+	for i, _ := range c.Subclades {
+		if c.Subclades[i].panelWarnings(result) {
+			hasMixedSubclade = true
+		}
+	}
+	counts := c.PanelCounts()
+	smallest, largest, mixed := isMixed(counts)
+	if mixed && !hasMixedSubclade {
+		*result = append(*result, fmt.Sprintf("%s mixes panels from %s (%d samples) to %s (%d samples)",
+			c.SNPs[0], smallest, counts[smallest], largest, counts[largest]))
+	}
+	return mixed
+}
@@ -0,0 +1,67 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestTestedPanel checks the inferred panels of synthetic persons.
+func TestTestedPanel(t *testing.T) {
+	tests := []struct {
+		name  string
+		first int
+		last  int
+		nulls []int
+		panel string
+	}{
+		{"nothing", 0, 0, nil, ""},
+		{"less than Y12", 0, 5, nil, ""},
+		{"Y12", 0, 12, nil, "Y12"},
+		{"Y37", 0, 37, nil, "Y37"},
+		{"Y37 with nulls", 0, 37, []int{3, 20, 30}, "Y37"},
+		{"half of Y67", 0, 52, nil, "Y67"},
+		{"less than half of Y67", 0, 51, nil, "Y37"},
+		{"Y111", 0, 111, nil, "Y111"},
+		{"Y111 without Y12", 12, 111, nil, ""},
+		{"Y500", 0, 211, nil, "Y500"},
+		{"Y111 and a few more", 0, 150, nil, "Y111"},
+	}
+	for _, test := range tests {
+		person := &genetic.Person{ID: test.name}
+		if test.last > len(person.YstrMarkers) {
+			// The genetic package does not know enough markers.
+			continue
+		}
+		for i := test.first; i < test.last; i++ {
+			person.YstrMarkers[i] = float64(10 + i%7)
+		}
+		for _, i := range test.nulls {
+			person.YstrMarkers[i] = Null
+		}
+		if panel := TestedPanel(person); panel != test.panel {
+			t.Errorf("%s: panel = %q, want %q", test.name, panel, test.panel)
+		}
+	}
+}
+
+// TestPanelWarnings checks that only the deepest clades that mix
+// panels are reported.
+func TestPanelWarnings(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tB\r\n\t\t\tid:1\r\n\t\t\tid:2\r\n\t\tid:3\r\n\tC\r\n\t\tid:4\r\n\t\tid:5\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		panelPerson("1", 12), panelPerson("2", 111), panelPerson("3", 37),
+		panelPerson("4", 67), panelPerson("5", 111),
+	})
+	warnings := tree.PanelWarnings()
+	want := "B mixes panels from Y12 (1 samples) to Y111 (1 samples)"
+	if len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("warnings = %q, want %q", warnings, want)
+	}
+
+	report := tree.PanelsReport()
+	if !strings.Contains(report, "\tA, Y12: 1, Y37: 1, Y111: 1, mixed panels\r\n") {
+		t.Errorf("report:\n%s", report)
+	}
+}
@@ -0,0 +1,31 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestTraceChangesOnly checks that Trace shows all values and that
+// TraceChangesOnly shows only real changes. Swapped copies of a
+// multi-copy marker are no changes.
+func TestTraceChangesOnly(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS385a": 11, "DYS385b": 14}
+	tree := mustParse(t, "R\r\n\tid:1\r\n\tid:2\r\n")
+	tree.InsertPersons([]*genetic.Person{
+		newPerson(t, "1", withValue(withValue(base, "DYS385a", 14), "DYS385b", 11)),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+	})
+	tree.Person = newPerson(t, "R", base)
+	STRs := []string{"DYS393", "DYS385a", "DYS385b"}
+
+	all := tree.Trace(STRs)
+	if !strings.Contains(all, "id:1, DYS393: 13, DYS385a: 14, DYS385b: 11,") {
+		t.Errorf("trace:\n%s", all)
+	}
+	changes := tree.TraceChangesOnly(STRs)
+	if !strings.Contains(changes, "\tid:1,\r\n") || !strings.Contains(changes, "\tid:2, DYS393: 14,\r\n") {
+		t.Errorf("trace changes only:\n%s", changes)
+	}
+}
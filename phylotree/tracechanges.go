@@ -0,0 +1,146 @@
+package phylotree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// MutatingMarkers returns the indices of all markers whose values
+// differ between the modal haplotype of a clade and the modal
+// haplotype of it's parent or between a sample and the modal
+// haplotype of it's clade. Uncertain and missing values are not
+// counted as differences, Null values are. The values of multi-copy
+// markers are sorted before they are compared. The indices are
+// sorted.
+func (c *Clade) MutatingMarkers() []int {
+	changed := make(map[int]bool)
+	compare := func(a, b *genetic.Person) {
+		if a == nil || b == nil {
+			return
+		}
+		ystrA, ystrB := a.YstrMarkers, b.YstrMarkers
+		SortMultiCopy(&ystrA)
+		SortMultiCopy(&ystrB)
+		for i, _ := range ystrA {
+			if isAllele(ystrA[i]) && isAllele(ystrB[i]) && ystrA[i] != ystrB[i] {
+				changed[i] = true
+			}
+		}
+	}
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		for i, _ := range clade.Samples {
+			compare(clade.Samples[i].Person, clade.Person)
+		}
+		for i, _ := range clade.Subclades {
+			compare(clade.Subclades[i].Person, clade.Person)
+		}
+		return nil
+	})
+	result := make([]int, 0, len(changed))
+	for i, _ := range changed {
+		result = append(result, i)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// TraceChanges returns a tree like Trace that contains the values of
+// all markers returned by MutatingMarkers. Values that differ from
+// the values of the parent's modal haplotype are marked by a *.
+// Uncertain and missing values are shown as ?, Null values as null.
+func (c *Clade) TraceChanges() string {
+	indices := c.MutatingMarkers()
+	var buffer bytes.Buffer
+	c.tracePrint(&buffer, 0, func(e *Element, parent *genetic.Person) string {
+		return e.strChanges(indices, parent)
+	}, nil)
+	return buffer.String()
+}
+
+// strChanges returns the names and values of the Y-STR markers
+// specified by indices. Values that are different from the values
+// of parent are marked by a *. Uncertain values are shown as ?
+// and Null values as null. The values of multi-copy markers are
+// shown sorted.
+func (e *Element) strChanges(indices []int, parent *genetic.Person) string {
+	if e.Person == nil {
+		return ""
+	}
+	var buffer bytes.Buffer
+	for _, i := range indices {
+		name := genetic.YstrMarkerTable[i].InternalName
+		value := markerValue(&e.Person.YstrMarkers, i)
+		switch {
+		case !isAllele(value):
+			buffer.WriteString(fmt.Sprintf(" %s: ?,", name))
+		case parent != nil && isAllele(parent.YstrMarkers[i]) && markerValue(&parent.YstrMarkers, i) != value:
+			buffer.WriteString(fmt.Sprintf(" %s: %s*,", name, formatValue(value)))
+		default:
+			buffer.WriteString(fmt.Sprintf(" %s: %s,", name, formatValue(value)))
+		}
+	}
+	return buffer.String()
+}
+
+// TraceCSV returns the marker values of the Y-STR markers specified
+// by STRs as a table in CSV format. The names of the markers are
+// resolved like in Trace. Each row contains a clade or a sample in
+// the order of the tree together with it's depth and the path from
+// the root. Cells of elements without Y-STR data and of uncertain
+// or missing values are empty.
+func (c *Clade) TraceCSV(STRs []string) (string, error) {
+	return c.traceTable(c.traceIndices(STRs))
+}
+
+// TraceChangesCSV returns the values of all markers returned by
+// MutatingMarkers as a table like TraceCSV.
+func (c *Clade) TraceChangesCSV() (string, error) {
+	return c.traceTable(c.MutatingMarkers())
+}
+
+// traceTable returns the table for TraceCSV that contains the
+// markers specified by indices.
+func (c *Clade) traceTable(indices []int) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.UseCRLF = true
+	header := []string{"Node", "Type", "Depth", "Path"}
+	for _, i := range indices {
+		header = append(header, genetic.YstrMarkerTable[i].InternalName)
+	}
+	writer.Write(header)
+	row := func(name, kind string, path []string, person *genetic.Person) []string {
+		result := []string{name, kind, fmt.Sprintf("%d", len(path)-1), strings.Join(path, " > ")}
+		for _, i := range indices {
+			if person != nil && (person.YstrMarkers[i] > 0 || person.YstrMarkers[i] == Null) {
+				result = append(result, formatValue(person.YstrMarkers[i]))
+			} else {
+				result = append(result, "")
+			}
+		}
+		return result
+	}
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		names := make([]string, 0, len(path)+1)
+		for _, ancestor := range path {
+			names = append(names, ancestor.SNPs[0])
+		}
+		names = append(names, clade.SNPs[0])
+		writer.Write(row(clade.SNPs[0], "clade", names, clade.Person))
+		for i, _ := range clade.Samples {
+			sample := &clade.Samples[i]
+			writer.Write(row(sample.ID, "sample", append(names, "id:"+sample.ID), sample.Person))
+		}
+		return nil
+	})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
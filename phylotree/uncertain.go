@@ -0,0 +1,219 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// UncertainReason explains why a marker value of a modal
+// haplotype could not be determined reliably.
+type UncertainReason int
+
+const (
+	// NoData means that no downstream sample has a value
+	// for the marker.
+	NoData UncertainReason = iota
+	// AmbiguousTie means that the downstream values did not
+	// lead to a unique result.
+	AmbiguousTie
+	// ForcedAtRoot means that the value of the root had no
+	// unique nearest real world value and has been forced
+	// to the smallest nearest value.
+	ForcedAtRoot
+)
+
+// String returns a short description of the reason.
+func (r UncertainReason) String() string {
+	switch r {
+	case NoData:
+		return "no data downstream"
+	case AmbiguousTie:
+		return "ambiguous tie"
+	case ForcedAtRoot:
+		return "forced at root"
+	}
+	return "unknown"
+}
+
+// UncertainMarker is a marker of a modal haplotype that could
+// not be determined reliably.
+type UncertainMarker struct {
+	// Clade is the name of the clade.
+	Clade string
+	// Marker is the index of the marker.
+	Marker int
+	Reason UncertainReason
+}
+
+// UncertainMarkers returns all markers of the modal haplotypes that
+// are still Uncertain or missing after the calculation, and the
+// markers of the root that have been forced to a value. Markers
+// that have no value in any sample of this clade are ignored.
+// The result is in tree order.
+// The modal haplotypes must be calculated before.
+func (c *Clade) UncertainMarkers() []UncertainMarker {
+	var result []UncertainMarker
+	observed := c.Completeness().Support
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.Person == nil {
+			return nil
+		}
+		support := clade.Completeness().Support
+		for i, value := range clade.Person.YstrMarkers {
+			if value > 0 || value == Null || observed[i] == 0 {
+				continue
+			}
+			reason := AmbiguousTie
+			if support[i] == 0 {
+				reason = NoData
+			}
+			result = append(result, UncertainMarker{Clade: clade.SNPs[0], Marker: i, Reason: reason})
+		}
+		for _, i := range clade.forcedMarkers {
+			result = append(result, UncertainMarker{Clade: clade.SNPs[0], Marker: i, Reason: ForcedAtRoot})
+		}
+		return nil
+	})
+	return result
+}
+
+// UncertainReport returns the markers of UncertainMarkers grouped
+// by clade and reason, followed by a summary of the number of
+// clades per marker and reason.
+func (c *Clade) UncertainReport() string {
+	var buffer bytes.Buffer
+	markers := c.UncertainMarkers()
+	reasons := []UncertainReason{NoData, AmbiguousTie, ForcedAtRoot}
+
+	// List of clades.
+	for start := 0; start < len(markers); {
+		end := start
+		for end < len(markers) && markers[end].Clade == markers[start].Clade {
+			end++
+		}
+		buffer.WriteString(markers[start].Clade + "\r\n")
+		for _, reason := range reasons {
+			var names []string
+			for _, m := range markers[start:end] {
+				if m.Reason == reason {
+					names = append(names, genetic.YstrMarkerTable[m.Marker].InternalName)
+				}
+			}
+			if len(names) > 0 {
+				buffer.WriteString(fmt.Sprintf("\t%s: %s\r\n", reason, strings.Join(names, ", ")))
+			}
+		}
+		start = end
+	}
+
+	// Summary per marker.
+	counts := make(map[int][]int)
+	for _, m := range markers {
+		if counts[m.Marker] == nil {
+			counts[m.Marker] = make([]int, len(reasons))
+		}
+		counts[m.Marker][m.Reason]++
+	}
+	indices := make([]int, 0, len(counts))
+	for i, _ := range counts {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	if len(indices) > 0 {
+		buffer.WriteString("\r\nSummary:\r\n")
+	}
+	for _, i := range indices {
+		buffer.WriteString(fmt.Sprintf("%s: %s: %d, %s: %d, %s: %d\r\n",
+			genetic.YstrMarkerTable[i].InternalName,
+			NoData, counts[i][NoData],
+			AmbiguousTie, counts[i][AmbiguousTie],
+			ForcedAtRoot, counts[i][ForcedAtRoot]))
+	}
+	return buffer.String()
+}
+
+// uncertainCount is the number of uncertain values in the modal
+// haplotype of a clade.
+type uncertainCount struct {
+	clade string
+	count int
+}
+
+// CheckUncertainty counts the uncertain values of UncertainMarkers
+// in the modal haplotypes of all clades. These are missing values and
+// values of the root that have been forced. Only markers that have a
+// value in at least one sample are counted. An error is returned if
+// a clade has more than maxPerClade uncertain values or if more than
+// maxPercent percent of all values are uncertain. The error lists the
+// clades with the most uncertain values. Negative limits are not
+// checked. The modal haplotypes must be calculated before.
+func (c *Clade) CheckUncertainty(maxPerClade int, maxPercent float64) error {
+	if maxPerClade < 0 && maxPercent < 0 {
+		return nil
+	}
+	var counts []uncertainCount
+	uncertain := 0
+	for _, marker := range c.UncertainMarkers() {
+		if n := len(counts); n == 0 || counts[n-1].clade != marker.Clade {
+			counts = append(counts, uncertainCount{clade: marker.Clade})
+		}
+		counts[len(counts)-1].count++
+		uncertain++
+	}
+	// Total number of values that are compared with the limit.
+	observed := c.Completeness().Support
+	total := 0
+	c.Walk(func(path []*Clade, clade *Clade) error {
+		if clade.Person == nil {
+			return nil
+		}
+		for i, _ := range clade.Person.YstrMarkers {
+			if observed[i] > 0 {
+				total++
+			}
+		}
+		return nil
+	})
+	var problems []string
+	if n := countAbove(counts, maxPerClade); maxPerClade >= 0 && n > 0 {
+		problems = append(problems, fmt.Sprintf("%d clades have more than %d uncertain marker values", n, maxPerClade))
+	}
+	if maxPercent >= 0 && total > 0 {
+		percent := 100 * float64(uncertain) / float64(total)
+		if percent > maxPercent {
+			problems = append(problems, fmt.Sprintf("%.1f%% of all marker values are uncertain, limit is %g%%", percent, maxPercent))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].count > counts[j].count
+	})
+	if len(counts) > 5 {
+		counts = counts[:5]
+	}
+	worst := make([]string, 0, len(counts))
+	for _, count := range counts {
+		worst = append(worst, fmt.Sprintf("%s: %d", count.clade, count.count))
+	}
+	return errors.New(fmt.Sprintf("%s, worst clades: %s",
+		strings.Join(problems, "; "), strings.Join(worst, ", ")))
+}
+
+// countAbove returns the number of clades with more than
+// max uncertain values.
+func countAbove(counts []uncertainCount, max int) int {
+	result := 0
+	for _, count := range counts {
+		if count.count > max {
+			result++
+		}
+	}
+	return result
+}
@@ -0,0 +1,40 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCheckUncertainty checks that missing values and values that
+// have been forced at the root count as uncertain.
+func TestCheckUncertainty(t *testing.T) {
+	tree := mustParse(t, "R\r\n\tA\r\n\t\tid:1\r\n\tid:2\r\n")
+	a := tree.Subclade("A")
+	a.Samples[0].Person = newPerson(t, "1", map[string]float64{"DYS393": 13, "DYS390": 24})
+	tree.Samples[0].Person = newPerson(t, "2", map[string]float64{"DYS393": 14, "DYS390": 23})
+	tree.Person = newPerson(t, "R", map[string]float64{"DYS393": 13, "DYS390": 23})
+	tree.forcedMarkers = []int{mustIndex(t, "DYS390")}
+	a.Person = newPerson(t, "A", map[string]float64{"DYS390": Uncertain})
+
+	tests := []struct {
+		maxPerClade int
+		maxPercent  float64
+		problem     string
+	}{
+		{-1, -1, ""},
+		{2, -1, ""},
+		{1, -1, "1 clades have more than 1 uncertain marker values, worst clades: A: 2, R: 1"},
+		{0, -1, "2 clades have more than 0 uncertain marker values"},
+		{-1, 80, ""},
+		{-1, 70, "75.0% of all marker values are uncertain, limit is 70%"},
+	}
+	for _, test := range tests {
+		err := tree.CheckUncertainty(test.maxPerClade, test.maxPercent)
+		switch {
+		case test.problem == "" && err != nil:
+			t.Errorf("limits %d, %g: unexpected error %v", test.maxPerClade, test.maxPercent, err)
+		case test.problem != "" && (err == nil || !strings.Contains(err.Error(), test.problem)):
+			t.Errorf("limits %d, %g: error = %v, want %q", test.maxPerClade, test.maxPercent, err, test.problem)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package phylotree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tolerance is the maximum allowed difference between an
+// expected and a calculated age.
+type Tolerance struct {
+	// Absolute is the maximum difference in years.
+	Absolute float64
+	// Relative is the maximum difference as a fraction
+	// of the expected age.
+	Relative float64
+}
+
+// ParseTolerance creates a Tolerance from a textual representation.
+// The text is either an absolute value in years, for example "10",
+// or a relative value in percent, for example "1%".
+func ParseTolerance(text string) (Tolerance, error) {
+	text = strings.TrimSpace(text)
+	isRelative := strings.HasSuffix(text, "%")
+	value, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+	if err != nil || value < 0 {
+		return Tolerance{}, errors.New("invalid tolerance: " + text)
+	}
+	if isRelative {
+		return Tolerance{Relative: value / 100}, nil
+	}
+	return Tolerance{Absolute: value}, nil
+}
+
+// allows returns true if the difference between expected and
+// actual lies within the tolerance.
+func (t Tolerance) allows(expected, actual float64) bool {
+	diff := math.Abs(actual - expected)
+	if t.Relative > 0 {
+		return diff <= t.Relative*math.Abs(expected)
+	}
+	return diff <= t.Absolute
+}
+
+// Verify compares this tree with an expected tree that has been
+// read from a previously calculated results tree. The topology
+// must be identical and all ages must lie within the tolerance.
+// Subclades are matched by their first SNP name and samples by
+// their IDs, so the order of both may differ.
+// The result lists all differences. It is empty if both trees
+// match.
+func (c *Clade) Verify(expected *Clade, tolerance Tolerance) string {
+	var buffer bytes.Buffer
+	if !strings.EqualFold(c.SNPs[0], expected.SNPs[0]) {
+		buffer.WriteString(fmt.Sprintf("%s: expected clade %s\r\n", c.SNPs[0], expected.SNPs[0]))
+		return buffer.String()
+	}
+	c.verify(expected, tolerance, &buffer)
+	return buffer.String()
+}
+
+// verify writes the differences between this clade and expected
+// into buffer. Both clades must have the same name.
+func (c *Clade) verify(expected *Clade, tolerance Tolerance, buffer *bytes.Buffer) {
+	name := c.SNPs[0]
+	// Compare samples.
+	expectedIDs := make(map[string]bool)
+	for i, _ := range expected.Samples {
+		expectedIDs[expected.Samples[i].ID] = true
+	}
+	ids := make(map[string]bool)
+	for i, _ := range c.Samples {
+		id := c.Samples[i].ID
+		ids[id] = true
+		if !expectedIDs[id] {
+			buffer.WriteString(fmt.Sprintf("%s: sample id:%s is not expected\r\n", name, id))
+		}
+	}
+	for i, _ := range expected.Samples {
+		if id := expected.Samples[i].ID; !ids[id] {
+			buffer.WriteString(fmt.Sprintf("%s: expected sample id:%s\r\n", name, id))
+		}
+	}
+	// Compare ages.
+	ages := expected.InputAges
+	switch {
+	case ages == nil && c.STRCountDownstream >= 0:
+		buffer.WriteString(fmt.Sprintf("%s: expected no ages, TMRCA: %.0f\r\n", name, c.TMRCA_STR))
+	case ages != nil && c.STRCountDownstream < 0:
+		buffer.WriteString(fmt.Sprintf("%s: no ages, expected TMRCA: %.0f\r\n", name, ages.TMRCA))
+	case ages != nil:
+		values := []struct {
+			label    string
+			actual   float64
+			expected float64
+		}{
+			{"formed", c.AgeSTR, ages.AgeSTR},
+			{"TMRCA", c.TMRCA_STR, ages.TMRCA},
+			{"CI lower", c.TMRCAlower, ages.TMRCAlower},
+			{"CI upper", c.TMRCAupper, ages.TMRCAupper},
+		}
+		for _, v := range values {
+			// Ages are written without decimals.
+			actual := math.Floor(v.actual + 0.5)
+			if !tolerance.allows(v.expected, actual) {
+				buffer.WriteString(fmt.Sprintf("%s: %s: %.0f, expected: %.0f (%+.0f)\r\n",
+					name, v.label, actual, v.expected, actual-v.expected))
+			}
+		}
+	}
+	// Compare subclades.
+	expectedClades := make(map[string]*Clade)
+	for i, _ := range expected.Subclades {
+		expectedClades[strings.ToLower(expected.Subclades[i].SNPs[0])] = expected.Subclades[i]
+	}
+	found := make(map[*Clade]bool)
+	for i, _ := range c.Subclades {
+		subclade := c.Subclades[i]
+		other, exists := expectedClades[strings.ToLower(subclade.SNPs[0])]
+		if !exists {
+			buffer.WriteString(fmt.Sprintf("%s: clade %s is not expected\r\n", name, subclade.SNPs[0]))
+			continue
+		}
+		found[other] = true
+		subclade.verify(other, tolerance, buffer)
+	}
+	for i, _ := range expected.Subclades {
+		if !found[expected.Subclades[i]] {
+			buffer.WriteString(fmt.Sprintf("%s: expected clade %s\r\n", name, expected.Subclades[i].SNPs[0]))
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package phylotree
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseTolerance checks absolute and relative tolerances.
+func TestParseTolerance(t *testing.T) {
+	tests := []struct {
+		text      string
+		tolerance Tolerance
+		isValid   bool
+	}{
+		{"10", Tolerance{Absolute: 10}, true},
+		{" 1% ", Tolerance{Relative: 0.01}, true},
+		{"0", Tolerance{}, true},
+		{"-1", Tolerance{}, false},
+		{"abc%", Tolerance{}, false},
+	}
+	for _, test := range tests {
+		tolerance, err := ParseTolerance(test.text)
+		if (err == nil) != test.isValid || tolerance != test.tolerance {
+			t.Errorf("ParseTolerance(%q) = %v, %v", test.text, tolerance, err)
+		}
+	}
+}
+
+// TestToleranceAllows checks the limits of absolute and relative
+// tolerances.
+func TestToleranceAllows(t *testing.T) {
+	tests := []struct {
+		tolerance Tolerance
+		expected  float64
+		actual    float64
+		allows    bool
+	}{
+		{Tolerance{Absolute: 10}, 1000, 1010, true},
+		{Tolerance{Absolute: 10}, 1000, 989, false},
+		{Tolerance{Relative: 0.01}, 1000, 990, true},
+		{Tolerance{Relative: 0.01}, 1000, 1011, false},
+		{Tolerance{Relative: 0.01}, 5000, 5040, true},
+		{Tolerance{}, 1000, 1000, true},
+	}
+	for _, test := range tests {
+		if allows := test.tolerance.allows(test.expected, test.actual); allows != test.allows {
+			t.Errorf("%v allows %g for %g: %t, want %t", test.tolerance, test.actual, test.expected, allows, test.allows)
+		}
+	}
+}
+
+// TestVerify checks that a results tree verifies against itself
+// even if it is sorted differently and that topology mismatches
+// and age drifts are reported.
+func TestVerify(t *testing.T) {
+	text := "R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 30\r\n\tB\r\n\t\tid:3, STR-Count: 20\r\n\t\tid:4, STR-Count: 40\r\n"
+	calculated := func(calibration float64) *Clade {
+		tree := mustParse(t, text)
+		tree.CalculateAge(33, calibration, 0)
+		return tree
+	}
+	expected := mustParse(t, calculated(1).String())
+
+	sorted, err := calculated(1).Sorted("tmrca", "strcount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sorted.Subclades[0].SNPs[0] != "B" {
+		t.Fatalf("tree not sorted:\n%s", sorted)
+	}
+	if diff := sorted.Verify(expected, Tolerance{}); diff != "" {
+		t.Errorf("sorted tree differs:\n%s", diff)
+	}
+
+	// Age drift.
+	drifted := calculated(1.1)
+	if diff := drifted.Verify(expected, Tolerance{Relative: 0.2}); diff != "" {
+		t.Errorf("drift within tolerance:\n%s", diff)
+	}
+	diff := drifted.Verify(expected, Tolerance{Absolute: 10})
+	if !strings.Contains(diff, "A: TMRCA: ") || !strings.Contains(diff, "R: TMRCA: ") {
+		t.Errorf("drift:\n%s", diff)
+	}
+
+	// Topology.
+	other := mustParse(t, "R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:5, STR-Count: 30\r\n\tC\r\n\t\tid:3, STR-Count: 20\r\n\t\tid:4, STR-Count: 40\r\n")
+	other.CalculateAge(33, 1, 0)
+	diff = other.Verify(expected, Tolerance{Relative: 1})
+	for _, line := range []string{
+		"A: sample id:5 is not expected\r\n",
+		"A: expected sample id:2\r\n",
+		"R: clade C is not expected\r\n",
+		"R: expected clade B\r\n",
+	} {
+		if !strings.Contains(diff, line) {
+			t.Errorf("topology differences do not contain %q:\n%s", line, diff)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package phylotree
+
+import "errors"
+
+// errFound stops a traversal if the searched element is found.
+var errFound = errors.New("found")
+
+// WalkFunc is the type of the function that is called by Walk
+// for each clade. path contains the ancestors of the clade,
+// starting with the clade on which Walk was called. It is empty
+// for the first clade. path is only valid during the call and
+// must be copied if it is kept.
+type WalkFunc func(path []*Clade, c *Clade) error
+
+// WalkSamplesFunc is the type of the function that is called by
+// WalkSamples for each sample. path contains the clades from the
+// clade on which WalkSamples was called down to the clade that
+// contains the sample. path is only valid during the call and
+// must be copied if it is kept.
+type WalkSamplesFunc func(path []*Clade, s *Sample) error
+
+// Walk traverses this clade and all subclades depth first and
+// calls fn for each clade. Each clade is visited before it's
+// subclades. Subclades are visited in the order of the tree.
+// If fn returns an error, the traversal stops and the error
+// is returned.
+func (c *Clade) Walk(fn WalkFunc) error {
+	return c.walk(make([]*Clade, 0, 16), fn)
+}
+
+// walk calls fn for this clade and all subclades.
+// path contains the ancestors of this clade.
+func (c *Clade) walk(path []*Clade, fn WalkFunc) error {
+	// Limit the capacity, so that fn can not overwrite
+	// the path by appending to it.
+	err := fn(path[:len(path):len(path)], c)
+	if err != nil {
+		return err
+	}
+	path = append(path, c)
+	for i, _ := range c.Subclades {
+		err = c.Subclades[i].walk(path, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkSamples traverses this clade and all subclades depth first
+// and calls fn for each sample. The samples of a clade are visited
+// before the samples of it's subclades. Samples and subclades are
+// visited in the order of the tree. If fn returns an error, the
+// traversal stops and the error is returned.
+func (c *Clade) WalkSamples(fn WalkSamplesFunc) error {
+	return c.Walk(func(path []*Clade, clade *Clade) error {
+		if len(clade.Samples) == 0 {
+			return nil
+		}
+		path = append(path, clade)
+		for i, _ := range clade.Samples {
+			err := fn(path[:len(path):len(path)], &clade.Samples[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
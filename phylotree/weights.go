@@ -0,0 +1,71 @@
+package phylotree
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadWeights reads sample weights from a CSV file and sets the
+// Weight of the samples of this tree. A weight of 0 means that a
+// sample is shown in the tree but ignored for all calculations.
+// Weights from the file replace weights from the tree.
+// IDs are compared after normalization, see DefaultIDMatch.
+// Format: id,weight
+func (c *Clade) ReadWeights(filename string) error {
+	return c.ReadWeightsMatching(filename, DefaultIDMatch)
+}
+
+// ReadWeightsMatching works like ReadWeights, but the IDs are
+// compared as specified by match. If no sample has exactly the ID
+// of a line, the weight is set for all samples that match it.
+func (c *Clade) ReadWeightsMatching(filename string, match IDMatch) error {
+	infile, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+
+	reader := csv.NewReader(infile)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err
+	}
+	// Create hash maps of the samples' IDs.
+	exact := make(map[string][]*Sample)
+	normalized := make(map[string][]*Sample)
+	c.WalkSamples(func(path []*Clade, s *Sample) error {
+		exact[s.ID] = append(exact[s.ID], s)
+		key := match.normalize(s.ID)
+		normalized[key] = append(normalized[key], s)
+		return nil
+	})
+	for i, record := range records {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || weight < 0 {
+			if i == 0 {
+				// Skip header.
+				continue
+			}
+			return errors.New(fmt.Sprintf("line: %d, invalid weight: %s", i+1, record[1]))
+		}
+		id := strings.TrimSpace(record[0])
+		samples, exists := exact[id]
+		if !exists {
+			samples, exists = normalized[match.normalize(id)]
+		}
+		if !exists {
+			return errors.New(fmt.Sprintf("line: %d, could not find sample %s", i+1, id))
+		}
+		for _, s := range samples {
+			s.Weight = weight
+		}
+	}
+	return nil
+}
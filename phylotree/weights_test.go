@@ -0,0 +1,74 @@
+package phylotree
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// writeWeights writes text into a weights file and returns it's name.
+func writeWeights(t *testing.T, text string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "weights.csv")
+	if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// TestWeightsOne checks that weights of 1 reproduce the ages of a
+// tree without weights.
+func TestWeightsOne(t *testing.T) {
+	base := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14, "DYS391": 10}
+	persons := []*genetic.Person{
+		newPerson(t, "1", base),
+		newPerson(t, "2", withValue(base, "DYS393", 14)),
+		newPerson(t, "3", withValue(base, "DYS19", 15)),
+		newPerson(t, "4", withValue(base, "DYS391", 11)),
+	}
+	text := "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n"
+	reference := mustParse(t, text)
+	weighted := mustParse(t, text)
+	err := weighted.ReadWeights(writeWeights(t, "id,weight\r\n1,1\r\n2,1\r\n3,1\r\n4,1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calculate(reference, persons)
+	calculate(weighted, persons)
+
+	for _, name := range []string{"R", "A", "B"} {
+		want, got := reference.Subclade(name), weighted.Subclade(name)
+		if got.TMRCA_STR != want.TMRCA_STR || got.STRCountDownstream != want.STRCountDownstream ||
+			got.TMRCAlower != want.TMRCAlower || got.TMRCAupper != want.TMRCAupper {
+			t.Errorf("%s: TMRCA = %g (%g-%g), want %g (%g-%g)", name,
+				got.TMRCA_STR, got.TMRCAlower, got.TMRCAupper, want.TMRCA_STR, want.TMRCAlower, want.TMRCAupper)
+		}
+	}
+}
+
+// TestWeightsMatching checks that the IDs of the weights file are
+// compared with the sample IDs as specified by the ID match.
+func TestWeightsMatching(t *testing.T) {
+	text := "R\r\n\tid:YF007\r\n\tid:kit 12\r\n\tid:12\r\n"
+	filename := writeWeights(t, "yf007, 0.5\r\n12, 0\r\n")
+
+	tree := mustParse(t, text)
+	if err := tree.ReadWeightsMatching(filename, IDMatch{Normalized: true, Prefixes: []string{"kit"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		id     string
+		weight float64
+	}{{"YF007", 0.5}, {"kit 12", 1}, {"12", 0}} {
+		if s, _ := tree.FindSample(test.id); s.Weight != test.weight {
+			t.Errorf("weight of %s = %g, want %g", test.id, s.Weight, test.weight)
+		}
+	}
+
+	tree = mustParse(t, text)
+	if err := tree.ReadWeightsMatching(filename, ExactIDMatch); err == nil {
+		t.Errorf("exact match found sample yf007")
+	}
+}
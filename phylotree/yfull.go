@@ -0,0 +1,123 @@
+package phylotree
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// yfullNode is a node of the YFull haplotree in JSON format.
+type yfullNode struct {
+	ID       string        `json:"id"`
+	SNPs     yfullSNPs     `json:"snps"`
+	Children []yfullNode   `json:"children"`
+	Samples  []yfullSample `json:"samples"`
+}
+
+// yfullSample is a sample of the YFull haplotree in JSON format.
+type yfullSample struct {
+	ID string `json:"id"`
+}
+
+// yfullSNPs is a list of SNPs. YFull stores them either as
+// a comma separated string or as an array.
+type yfullSNPs []string
+
+func (s *yfullSNPs) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*s = nil
+	for _, snp := range strings.Split(text, ",") {
+		if snp = strings.TrimSpace(snp); snp != "" {
+			*s = append(*s, snp)
+		}
+	}
+	return nil
+}
+
+// NewFromYFullJSON reads a tree from a file in the JSON format
+// of the YFull haplotree. The file may contain the whole tree or
+// a subtree. Nodes without SNPs are named by their node IDs.
+// The node IDs of all other nodes are added as aliases.
+func NewFromYFullJSON(filename string) (*Clade, error) {
+	infile, err := OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+	content, err := ioutil.ReadAll(infile)
+	if err != nil {
+		return nil, err
+	}
+	return parseYFullJSON([]byte(decodeText(content)))
+}
+
+// isYFullJSON returns true if text looks like a tree in JSON format.
+func isYFullJSON(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[")
+}
+
+// parseYFullJSON creates a tree from a YFull haplotree in JSON format.
+// The top level element is either a single node or an array that
+// contains exactly one node.
+func parseYFullJSON(content []byte) (*Clade, error) {
+	var root yfullNode
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "[") {
+		var nodes []yfullNode
+		if err := json.Unmarshal(content, &nodes); err != nil {
+			return nil, err
+		}
+		if len(nodes) != 1 {
+			return nil, errors.New("YFull tree must contain exactly one root node")
+		}
+		root = nodes[0]
+	} else if err := json.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	return root.clade()
+}
+
+// clade converts this node and all of it's children into a Clade.
+func (n *yfullNode) clade() (*Clade, error) {
+	snps := n.SNPs
+	if len(snps) == 0 {
+		if n.ID == "" {
+			return nil, errors.New("YFull node without ID and SNPs")
+		}
+		snps = yfullSNPs{n.ID}
+	}
+	result, err := newClade(snps[0], DefaultNegativePrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, snp := range snps[1:] {
+		result.AddSNP(snp)
+	}
+	// The node ID, for example R-Z2103, is an alias, so that the
+	// clade can be found by it's YFull name.
+	if n.ID != "" && !result.Contains(n.ID) {
+		result.AddSNP(n.ID)
+	}
+	for _, s := range n.Samples {
+		sample := newSample()
+		sample.ID = s.ID
+		result.AddSample(sample)
+	}
+	for i, _ := range n.Children {
+		child, err := n.Children[i].clade()
+		if err != nil {
+			return nil, err
+		}
+		result.AttachSubclade(child)
+	}
+	return &result, nil
+}
@@ -0,0 +1,58 @@
+package phylotree
+
+import (
+	"testing"
+)
+
+// TestParseYFullJSON reads YFull trees with SNPs given as array
+// and as comma separated string. All clades must be found by
+// their SNPs and by their node IDs.
+func TestParseYFullJSON(t *testing.T) {
+	tests := []struct {
+		name, json string
+	}{
+		{"array", `{"id": "R-L23", "snps": ["L23", "PF6534"], "children": [
+			{"id": "R-Z2103", "snps": ["Z2103", "Z2105"], "samples": [{"id": "YF001"}]},
+			{"id": "R-Z2106", "children": [{"id": "R-L584", "snps": ["L584"]}]}]}`},
+		{"string", `[{"id": "R-L23", "snps": "L23, PF6534", "children": [
+			{"id": "R-Z2103", "snps": "Z2103,Z2105", "samples": [{"id": "YF001"}]},
+			{"id": "R-Z2106", "snps": "", "children": [{"id": "R-L584", "snps": "L584"}]}]}]`},
+	}
+	for _, test := range tests {
+		tree, err := parseYFullJSON([]byte(test.json))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		for _, names := range [][]string{
+			{"L23", "PF6534", "R-L23"},
+			{"Z2103", "Z2105", "R-Z2103"},
+			{"R-Z2106"},
+			{"L584", "R-L584"},
+		} {
+			first := tree.Subclade(names[0])
+			if first == nil {
+				t.Errorf("%s: clade %s not found", test.name, names[0])
+				continue
+			}
+			if first.SNPs[0] != names[0] {
+				t.Errorf("%s: clade is named %s, want %s", test.name, first.SNPs[0], names[0])
+			}
+			for _, name := range names[1:] {
+				if clade := tree.Subclade(name); clade != first {
+					t.Errorf("%s: %s does not find clade %s", test.name, name, names[0])
+				}
+				if clade, err := tree.FindSubclade(name); err != nil || clade != first {
+					t.Errorf("%s: FindSubclade(%s) does not find clade %s", test.name, name, names[0])
+				}
+			}
+		}
+		if len(tree.Subclade("R-Z2106").SNPs) != 1 {
+			t.Errorf("%s: node ID added twice: %v", test.name, tree.Subclade("R-Z2106").SNPs)
+		}
+		z2103 := tree.Subclade("R-Z2103")
+		if z2103 == nil || len(z2103.Samples) != 1 || z2103.Samples[0].ID != "YF001" {
+			t.Errorf("%s: sample YF001 not found in R-Z2103", test.name)
+		}
+	}
+}
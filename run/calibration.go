@@ -0,0 +1,181 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yogischogi/phyloage/phylotree"
+)
+
+// Anchor is a clade with a known TMRCA that is used to calibrate
+// the ages.
+type Anchor struct {
+	Clade string
+	Age   float64
+	// Weight is the weight of the anchor for the fit.
+	Weight float64
+}
+
+// ParseCalibration parses a calibration clade and it's age
+// in the format SNP:AGE. The weight of the anchor is 1.
+func ParseCalibration(text string) (Anchor, error) {
+	pos := strings.LastIndex(text, ":")
+	if pos <= 0 || pos == len(text)-1 {
+		return Anchor{}, errors.New(fmt.Sprintf("invalid calibration %q, format must be SNP:AGE", text))
+	}
+	age, err := strconv.ParseFloat(strings.TrimSpace(text[pos+1:]), 64)
+	if err != nil || age <= 0 {
+		return Anchor{}, errors.New(fmt.Sprintf("invalid age in calibration %q", text))
+	}
+	return Anchor{Clade: strings.TrimSpace(text[:pos]), Age: age, Weight: 1}, nil
+}
+
+// ReadAnchors reads calibration anchors from a file. Each line
+// contains the SNP name of a clade, the age and optionally a weight,
+// separated by commas or white space. The default weight is 1.
+// Comments start with //.
+func ReadAnchors(filename string) ([]Anchor, error) {
+	infile, err := phylotree.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	var result []Anchor
+	isSeparator := func(c rune) bool {
+		return unicode.IsSpace(c) || c == ',' || c == ';'
+	}
+	scanner := bufio.NewScanner(infile)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.FieldsFunc(line, isSeparator)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, errors.New(fmt.Sprintf("line %d: format must be SNP, age, weight", lineNo))
+		}
+		anchor := Anchor{Clade: fields[0], Weight: 1}
+		anchor.Age, err = strconv.ParseFloat(fields[1], 64)
+		if err != nil || anchor.Age <= 0 {
+			return nil, errors.New(fmt.Sprintf("line %d: invalid age %s", lineNo, fields[1]))
+		}
+		if len(fields) == 3 {
+			anchor.Weight, err = strconv.ParseFloat(fields[2], 64)
+			if err != nil || anchor.Weight <= 0 {
+				return nil, errors.New(fmt.Sprintf("line %d: invalid weight %s", lineNo, fields[2]))
+			}
+		}
+		result = append(result, anchor)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no anchors found in " + filename)
+	}
+	return result, nil
+}
+
+// calibrate fits the calibration factor and, if FitOffset is set,
+// the offset to the ages of the anchors by weighted least squares and
+// stores them in r. The ages minus the offset and minus the age of
+// ancient samples are proportional to the calibration factor, so the
+// ages are calculated once with a factor of 1 and no offset. The
+// result is a report of the residuals.
+func (r *Result) calibrate(t *phylotree.Clade) (string, error) {
+	opts := r.options
+	clades := make([]*phylotree.Clade, len(opts.Anchors))
+	for i, anchor := range opts.Anchors {
+		clade, err := t.FindSubclade(anchor.Clade)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("calibration anchor, %v", err))
+		}
+		clades[i] = clade
+	}
+	r.Calibration = 1
+	r.Offset = 0
+	r.calculateAges(t)
+	// years are the scaled parts of the TMRCAs and ages are the
+	// ages of the anchors without the age of ancient samples.
+	years := make([]float64, len(clades))
+	ages := make([]float64, len(clades))
+	for i, clade := range clades {
+		years[i] = clade.TMRCA_STR - clade.Sampled
+		if clade.STRCountDownstream <= 0 || years[i] <= 0 {
+			return "", errors.New(fmt.Sprintf("can not calibrate with %s, the clade has no STR based TMRCA", opts.Anchors[i].Clade))
+		}
+		ages[i] = opts.Anchors[i].Age - clade.Sampled
+	}
+
+	// Weighted least squares for age = calibration * years + offset.
+	var sumW, sumWX, sumWY float64
+	for i, anchor := range opts.Anchors {
+		sumW += anchor.Weight
+		sumWX += anchor.Weight * years[i]
+		sumWY += anchor.Weight * ages[i]
+	}
+	if opts.FitOffset {
+		if len(opts.Anchors) < 2 {
+			return "", errors.New("at least two anchors are needed to fit the offset")
+		}
+		meanX, meanY := sumWX/sumW, sumWY/sumW
+		var sxx, sxy float64
+		for i, anchor := range opts.Anchors {
+			sxx += anchor.Weight * (years[i] - meanX) * (years[i] - meanX)
+			sxy += anchor.Weight * (years[i] - meanX) * (ages[i] - meanY)
+		}
+		if sxx == 0 {
+			return "", errors.New("can not fit the offset, all anchors have the same STR based TMRCA")
+		}
+		r.Calibration = sxy / sxx
+		r.Offset = meanY - r.Calibration*meanX
+	} else {
+		var sxx, sxy float64
+		for i, anchor := range opts.Anchors {
+			sxx += anchor.Weight * years[i] * years[i]
+			sxy += anchor.Weight * years[i] * (ages[i] - opts.Offset)
+		}
+		r.Calibration = sxy / sxx
+		r.Offset = opts.Offset
+	}
+	if r.Calibration <= 0 {
+		return "", errors.New(fmt.Sprintf("calibration failed, the fitted factor %g is not positive", r.Calibration))
+	}
+
+	// Report residuals.
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("Calibration factor: %g, offset: %g\r\n", r.Calibration, r.Offset))
+	var sumSquares float64
+	for i, anchor := range opts.Anchors {
+		fitted := r.Calibration*years[i] + r.Offset + clades[i].Sampled
+		residual := anchor.Age - fitted
+		sumSquares += anchor.Weight * residual * residual
+		buffer.WriteString(fmt.Sprintf("\t%s: age: %g, TMRCA: %.0f, residual: %.0f, weight: %g\r\n",
+			anchor.Clade, anchor.Age, fitted, residual, anchor.Weight))
+	}
+	if len(opts.Anchors) > 1 {
+		buffer.WriteString(fmt.Sprintf("\tweighted RMS residual: %.0f\r\n", math.Sqrt(sumSquares/sumW)))
+	}
+	return buffer.String(), nil
+}
+
+// calibrationHeader returns the fitted calibration parameters
+// as a comment for the header of the results.
+func (r *Result) calibrationHeader() string {
+	anchors := make([]string, 0, len(r.options.Anchors))
+	for _, anchor := range r.options.Anchors {
+		anchors = append(anchors, fmt.Sprintf("%s (%g)", anchor.Clade, anchor.Age))
+	}
+	return fmt.Sprintf("// Calibration factor: %g, offset: %g, fitted to anchors: %s\r\n",
+		r.Calibration, r.Offset, strings.Join(anchors, ", "))
+}
@@ -0,0 +1,141 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// minIdenticalMarkers is the number of markers above which
+// identical haplotypes of different kits are suspicious.
+const minIdenticalMarkers = 37
+
+// severity is the severity of a data quality problem.
+type severity int
+
+const (
+	warning severity = iota
+	severe
+)
+
+func (s severity) String() string {
+	if s == severe {
+		return "SEVERE"
+	}
+	return "WARNING"
+}
+
+// finding is a data quality problem of a single kit.
+type finding struct {
+	id       string
+	severity severity
+	message  string
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.id, f.severity, f.message)
+}
+
+// checkDataQuality checks the Y-STR values of persons for
+// data problems and returns a list of findings.
+func checkDataQuality(persons []*genetic.Person) []finding {
+	var findings []finding
+	add := func(id string, sev severity, format string, a ...interface{}) {
+		findings = append(findings, finding{id: id, severity: sev, message: fmt.Sprintf(format, a...)})
+	}
+
+	// Determine markers that have non-integer values
+	// for more than one person.
+	fractions := make([]int, len(genetic.YstrMarkers{}))
+	for _, person := range persons {
+		for i, value := range person.YstrMarkers {
+			if value != math.Trunc(value) {
+				fractions[i]++
+			}
+		}
+	}
+
+	byID := make(map[string]*genetic.Person)
+	byHaplotype := make(map[genetic.YstrMarkers]string)
+	for _, person := range persons {
+		id := person.ID
+		nValues := 0
+		for i, value := range person.YstrMarkers {
+			if value == 0 {
+				continue
+			}
+			nValues++
+			name := genetic.YstrMarkerTable[i].InternalName
+			switch {
+			case value == phylotree.Null:
+				// Null allele.
+			case value < 0:
+				add(id, severe, "negative value for %s: %g", name, value)
+			case !isMicroallele(value):
+				add(id, severe, "invalid value for %s: %g", name, value)
+			case value != math.Trunc(value) && fractions[i] == 1:
+				add(id, warning, "non-integer value for %s: %g", name, value)
+			}
+		}
+		if nValues == 0 {
+			add(id, severe, "empty haplotype")
+			continue
+		}
+
+		// Identical haplotypes.
+		if nValues >= minIdenticalMarkers {
+			if other, exists := byHaplotype[person.YstrMarkers]; exists && other != id {
+				add(id, warning, "haplotype identical to %s for %d markers", other, nValues)
+			} else {
+				byHaplotype[person.YstrMarkers] = id
+			}
+		}
+
+		// Duplicate records.
+		if other, exists := byID[id]; exists {
+			for i, value := range person.YstrMarkers {
+				name := genetic.YstrMarkerTable[i].InternalName
+				otherValue := other.YstrMarkers[i]
+				switch {
+				case value == otherValue:
+					// Identical values are fine.
+				case value == 0 || otherValue == 0:
+					add(id, warning, "duplicate record, %s is missing in one record", name)
+				default:
+					add(id, severe, "duplicate record, different values for %s: %g, %g", name, otherValue, value)
+				}
+			}
+		} else {
+			byID[id] = person
+		}
+	}
+	return findings
+}
+
+// isMicroallele returns true if value is a whole number or a
+// microallele like 17.1, 17.2 or 17.3.
+func isMicroallele(value float64) bool {
+	fraction := value - math.Trunc(value)
+	decimal := math.Round(fraction * 10)
+	return math.Abs(fraction*10-decimal) < 1e-6 && decimal <= 3
+}
+
+// DataQualityReport checks the Y-STR values of persons for data
+// problems and returns a text with one line per problem.
+func DataQualityReport(persons []*genetic.Person) string {
+	return dataQualityReport(checkDataQuality(persons))
+}
+
+// dataQualityReport returns findings as a text with one line
+// per finding.
+func dataQualityReport(findings []finding) string {
+	var buffer bytes.Buffer
+	for _, f := range findings {
+		buffer.WriteString(f.String())
+		buffer.WriteString("\r\n")
+	}
+	return buffer.String()
+}
@@ -0,0 +1,117 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// newPerson creates a person with the marker values in values.
+// The keys are marker names.
+func newPerson(t *testing.T, id string, values map[string]float64) *genetic.Person {
+	t.Helper()
+	person := &genetic.Person{ID: id, Name: id, Label: id}
+	for name, value := range values {
+		i := phylotree.MarkerIndex(name)
+		if i < 0 {
+			t.Fatalf("unknown marker %s", name)
+		}
+		person.YstrMarkers[i] = value
+	}
+	return person
+}
+
+// fullPerson creates a person with values for the first n markers.
+func fullPerson(id string, n int) *genetic.Person {
+	person := &genetic.Person{ID: id, Name: id, Label: id}
+	for i := 0; i < n; i++ {
+		person.YstrMarkers[i] = float64(10 + i%5)
+	}
+	return person
+}
+
+func TestCheckDataQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		persons []*genetic.Person
+		want    []string
+	}{
+		{
+			name:    "clean",
+			persons: []*genetic.Person{newPerson(t, "1", map[string]float64{"DYS393": 13, "DYS390": 24})},
+			want:    nil,
+		},
+		{
+			name:    "empty haplotype",
+			persons: []*genetic.Person{newPerson(t, "1", nil)},
+			want:    []string{"1: SEVERE: empty haplotype"},
+		},
+		{
+			name:    "negative value",
+			persons: []*genetic.Person{newPerson(t, "1", map[string]float64{"DYS393": -13})},
+			want:    []string{"1: SEVERE: negative value for DYS393: -13"},
+		},
+		{
+			name:    "null allele",
+			persons: []*genetic.Person{newPerson(t, "1", map[string]float64{"DYS393": 13, "DYS425": phylotree.Null})},
+			want:    nil,
+		},
+		{
+			name:    "invalid fraction",
+			persons: []*genetic.Person{newPerson(t, "1", map[string]float64{"DYS393": 13.5})},
+			want:    []string{"1: SEVERE: invalid value for DYS393: 13.5"},
+		},
+		{
+			name:    "single non-integer value",
+			persons: []*genetic.Person{newPerson(t, "1", map[string]float64{"DYS393": 13.2})},
+			want:    []string{"1: WARNING: non-integer value for DYS393: 13.2"},
+		},
+		{
+			name: "common microallele",
+			persons: []*genetic.Person{
+				newPerson(t, "1", map[string]float64{"DYS458": 17.2}),
+				newPerson(t, "2", map[string]float64{"DYS458": 17.2}),
+			},
+			want: nil,
+		},
+		{
+			name:    "identical haplotypes",
+			persons: []*genetic.Person{fullPerson("1", 37), fullPerson("2", 37)},
+			want:    []string{"2: WARNING: haplotype identical to 1 for 37 markers"},
+		},
+		{
+			name:    "few identical markers",
+			persons: []*genetic.Person{fullPerson("1", 12), fullPerson("2", 12)},
+			want:    nil,
+		},
+		{
+			name: "duplicate record with missing marker",
+			persons: []*genetic.Person{
+				newPerson(t, "1", map[string]float64{"DYS393": 13, "DYS390": 24}),
+				newPerson(t, "1", map[string]float64{"DYS393": 13}),
+			},
+			want: []string{"1: WARNING: duplicate record, DYS390 is missing in one record"},
+		},
+		{
+			name: "duplicate record with different values",
+			persons: []*genetic.Person{
+				newPerson(t, "1", map[string]float64{"DYS393": 13}),
+				newPerson(t, "1", map[string]float64{"DYS393": 14}),
+			},
+			want: []string{"1: SEVERE: duplicate record, different values for DYS393: 13, 14"},
+		},
+	}
+	for _, test := range tests {
+		findings := checkDataQuality(test.persons)
+		if len(findings) != len(test.want) {
+			t.Errorf("%s: findings = %v, want %v", test.name, findings, test.want)
+			continue
+		}
+		for i, f := range findings {
+			if f.String() != test.want[i] {
+				t.Errorf("%s: finding = %q, want %q", test.name, f.String(), test.want[i])
+			}
+		}
+	}
+}
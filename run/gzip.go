@@ -0,0 +1,116 @@
+package run
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yogischogi/phyloage/phylotree"
+)
+
+// gzSuffix is the filename suffix of files that are compressed by gzip.
+const gzSuffix = ".gz"
+
+// uncompressed returns the name of a file that contains the
+// uncompressed content of filename. If the file is not compressed
+// by gzip, filename is returned. Otherwise the content is decompressed
+// into a temporary file. cleanup removes the temporary file.
+func uncompressed(filename string) (name string, cleanup func(), err error) {
+	isGzip, err := phylotree.IsGzipFile(filename)
+	if err != nil || !isGzip {
+		return filename, func() {}, err
+	}
+	infile, err := phylotree.OpenFile(filename)
+	if err != nil {
+		return "", func() {}, err
+	}
+	defer infile.Close()
+	// Keep the original suffix, because the file format
+	// is detected by the suffix.
+	suffix := filepath.Ext(strings.TrimSuffix(filename, gzSuffix))
+	outfile, err := ioutil.TempFile("", "phyloage-*"+suffix)
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup = func() { os.Remove(outfile.Name()) }
+	_, err = io.Copy(outfile, infile)
+	if closeErr := outfile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	return outfile.Name(), cleanup, nil
+}
+
+// uncompressedDir returns the name of a directory that contains the
+// files of dirname. If dirname contains files that are compressed by
+// gzip, all files are copied into a temporary directory and the
+// compressed files are decompressed. Like for OpenFile, compressed
+// files are detected by their first bytes. cleanup removes the
+// temporary directory.
+func uncompressedDir(dirname string) (name string, cleanup func(), err error) {
+	files, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return "", func() {}, err
+	}
+	hasGzip := false
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		isGzip, err := phylotree.IsGzipFile(filepath.Join(dirname, file.Name()))
+		if err != nil {
+			return "", func() {}, err
+		}
+		if isGzip {
+			hasGzip = true
+			break
+		}
+	}
+	if !hasGzip {
+		return dirname, func() {}, nil
+	}
+	tmpdir, err := ioutil.TempDir("", "phyloage-")
+	if err != nil {
+		return "", func() {}, err
+	}
+	cleanup = func() { os.RemoveAll(tmpdir) }
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		name := file.Name()
+		if strings.HasSuffix(strings.ToLower(name), gzSuffix) {
+			name = name[:len(name)-len(gzSuffix)]
+		}
+		err = copyFile(filepath.Join(tmpdir, name), filepath.Join(dirname, file.Name()))
+		if err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+	}
+	return tmpdir, cleanup, nil
+}
+
+// copyFile copies the content of src to dst. If src is compressed
+// by gzip, it is decompressed.
+func copyFile(dst, src string) error {
+	infile, err := phylotree.OpenFile(src)
+	if err != nil {
+		return err
+	}
+	defer infile.Close()
+	outfile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(outfile, infile)
+	if closeErr := outfile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
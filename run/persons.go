@@ -0,0 +1,184 @@
+package run
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+	"github.com/yogischogi/phylofriend/genfiles"
+)
+
+// ReadPersons reads persons from files or directories. Files with
+// the suffix .csv are read as CSV files, all other files as text
+// files. Directories must contain files in YFull format.
+// format is the format of CSV files: auto, wide or long.
+// Files that are compressed by gzip are decompressed.
+func ReadPersons(filenames []string, format string) ([]*genetic.Person, error) {
+	switch format {
+	case "auto", "wide", "long":
+	default:
+		return nil, errors.New("unknown persons format: " + format)
+	}
+	persons := make([]*genetic.Person, 0)
+	for _, filename := range filenames {
+		var pers []*genetic.Person
+		fileInfo, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		isCSV := !fileInfo.IsDir() &&
+			strings.HasSuffix(strings.TrimSuffix(strings.ToLower(filename), gzSuffix), ".csv")
+		isLong := isCSV && format == "long"
+		if isCSV && format == "auto" {
+			isLong, err = isLongFormat(filename)
+		}
+		// Files in long format are decompressed while reading.
+		// The genfiles readers need uncompressed files.
+		cleanup := func() {}
+		switch {
+		case err != nil:
+			// Error is handled below.
+		case isLong:
+			pers, err = readPersonsFromLongCSV(filename)
+		case fileInfo.IsDir():
+			if filename, cleanup, err = uncompressedDir(filename); err == nil {
+				pers, err = genfiles.ReadPersonsFromDir(filename)
+			}
+		case isCSV:
+			if filename, cleanup, err = uncompressed(filename); err == nil {
+				pers, err = genfiles.ReadPersonsFromCSV(filename, 0)
+			}
+		default:
+			if filename, cleanup, err = uncompressed(filename); err == nil {
+				pers, err = genfiles.ReadPersonsFromTXT(filename)
+			}
+		}
+		cleanup()
+		if err != nil {
+			return nil, err
+		}
+		persons = append(persons, pers...)
+	}
+	return persons, nil
+}
+
+// FilterMinMarkers returns the persons that have at least minMarkers
+// tested markers and the IDs of the removed persons. Like for the
+// genetic distances, a marker is tested if it's value is > 0.
+// Null alleles have also been tested.
+func FilterMinMarkers(persons []*genetic.Person, minMarkers int) (result []*genetic.Person, removed []string) {
+	result = make([]*genetic.Person, 0, len(persons))
+	for _, person := range persons {
+		tested := 0
+		for _, value := range person.YstrMarkers {
+			if value > 0 || value == phylotree.Null {
+				tested++
+			}
+		}
+		if tested < minMarkers {
+			removed = append(removed, person.ID)
+		} else {
+			result = append(result, person)
+		}
+	}
+	return result, removed
+}
+
+// isLongFormat checks if the first line of a CSV file is the
+// header of a file in long format: kit,marker,value.
+func isLongFormat(filename string) (bool, error) {
+	infile, err := phylotree.OpenFile(filename)
+	if err != nil {
+		return false, err
+	}
+	defer infile.Close()
+
+	scanner := bufio.NewScanner(infile)
+	if !scanner.Scan() {
+		return false, scanner.Err()
+	}
+	fields := strings.Split(scanner.Text(), ",")
+	if len(fields) != 3 {
+		return false, nil
+	}
+	for i, name := range []string{"kit", "marker", "value"} {
+		if strings.ToLower(strings.TrimSpace(fields[i])) != name {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readPersonsFromLongCSV reads persons from a CSV file in long format.
+// Each row contains a single marker value for a kit:
+//
+//	kit,marker,value
+//	12345,DYS393,13
+//	12345,DYS390,24
+//
+// Marker names may be internal, FTDNA or YFull names. A header line
+// is optional. Rows that contain different values for the same kit
+// and marker are reported as an error.
+func readPersonsFromLongCSV(filename string) ([]*genetic.Person, error) {
+	infile, err := phylotree.OpenFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	persons := make([]*genetic.Person, 0)
+	personsMap := make(map[string]*genetic.Person)
+	var conflicts []string
+
+	reader := csv.NewReader(infile)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	lineNo := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lineNo++
+		kit := strings.TrimSpace(record[0])
+		if lineNo == 1 && strings.ToLower(kit) == "kit" {
+			// Skip header.
+			continue
+		}
+		marker := phylotree.MarkerIndex(record[1])
+		if marker < 0 {
+			return nil, fmt.Errorf("line %d, unknown marker: %s", lineNo, record[1])
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d, could not convert value to float: %s", lineNo, record[2])
+		}
+		person, exists := personsMap[kit]
+		if !exists {
+			person = &genetic.Person{ID: kit, Name: kit}
+			personsMap[kit] = person
+			persons = append(persons, person)
+		}
+		oldValue := person.YstrMarkers[marker]
+		if oldValue != 0 && oldValue != value {
+			conflicts = append(conflicts,
+				fmt.Sprintf("%s %s: %g, %g", kit, genetic.YstrMarkerTable[marker].InternalName, oldValue, value))
+			continue
+		}
+		person.YstrMarkers[marker] = value
+	}
+	if len(conflicts) > 0 {
+		return nil, errors.New("conflicting values for " + strings.Join(conflicts, "; "))
+	}
+	return persons, nil
+}
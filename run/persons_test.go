@@ -0,0 +1,134 @@
+package run
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestFilterMinMarkers checks that null alleles count as tested
+// markers, but Uncertain and missing values do not.
+func TestFilterMinMarkers(t *testing.T) {
+	values := map[string]float64{"DYS393": 13, "DYS390": 24, "DYS19": 14}
+	persons := []*genetic.Person{
+		newPerson(t, "1", values),
+		newPerson(t, "2", map[string]float64{"DYS393": 13, "DYS390": 24, "DYS425": phylotree.Null}),
+		newPerson(t, "3", map[string]float64{"DYS393": 13, "DYS390": 24, "DYS425": phylotree.Uncertain}),
+	}
+	result, removed := FilterMinMarkers(persons, 3)
+	if len(result) != 2 || result[0].ID != "1" || result[1].ID != "2" {
+		t.Errorf("%d persons left", len(result))
+	}
+	if len(removed) != 1 || removed[0] != "3" {
+		t.Errorf("removed %v, want [3]", removed)
+	}
+}
+
+// TestIsLongFormat checks the detection of the header of files
+// in long format.
+func TestIsLongFormat(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		text   string
+		isLong bool
+	}{
+		{"kit,marker,value\r\n1,DYS393,13\r\n", true},
+		{" Kit , Marker , VALUE\r\n1,DYS393,13\r\n", true},
+		{"kit,DYS393,DYS390\r\n1,13,24\r\n", false},
+		{"1,DYS393,13\r\n", false},
+		{"", false},
+	}
+	for i, test := range tests {
+		filename := writeFile(t, dir, fmt.Sprintf("persons%d.csv", i), test.text)
+		isLong, err := isLongFormat(filename)
+		if err != nil || isLong != test.isLong {
+			t.Errorf("%q: long format %t, %v, want %t", test.text, isLong, err, test.isLong)
+		}
+	}
+}
+
+// TestReadPersonsFromLongCSV checks that marker names are resolved
+// by all of their names and that unknown markers and conflicting
+// values are reported.
+func TestReadPersonsFromLongCSV(t *testing.T) {
+	dir := t.TempDir()
+	dys390 := phylotree.MarkerIndex("DYS390")
+	dys19 := phylotree.MarkerIndex("DYS19")
+	text := "kit,marker,value\r\n" +
+		"1,dys393,13\r\n" +
+		"1," + genetic.YstrMarkerTable[dys390].FTDNAName + ",24\r\n" +
+		"1," + genetic.YstrMarkerTable[dys19].YFullName + ",14\r\n" +
+		"2, DYS393 ,14\r\n" +
+		"2,DYS393,14\r\n"
+	persons, err := readPersonsFromLongCSV(writeFile(t, dir, "persons.csv", text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(persons) != 2 || persons[0].ID != "1" || persons[1].ID != "2" {
+		t.Fatalf("%d persons", len(persons))
+	}
+	values := persons[0].YstrMarkers
+	if values[phylotree.MarkerIndex("DYS393")] != 13 || values[dys390] != 24 || values[dys19] != 14 {
+		t.Errorf("values of kit 1: %v", values)
+	}
+
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"kit,marker,value\r\n1,DYS393,13\r\n1,DYS999,10\r\n", "line 3, unknown marker: DYS999"},
+		{"1,DYS393,13\r\n1,DYS393,14\r\n", "conflicting values for 1 DYS393: 13, 14"},
+	}
+	for i, test := range tests {
+		_, err := readPersonsFromLongCSV(writeFile(t, dir, fmt.Sprintf("error%d.csv", i), test.text))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("error = %v, want %s", err, test.err)
+		}
+	}
+}
+
+// writeGzipFile writes text compressed by gzip into the file name
+// in dir and returns the filename.
+func writeGzipFile(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write([]byte(text))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writeFile(t, dir, name, buffer.String())
+}
+
+// TestReadCompressed checks that compressed files in long format
+// and calibration files are detected by their content, with and
+// without the .gz suffix.
+func TestReadCompressed(t *testing.T) {
+	dir := t.TempDir()
+	persons := "kit,marker,value\r\n1,DYS393,13\r\n2,DYS393,14\r\n"
+	for _, name := range []string{"persons.csv.gz", "persons.csv"} {
+		filename := writeGzipFile(t, dir, name, persons)
+		result, err := ReadPersons([]string{filename}, "auto")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(result) != 2 || result[1].YstrMarkers[phylotree.MarkerIndex("DYS393")] != 14 {
+			t.Errorf("%s: %d persons", name, len(result))
+		}
+		os.Remove(filename)
+	}
+
+	anchors, err := ReadAnchors(writeGzipFile(t, dir, "anchors.txt", "L21, 4500\r\nZ2103 5000 2\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchors) != 2 || anchors[1].Clade != "Z2103" || anchors[1].Weight != 2 {
+		t.Errorf("anchors = %v", anchors)
+	}
+}
@@ -0,0 +1,266 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// maxMutationRate is the largest plausible mutation rate
+// per generation for a single marker.
+const maxMutationRate = 0.05
+
+// checkMutationRates checks mutation rates for unusable values.
+// It reports markers with negative or absurdly large rates. listed
+// are the indices of the markers that are listed in the mutation
+// rates file. A rate of zero is reported for these markers, because
+// it is most likely an error in the file.
+// The result is a list of human readable problems.
+func checkMutationRates(rates genetic.YstrMarkers, listed []int) []string {
+	var problems []string
+	isListed := make([]bool, len(rates))
+	for _, i := range listed {
+		isListed[i] = true
+	}
+	for i, rate := range rates {
+		name := genetic.YstrMarkerTable[i].InternalName
+		switch {
+		case rate < 0:
+			problems = append(problems, fmt.Sprintf("negative mutation rate for %s: %g", name, rate))
+		case rate > maxMutationRate:
+			problems = append(problems, fmt.Sprintf("mutation rate too large for %s: %g", name, rate))
+		case rate == 0 && isListed[i]:
+			problems = append(problems, fmt.Sprintf("zero mutation rate for %s", name))
+		}
+	}
+	return problems
+}
+
+// checkMissingRates reports markers that are used by persons but
+// have no mutation rate. The markers specified by excluded have been
+// excluded on purpose and are not reported.
+// The result is a list of human readable problems.
+func checkMissingRates(rates genetic.YstrMarkers, persons []*genetic.Person, excluded []int) []string {
+	var problems []string
+	used := make([]bool, len(rates))
+	for _, person := range persons {
+		for i, value := range person.YstrMarkers {
+			if value > 0 {
+				used[i] = true
+			}
+		}
+	}
+	for _, i := range excluded {
+		used[i] = false
+	}
+	for i, rate := range rates {
+		if rate == 0 && used[i] {
+			problems = append(problems, fmt.Sprintf("missing mutation rate for %s", genetic.YstrMarkerTable[i].InternalName))
+		}
+	}
+	return problems
+}
+
+// reportRateProblems writes problems with the mutation rates as
+// warnings. If strict is true, the problems are returned as an error.
+func reportRateProblems(problems []string, strict bool, warnings io.Writer) error {
+	if strict == true && len(problems) > 0 {
+		return errors.New("problems with mutation rates: " + strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", problem)
+	}
+	return nil
+}
+
+// FormatMutationRates returns rates in the format of a mutation
+// rates file, one marker per line. Markers with a rate of zero are
+// marked by a comment, because they do not contribute to distances.
+func FormatMutationRates(rates genetic.YstrMarkers) string {
+	var buffer bytes.Buffer
+	for i, rate := range rates {
+		name := genetic.YstrMarkerTable[i].InternalName
+		if name == "" {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf("%s, %g", name, rate))
+		if rate == 0 {
+			buffer.WriteString(" // zero rate, not used for distances")
+		}
+		buffer.WriteString("\r\n")
+	}
+	return buffer.String()
+}
+
+// ReadMarkerList reads a list of marker names from a file. The names
+// are separated by commas or white space. Comments start with //.
+func ReadMarkerList(filename string) ([]string, error) {
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	var result []string
+	isSeparator := func(c rune) bool {
+		return unicode.IsSpace(c) || c == ',' || c == ';'
+	}
+	scanner := bufio.NewScanner(infile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		result = append(result, strings.FieldsFunc(line, isSeparator)...)
+	}
+	return result, scanner.Err()
+}
+
+// markerIndices returns the indices of the markers named by names.
+// All unknown names are reported in the error.
+func markerIndices(names []string) ([]int, error) {
+	var result []int
+	var unknown []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		indices := phylotree.MarkerIndices(name)
+		if len(indices) == 0 {
+			unknown = append(unknown, name)
+		}
+		result = append(result, indices...)
+	}
+	if len(unknown) > 0 {
+		return nil, errors.New("unknown markers: " + strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+// excludeMarkers sets the mutation rates of the markers specified
+// by indices to zero and removes their values from all persons.
+// The result is the number of markers with a mutation rate > 0.
+func excludeMarkers(indices []int, rates *genetic.YstrMarkers, persons []*genetic.Person) int {
+	for _, i := range indices {
+		rates[i] = 0
+		for _, person := range persons {
+			person.YstrMarkers[i] = 0
+		}
+	}
+	active := 0
+	for _, rate := range rates {
+		if rate > 0 {
+			active++
+		}
+	}
+	return active
+}
+
+// fastMarkers returns the indices of all markers whose mutation
+// rate is larger than maxRate.
+func fastMarkers(rates genetic.YstrMarkers, maxRate float64) []int {
+	var result []int
+	for i, rate := range rates {
+		if rate > maxRate {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// fastMarkersReport lists the markers specified by fast together with
+// their mutation rates, the sum of their rates and the sum of the
+// remaining rates, which shows the effective speed of the clock.
+func fastMarkersReport(fast []int, rates genetic.YstrMarkers, maxRate float64) string {
+	var buffer bytes.Buffer
+	removed := 0.0
+	names := make([]string, 0, len(fast))
+	for _, i := range fast {
+		removed += rates[i]
+		names = append(names, fmt.Sprintf("%s (%g)", genetic.YstrMarkerTable[i].InternalName, rates[i]))
+	}
+	total := 0.0
+	for _, rate := range rates {
+		if rate > 0 {
+			total += rate
+		}
+	}
+	if len(fast) > 0 {
+		buffer.WriteString(fmt.Sprintf("Excluded markers with a mutation rate > %g: %s\r\n", maxRate, strings.Join(names, ", ")))
+	} else {
+		buffer.WriteString(fmt.Sprintf("No markers with a mutation rate > %g.\r\n", maxRate))
+	}
+	buffer.WriteString(fmt.Sprintf("Removed mutation rate: %g, remaining mutation rate: %g per generation.\r\n",
+		removed, total-removed))
+	return buffer.String()
+}
+
+// SelectMarkers selects a stable set of markers from statistics.
+// The markers must have a frequency of at least minFreq and between
+// nValuesMin and nValuesMax different values. The result contains
+// the mutation rates of the selected markers and their number.
+func SelectMarkers(statistics *genetic.MarkerStatistics, minFreq float64, nValuesMin, nValuesMax int) (rates string, n int) {
+	selected := statistics.Select(minFreq, nValuesMin, nValuesMax)
+	for i, _ := range selected.Markers {
+		if len(selected.Markers[i].ValuesOccurrences) > 0 {
+			n++
+		}
+	}
+	return selected.MutationRates(), n
+}
+
+// WriteSelectedMarkers writes the mutation rates of the markers that
+// are selected by SelectMarkers to the file filename. The result is
+// the number of selected markers.
+func WriteSelectedMarkers(filename string, statistics *genetic.MarkerStatistics, minFreq float64, nValuesMin, nValuesMax int) (int, error) {
+	rates, n := SelectMarkers(statistics, minFreq, nValuesMin, nValuesMax)
+	if err := ioutil.WriteFile(filename, []byte(rates), os.ModePerm); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// rateFileNames returns the indices of all markers that are listed
+// in a mutation rates file and all names that do not match any known
+// marker name. Every token that is not a number is considered to be
+// a name.
+func rateFileNames(filename string) (listed []int, unknown []string, err error) {
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer infile.Close()
+
+	isSeparator := func(c rune) bool {
+		return unicode.IsSpace(c) || c == ',' || c == ';' || c == ':' || c == '='
+	}
+	scanner := bufio.NewScanner(infile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		for _, token := range strings.FieldsFunc(line, isSeparator) {
+			if _, err := strconv.ParseFloat(token, 64); err == nil {
+				continue
+			}
+			if i := phylotree.MarkerIndex(token); i >= 0 {
+				listed = append(listed, i)
+			} else {
+				unknown = append(unknown, token)
+			}
+		}
+	}
+	return listed, unknown, scanner.Err()
+}
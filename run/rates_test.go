@@ -0,0 +1,74 @@
+package run
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+	"github.com/yogischogi/phylofriend/genfiles"
+)
+
+// TestWriteSelectedMarkers checks that the mutation rates of the
+// selected markers are written to the file and that the selected
+// markers are counted.
+func TestWriteSelectedMarkers(t *testing.T) {
+	persons := []*genetic.Person{
+		newPerson(t, "1", map[string]float64{"DYS393": 13, "DYS390": 24}),
+		newPerson(t, "2", map[string]float64{"DYS393": 14, "DYS390": 24}),
+		newPerson(t, "3", map[string]float64{"DYS393": 13, "DYS390": 23}),
+	}
+	statistics := genetic.NewStatistics(persons)
+	filename := filepath.Join(t.TempDir(), "selected.txt")
+	n, err := WriteSelectedMarkers(filename, statistics, 1.0, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates, count := SelectMarkers(genetic.NewStatistics(persons), 1.0, 1, 5)
+	if n != count || n != 2 {
+		t.Errorf("%d markers selected, SelectMarkers selects %d, want 2", n, count)
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != rates {
+		t.Errorf("file contains %q, want %q", content, rates)
+	}
+
+	if _, err := WriteSelectedMarkers(filepath.Join(t.TempDir(), "missing", "selected.txt"), statistics, 1.0, 1, 5); err == nil {
+		t.Errorf("no error for a file in a missing directory")
+	}
+}
+
+// TestFormatMutationRates checks that the written mutation rates are
+// read back unchanged by genfiles.ReadMutationRates and that markers
+// with a zero rate are marked by a comment.
+func TestFormatMutationRates(t *testing.T) {
+	rates := genetic.DefaultMutationRates()
+	zero := phylotree.MarkerIndex("DYS389ii")
+	rates[zero] = 0
+	rates[phylotree.MarkerIndex("DYS393")] = 0.00076
+	text := FormatMutationRates(rates)
+
+	name := genetic.YstrMarkerTable[zero].InternalName
+	if !strings.Contains(text, name+", 0 // zero rate, not used for distances\r\n") {
+		t.Errorf("zero rate of %s is not marked:\n%s", name, text)
+	}
+	if n := strings.Count(text, "// zero rate"); n != 1 {
+		t.Errorf("%d zero rate comments, want 1", n)
+	}
+
+	filename := writeFile(t, t.TempDir(), "rates.txt", text)
+	read, err := genfiles.ReadMutationRates(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, _ := range rates {
+		if genetic.YstrMarkerTable[i].InternalName != "" && read[i] != rates[i] {
+			t.Errorf("%s: rate %g, want %g", genetic.YstrMarkerTable[i].InternalName, read[i], rates[i])
+		}
+	}
+}
@@ -0,0 +1,911 @@
+// Package run implements the calculation of time estimates for a
+// phylogenetic tree. It contains the whole processing pipeline of
+// phyloage and can be used by other programs.
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/yogischogi/phyloage/phylotree"
+	"github.com/yogischogi/phylofriend/genetic"
+	"github.com/yogischogi/phylofriend/genfiles"
+)
+
+// Options are the parameters for the calculation.
+type Options struct {
+	// TreeFiles are the filenames of the input trees.
+	// Multiple trees are merged. The filename "-" means Stdin.
+	TreeFiles []string
+	// Stdin is used to read a tree with the filename "-".
+	Stdin io.Reader
+	// NewickLengths specifies that the trees are in Newick format
+	// and that the branch lengths are used as STR counts.
+	NewickLengths bool
+	// TabWidth is the number of spaces that a tab counts for the
+	// indentation of trees in text format.
+	TabWidth int
+	// NegativePrefix marks negative SNP calls in the input tree.
+	// An empty prefix turns off negative calls.
+	NegativePrefix string
+	// Grafts are trees that are attached to clades of the input tree.
+	Grafts []Graft
+	// Priors is the filename of prior TMRCA estimates. This may be empty.
+	Priors string
+	// Weights is the filename of sample weights. This may be empty.
+	Weights string
+	// Subclades are the SNP names of the branches of the tree that
+	// are selected. A sample ID selects the clade that contains the
+	// sample. Each branch is calculated independently. If empty,
+	// the whole tree is used.
+	Subclades []string
+	// MutationRates is the filename of the mutation rates.
+	// If empty, the default mutation rates are used.
+	MutationRates string
+	// StrictRates treats problems with the mutation rates as errors.
+	StrictRates bool
+	// PersonsFiles are the filenames or directories of the
+	// persons' Y-STR data.
+	PersonsFiles []string
+	// IDMatch specifies how the IDs of persons and samples are compared.
+	IDMatch phylotree.IDMatch
+	// PersonsFormat is the format of CSV files: auto, wide or long.
+	PersonsFormat string
+	// ExcludeMarkers are the names of markers that are not used for
+	// the calculation. Multi-copy markers may be named without the
+	// copy letter, for example DYS464.
+	ExcludeMarkers []string
+	// MaxRate is the maximum mutation rate of a marker. Faster
+	// markers are excluded like ExcludeMarkers. 0 means no limit.
+	MaxRate float64
+	// NullMarkers are the names of markers for which a missing value
+	// of a person, who has tested the marker, is a null allele.
+	NullMarkers []string
+	// Average is the method to average the STR counts of the
+	// samples of a clade: mean or median. Empty means mean.
+	Average string
+	// Trim is the fraction of the smallest and of the largest STR
+	// counts of the samples of a clade that are trimmed before the
+	// mean is calculated. 0 means no trimming.
+	Trim float64
+	// TrimMode is the handling of the trimmed STR counts: drop or
+	// winsorize. Empty means drop.
+	TrimMode string
+	// Microalleles is the handling of marker values with partial
+	// repeats, like 17.2: strict or ignore. strict treats the partial
+	// repeat as a label of the allele, ignore rounds the values to
+	// whole numbers. Empty means strict.
+	Microalleles string
+	// MinMarkers is the minimum number of tested markers of a person.
+	// Persons with fewer markers are not used. 0 means no minimum.
+	MinMarkers int
+	// Strict treats severe problems of the persons' data and
+	// problems with the indentation of the input trees as errors.
+	Strict bool
+	// Method is the method to calculate modal haplotypes:
+	// phylofriend or parsimony.
+	Method string
+	// Stage is the processing stage for the parsimony method.
+	Stage int
+	// Model is the mutation model: hybrid, infinite or both.
+	Model string
+	// Distance is the distance function: hybrid, infinite or stepwise.
+	// If it is empty, the distance function of Model is used.
+	Distance string
+	// RerunModals specifies if the modal haplotypes are calculated
+	// separately for each mutation model, if Model is both.
+	RerunModals bool
+	// MaxSteps is the maximum number of mutation steps for
+	// a single marker. 0 means unlimited.
+	MaxSteps float64
+	// StepsMode is the handling of larger differences than
+	// MaxSteps: cap or single.
+	StepsMode string
+	// MinSupport is the minimum number of samples that must
+	// support a modal marker value.
+	MinSupport int
+	// MaxUncertain is the maximum number of Uncertain values in the
+	// modal haplotype of a clade. A negative value means no limit.
+	MaxUncertain int
+	// MaxUncertainPct is the maximum percentage of Uncertain values
+	// in all modal haplotypes. A negative value means no limit.
+	MaxUncertainPct float64
+	// MaxBranchGD is the maximum genetic distance between the modal
+	// haplotypes of a subclade and it's parent. 0 means no check.
+	MaxBranchGD float64
+	// ExcludeDistant excludes subclades that exceed MaxBranchGD
+	// from the age calculation of their parents.
+	ExcludeDistant bool
+	// PanelWarnings warns about clades whose samples mix very
+	// small and very large panels, see PanelWarnings of the
+	// phylotree package.
+	PanelWarnings bool
+	// GenTime is the generation time in years.
+	GenTime float64
+	// Calibration is the calibration factor for the ages.
+	Calibration float64
+	// Anchors are clades with known TMRCAs. If there are anchors,
+	// the calibration factor is fitted to their ages and
+	// Calibration is not used.
+	Anchors []Anchor
+	// TreeAnchors specifies that the age anchors of the input tree
+	// are added to Anchors.
+	TreeAnchors bool
+	// FitOffset specifies that the offset is fitted to the anchors
+	// together with the calibration factor. Then Offset is not used.
+	FitOffset bool
+	// Offset is added to all calculated ages.
+	Offset float64
+	// TopDown specifies if a top down recalculation of the ages
+	// is performed.
+	TopDown bool
+	// SNPRate is the number of years per SNP mutation. If > 0,
+	// SNP counts are combined with STR branch lengths.
+	SNPRate float64
+	// ExcludeSamples are the IDs of samples that are removed
+	// from the tree before the calculation.
+	ExcludeSamples []string
+	// ExcludeClades are the SNP names of subclades that are removed
+	// together with their samples from the tree before the calculation.
+	ExcludeClades []string
+	// Previous is the filename of a previously calculated tree
+	// for an incremental update. This may be empty.
+	Previous string
+	// Log receives reports. If nil, they are discarded.
+	Log io.Writer
+	// Warnings receives warnings about problems of the input data.
+	// If nil, warnings are written to Log.
+	Warnings io.Writer
+}
+
+// Graft is a tree from a file that is attached as a new subclade
+// to the clade Target of the input tree.
+type Graft struct {
+	Filename string
+	Target   string
+}
+
+// ReadOptions returns the options to read trees in text format.
+func (opts Options) ReadOptions() phylotree.ReadOptions {
+	return phylotree.ReadOptions{Strict: opts.Strict, TabWidth: opts.TabWidth, NegativePrefix: opts.NegativePrefix}
+}
+
+// ParseGraft parses a graft in the format filename:SNP.
+func ParseGraft(text string) (Graft, error) {
+	pos := strings.LastIndex(text, ":")
+	if pos <= 0 || pos == len(text)-1 {
+		return Graft{}, errors.New(fmt.Sprintf("invalid graft %q, format must be filename:SNP", text))
+	}
+	return Graft{Filename: text[:pos], Target: text[pos+1:]}, nil
+}
+
+// DefaultOptions returns the default options of the phyloage program.
+func DefaultOptions() Options {
+	return Options{
+		IDMatch:         phylotree.DefaultIDMatch,
+		TabWidth:        phylotree.DefaultReadOptions().TabWidth,
+		NegativePrefix:  phylotree.DefaultNegativePrefix,
+		PersonsFormat:   "auto",
+		Method:          "parsimony",
+		Stage:           4,
+		Model:           "hybrid",
+		RerunModals:     true,
+		StepsMode:       "cap",
+		MinSupport:      1,
+		MaxUncertain:    -1,
+		MaxUncertainPct: -1,
+		GenTime:         1,
+		Calibration:     1,
+		TopDown:         true}
+}
+
+// Result contains the results of the calculation.
+type Result struct {
+	// Tree is the tree with modal haplotypes and ages.
+	Tree *phylotree.Clade
+	// InputTree is a copy of the tree with the persons inserted,
+	// before any modal haplotypes, exclusions or ages have been
+	// calculated. It is used for calculations that start from
+	// scratch, for example the comparison of methods.
+	InputTree *phylotree.Clade
+	// InfiniteTree contains the results of the infinite alleles
+	// model, if Model is both. Otherwise it is nil.
+	InfiniteTree *phylotree.Clade
+	// Header is a comment that describes the results.
+	Header string
+	// Persons are the persons' Y-STR data.
+	Persons []*genetic.Person
+	// MutationRates are the mutation rates that were used.
+	MutationRates genetic.YstrMarkers
+	// Matching describes how the persons matched the samples
+	// of the tree.
+	Matching phylotree.Matching
+	// Statistics are the marker statistics of the persons.
+	// This is nil if there is no persons' data.
+	Statistics *genetic.MarkerStatistics
+	// IsInfiniteAlleles is true if the primary model is the
+	// infinite alleles model.
+	IsInfiniteAlleles bool
+	// DistanceName is the name of the distance function
+	// of the primary model.
+	DistanceName string
+	// Limit is the limit for mutation steps.
+	Limit phylotree.StepLimit
+	// Averaging is the method to average the STR counts
+	// of the samples.
+	Averaging phylotree.Averaging
+	// Calibration and Offset are the calibration factor and the
+	// offset that are used for the ages. They are fitted if anchors
+	// are specified.
+	Calibration float64
+	Offset      float64
+
+	options Options
+	// isCalibrated is true if Calibration and Offset have been
+	// fitted to the whole tree before the subclades are calculated.
+	isCalibrated bool
+}
+
+// Run performs the whole calculation and returns the resulting tree.
+// Only a single subclade may be selected.
+func Run(opts Options) (*phylotree.Clade, error) {
+	result, err := Calculate(opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tree, nil
+}
+
+// Calculate performs the whole calculation: It reads the tree, the
+// mutation rates and the persons' data, calculates the modal
+// haplotypes and the ages. Only a single subclade may be selected.
+func Calculate(opts Options) (*Result, error) {
+	if len(opts.Subclades) > 1 {
+		return nil, errors.New("multiple subclades selected, use CalculateAll")
+	}
+	results, err := CalculateAll(opts)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CalculateAll performs the whole calculation like Calculate for
+// each selected subclade. The input files are read only once. The
+// results are in the order of opts.Subclades. If no subclade is
+// selected, the result contains the whole tree.
+func CalculateAll(opts Options) ([]*Result, error) {
+	log := opts.Log
+	if log == nil {
+		log = ioutil.Discard
+	}
+	warnings := opts.Warnings
+	if warnings == nil {
+		warnings = log
+	}
+	result := &Result{options: opts, Calibration: opts.Calibration, Offset: opts.Offset}
+
+	// Check options.
+	switch opts.Model {
+	case "infinite":
+		result.IsInfiniteAlleles = true
+	case "hybrid", "both":
+		result.IsInfiniteAlleles = false
+	default:
+		return nil, errors.New("unknown mutation model: " + opts.Model)
+	}
+	result.DistanceName = opts.Distance
+	if result.DistanceName == "" {
+		result.DistanceName = "hybrid"
+		if result.IsInfiniteAlleles {
+			result.DistanceName = "infinite"
+		}
+	}
+	if _, exists := distances[result.DistanceName]; !exists {
+		return nil, errors.New(fmt.Sprintf("unknown distance function %s, valid options are %s",
+			result.DistanceName, strings.Join(DistanceNames(), ", ")))
+	}
+	result.Header += "// Distance function: " + result.DistanceName + "\r\n"
+	result.Limit = phylotree.StepLimit{MaxSteps: opts.MaxSteps}
+	switch opts.StepsMode {
+	case "cap":
+		result.Limit.IsSingle = false
+	case "single":
+		result.Limit.IsSingle = true
+	default:
+		return nil, errors.New("unknown mode for max-steps: " + opts.StepsMode)
+	}
+	switch opts.Average {
+	case "", "mean":
+	case "median":
+		result.Averaging.Median = true
+		result.Header += "// Sample STR counts are averaged by the median.\r\n"
+	default:
+		return nil, errors.New("unknown averaging method: " + opts.Average)
+	}
+	if opts.Trim < 0 || opts.Trim >= 0.5 {
+		return nil, errors.New(fmt.Sprintf("invalid trim fraction %g, it must be at least 0 and less than 0.5", opts.Trim))
+	}
+	if opts.Trim > 0 && result.Averaging.Median {
+		return nil, errors.New("trimming can not be combined with the median")
+	}
+	result.Averaging.Trim = opts.Trim
+	switch opts.TrimMode {
+	case "", "drop":
+	case "winsorize":
+		result.Averaging.Winsorize = true
+	default:
+		return nil, errors.New("unknown trim mode: " + opts.TrimMode)
+	}
+	if opts.Trim > 0 {
+		mode := "drop"
+		if result.Averaging.Winsorize {
+			mode = "winsorize"
+		}
+		result.Header += fmt.Sprintf("// Sample STR counts are trimmed by %g (%s).\r\n", opts.Trim, mode)
+	}
+	switch opts.Microalleles {
+	case "", "strict", "ignore":
+	default:
+		return nil, errors.New("unknown mode for microalleles: " + opts.Microalleles)
+	}
+	switch opts.Method {
+	case "phylofriend", "parsimony":
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown method %q to calculate modal haplotypes", opts.Method))
+	}
+
+	excludedMarkers, err := markerIndices(opts.ExcludeMarkers)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("excluded markers, %v", err))
+	}
+	nullMarkers, err := markerIndices(opts.NullMarkers)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("null markers, %v", err))
+	}
+
+	// Load phylogenetic tree.
+	wholeTree, trees, err := readTree(opts, log, warnings)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.ExcludeSamples) > 0 {
+		result.Header += "// Removed samples: " + strings.Join(opts.ExcludeSamples, ", ") + "\r\n"
+	}
+	if len(opts.ExcludeClades) > 0 {
+		result.Header += "// Removed subclades: " + strings.Join(opts.ExcludeClades, ", ") + "\r\n"
+	}
+
+	// Read mutation rates from file.
+	mrfile := opts.MutationRates
+	if opts.MutationRates != "" {
+		var cleanup func()
+		mrfile, cleanup, err = uncompressed(opts.MutationRates)
+		if err == nil {
+			result.MutationRates, err = genfiles.ReadMutationRates(mrfile)
+		}
+		defer cleanup()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("reading mutation rates, %v", err))
+		}
+	} else {
+		// Use default values.
+		result.MutationRates = genetic.DefaultMutationRates()
+	}
+
+	// Check mutation rates.
+	var listed []int
+	var unknown []string
+	if opts.MutationRates != "" {
+		listed, unknown, err = rateFileNames(mrfile)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("reading mutation rates, %v", err))
+		}
+	}
+	problems := checkMutationRates(result.MutationRates, listed)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown marker in mutation rates: %s", name))
+	}
+	if err := reportRateProblems(problems, opts.StrictRates, warnings); err != nil {
+		return nil, err
+	}
+
+	// Do not use excluded markers.
+	if len(excludedMarkers) > 0 {
+		active := excludeMarkers(excludedMarkers, &result.MutationRates, nil)
+		fmt.Fprintf(log, "Excluded markers: %s, %d markers remain active.\r\n",
+			strings.Join(opts.ExcludeMarkers, ", "), active)
+		result.Header += "// Excluded markers: " + strings.Join(opts.ExcludeMarkers, ", ") + "\r\n"
+	}
+
+	// Do not use markers that mutate too fast.
+	if opts.MaxRate > 0 {
+		fast := fastMarkers(result.MutationRates, opts.MaxRate)
+		fmt.Fprintf(log, "%s", fastMarkersReport(fast, result.MutationRates, opts.MaxRate))
+		if len(fast) > 0 {
+			excludeMarkers(fast, &result.MutationRates, nil)
+			excludedMarkers = append(excludedMarkers, fast...)
+			result.Header += fmt.Sprintf("// Excluded markers with a mutation rate > %g: %d\r\n", opts.MaxRate, len(fast))
+		}
+	}
+
+	// Load genetic sample results.
+	if len(opts.PersonsFiles) > 0 {
+		result.Persons, err = ReadPersons(opts.PersonsFiles, opts.PersonsFormat)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("loading persons data, %v", err))
+		}
+
+		// Remove excluded markers from the persons' data.
+		if len(excludedMarkers) > 0 {
+			excludeMarkers(excludedMarkers, &result.MutationRates, result.Persons)
+		}
+
+		// Mark null alleles.
+		if len(nullMarkers) > 0 {
+			n := phylotree.MarkNulls(result.Persons, nullMarkers)
+			fmt.Fprintf(log, "Marked %d null alleles.\r\n", n)
+		}
+
+		// Round microalleles.
+		if opts.Microalleles == "ignore" {
+			if n := phylotree.RoundMicroalleles(result.Persons); n > 0 {
+				fmt.Fprintf(log, "Rounded %d microallele values to whole numbers.\r\n", n)
+			}
+		}
+
+		// Remove persons with too few tested markers.
+		if opts.MinMarkers > 0 {
+			var removed []string
+			result.Persons, removed = FilterMinMarkers(result.Persons, opts.MinMarkers)
+			if len(removed) > 0 {
+				fmt.Fprintf(log, "Excluded kits with less than %d markers: %s\r\n",
+					opts.MinMarkers, strings.Join(removed, ", "))
+			}
+		}
+
+		// Check data quality.
+		if opts.Strict == true {
+			var severes []string
+			for _, f := range checkDataQuality(result.Persons) {
+				if f.severity == severe {
+					severes = append(severes, f.String())
+				}
+			}
+			if len(severes) > 0 {
+				return nil, errors.New("severe data problems: " + strings.Join(severes, "; "))
+			}
+		}
+
+		// Check for markers without mutation rates.
+		problems := checkMissingRates(result.MutationRates, result.Persons, excludedMarkers)
+		if err := reportRateProblems(problems, opts.StrictRates, warnings); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fit the calibration once on the whole tree, so that all
+	// selected subclades use the same calibration factor and offset.
+	if len(opts.Subclades) > 0 && (len(opts.Anchors) > 0 || opts.TreeAnchors) {
+		whole := *result
+		err = whole.prepare(wholeTree, ioutil.Discard, ioutil.Discard)
+		if err == nil {
+			err = whole.fitCalibration(wholeTree, log)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Calibration, result.Offset = whole.Calibration, whole.Offset
+		result.options.Anchors = whole.options.Anchors
+		result.Header += whole.calibrationHeader()
+		result.isCalibrated = true
+	}
+
+	// Calculate each selected subclade.
+	results := make([]*Result, 0, len(trees))
+	for _, tree := range trees {
+		r := *result
+		if len(trees) > 1 {
+			r.Header += "// Selected subclade: " + tree.SNPs[0] + "\r\n"
+		}
+		err = r.calculate(tree, log, warnings)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &r)
+	}
+	return results, nil
+}
+
+// calculate inserts the persons into tree, calculates the modal
+// haplotypes and the ages and stores tree in r.
+func (r *Result) calculate(tree *phylotree.Clade, log, warnings io.Writer) error {
+	if err := r.prepare(tree, log, warnings); err != nil {
+		return err
+	}
+	if !r.isCalibrated {
+		if err := r.fitCalibration(tree, log); err != nil {
+			return err
+		}
+	}
+	opts := r.options
+
+	// Keep the results of the previous tree for unchanged clades.
+	if opts.Previous != "" {
+		prevTree, err := phylotree.NewFromFileOptions(opts.Previous, opts.ReadOptions())
+		if err != nil {
+			return errors.New(fmt.Sprintf("reading previous tree from file, %v", err))
+		}
+		tree.ApplyPrevious(prevTree)
+	}
+
+	// Calculate the age of this clade and all subclades.
+	// If the STR-Count is provided in the original tree input
+	// file the calculation can be performed even without sample
+	// data.
+	r.calculateAges(tree)
+
+	// Report trimmed samples.
+	if r.Averaging.Trim > 0 {
+		fmt.Fprintf(log, "%s", tree.TrimReport())
+	}
+
+	// Add the results of the mean for comparison.
+	// Median ages are not directly comparable to mean ages.
+	if r.Averaging.Median {
+		meanTree := tree.Clone()
+		averaging := r.Averaging
+		r.Averaging = phylotree.Averaging{}
+		r.calculateAges(meanTree)
+		r.Averaging = averaging
+		tree.AddModelTMRCAs("mean", meanTree)
+	}
+
+	// Compare the ages with the age anchors of the tree.
+	fmt.Fprintf(log, "%s", tree.AnchorReport())
+
+	// Combine STR and SNP based branch lengths.
+	if opts.SNPRate > 0 {
+		fmt.Fprintf(log, "%s", tree.BlendBranchLengths(opts.SNPRate, r.Offset))
+	}
+
+	// Combine TMRCA estimates with prior estimates.
+	for _, conflict := range tree.CalculatePosteriors() {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", conflict)
+	}
+
+	// Add results of the infinite alleles model.
+	if r.InfiniteTree != nil {
+		r.calculateAges(r.InfiniteTree)
+		tree.AddModelTMRCAs("infinite", r.InfiniteTree)
+		r.Header += "// Results use the hybrid mutation model. TMRCA (infinite) uses the infinite alleles model.\r\n"
+	}
+
+	// Report the recalculated clades of an incremental update.
+	if opts.Previous != "" {
+		fmt.Fprintf(log, "%s", tree.IncrementalReport())
+	}
+	r.Tree = tree
+	return nil
+}
+
+// prepare inserts the persons into tree, calculates the modal
+// haplotypes and the STR counts. Nothing happens if there are no
+// persons.
+func (r *Result) prepare(tree *phylotree.Clade, log, warnings io.Writer) error {
+	opts := r.options
+	if len(opts.PersonsFiles) == 0 {
+		return nil
+	}
+	duplicates := tree.InsertPersonsMatching(r.Persons, opts.IDMatch)
+	if opts.Strict == true && len(duplicates) > 0 {
+		return errors.New(strings.Join(duplicates, "; "))
+	}
+	for _, duplicate := range duplicates {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", duplicate)
+	}
+	r.Matching = tree.Match(r.Persons)
+	if len(r.Matching.UnmatchedPersons) > 0 || len(r.Matching.UnmatchedSamples) > 0 {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", r.Matching.Summary())
+	}
+	if opts.PanelWarnings {
+		for _, warning := range tree.PanelWarnings() {
+			fmt.Fprintf(warnings, "Warning, %s.\r\n", warning)
+		}
+	}
+
+	// Calculate marker statistics. Excluded samples, tentative
+	// samples and samples with a weight of 0 are not included.
+	r.Statistics, _ = tree.Statistics()
+	r.InputTree = tree.Clone()
+
+	// Calculate modal haplotypes.
+	if opts.Model == "both" && opts.RerunModals == true {
+		r.InfiniteTree = tree.Clone()
+		r.CalculateModals(r.InfiniteTree, opts.Method, true)
+	}
+	r.CalculateModals(tree, opts.Method, r.IsInfiniteAlleles)
+	if opts.Model == "both" && opts.RerunModals == false {
+		r.InfiniteTree = tree.Clone()
+	}
+
+	// Stop before the ages are calculated from
+	// unreliable modal haplotypes.
+	if err := tree.CheckUncertainty(opts.MaxUncertain, opts.MaxUncertainPct); err != nil {
+		return err
+	}
+	if r.InfiniteTree != nil {
+		if err := r.InfiniteTree.CheckUncertainty(opts.MaxUncertain, opts.MaxUncertainPct); err != nil {
+			return err
+		}
+	}
+
+	// Warn about fixed marker values that contradict the data.
+	for _, conflict := range tree.FixedValueConflicts() {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", conflict)
+	}
+
+	tree.CalculateDistances(r.MutationRates, r.Distance(r.IsInfiniteAlleles))
+	if r.InfiniteTree != nil {
+		r.InfiniteTree.CalculateDistances(r.MutationRates, r.Distance(true))
+	}
+
+	// Check distances between subclades and their parents.
+	if opts.MaxBranchGD > 0 {
+		for _, branch := range tree.CheckBranchDistances(opts.MaxBranchGD, opts.ExcludeDistant) {
+			fmt.Fprintf(warnings, "Warning, %s.\r\n", branch)
+		}
+		if r.InfiniteTree != nil {
+			for _, branch := range r.InfiniteTree.CheckBranchDistances(opts.MaxBranchGD, opts.ExcludeDistant) {
+				fmt.Fprintf(warnings, "Warning, infinite alleles model, %s.\r\n", branch)
+			}
+		}
+	}
+
+	// Print markers that exceed the maximum number of steps.
+	fmt.Fprintf(log, "%s", tree.StepLimitReport(r.Limit))
+	return nil
+}
+
+// fitCalibration fits the calibration factor and the offset to the
+// age anchors of the options and, if TreeAnchors is set, of tree.
+// Nothing happens if there are no anchors.
+// The STR counts of tree must be calculated before.
+func (r *Result) fitCalibration(tree *phylotree.Clade, log io.Writer) error {
+	opts := r.options
+	if opts.TreeAnchors {
+		anchors := append([]Anchor(nil), opts.Anchors...)
+		for _, clade := range tree.AgeAnchors() {
+			anchors = append(anchors, Anchor{Clade: clade.SNPs[0], Age: clade.AgeAnchor, Weight: 1})
+		}
+		if len(anchors) == 0 {
+			return errors.New("no age anchors found in the tree")
+		}
+		r.options.Anchors = anchors
+		opts = r.options
+	}
+	if len(opts.Anchors) > 0 {
+		residuals, err := r.calibrate(tree)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(log, "%s", residuals)
+		r.Header += r.calibrationHeader()
+	}
+	return nil
+}
+
+// readTree reads and merges the input trees, checks for duplicate
+// samples, reads the priors, removes the excluded samples and
+// subclades and selects the subclades. The results are the whole
+// tree and a tree for each selected subclade or the whole tree.
+func readTree(opts Options, log, warnings io.Writer) (tree *phylotree.Clade, subclades []*phylotree.Clade, err error) {
+	if len(opts.TreeFiles) == 0 {
+		return nil, nil, errors.New("no filename for input tree specified")
+	}
+	for i, filename := range opts.TreeFiles {
+		var t *phylotree.Clade
+		switch {
+		case filename == "-":
+			t, err = phylotree.NewFromReaderOptions(opts.Stdin, opts.ReadOptions())
+		case opts.NewickLengths == true:
+			t, err = phylotree.NewFromNewick(filename, true)
+		default:
+			t, err = phylotree.NewFromFileOptions(filename, opts.ReadOptions())
+		}
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("reading tree from file %s, %v", filename, err))
+		}
+		for _, warning := range t.ParseWarnings() {
+			fmt.Fprintf(warnings, "Warning, %s: %s.\r\n", filename, warning)
+		}
+		if i == 0 {
+			tree = t
+			continue
+		}
+		// Merge trees from multiple files.
+		err = tree.Merge(t)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("merging tree from file %s into %s, %v",
+				filename, strings.Join(opts.TreeFiles[:i], ", "), err))
+		}
+	}
+
+	// Attach subtrees from other files.
+	for _, graft := range opts.Grafts {
+		sub, err := phylotree.NewFromFileOptions(graft.Filename, opts.ReadOptions())
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("reading graft from file %s, %v", graft.Filename, err))
+		}
+		err = tree.Graft(graft.Target, sub)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("grafting tree from file %s, %v", graft.Filename, err))
+		}
+	}
+
+	// Check for SNPs that appear on multiple clades.
+	duplicates := tree.DuplicateSNPs()
+	if opts.Strict == true && len(duplicates) > 0 {
+		return nil, nil, errors.New(strings.Join(duplicates, "; "))
+	}
+	for _, duplicate := range duplicates {
+		fmt.Fprintf(warnings, "Warning, %s.\r\n", duplicate)
+	}
+
+	// Check for samples that are listed multiple times.
+	tentatives, err := tree.ResolveDuplicates()
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("tree, %v", err))
+	}
+	fmt.Fprintf(log, "%s", tentatives)
+
+	// Print samples that are excluded from all calculations.
+	fmt.Fprintf(log, "%s", tree.ExclusionReport())
+
+	// Read prior age estimates.
+	if opts.Priors != "" {
+		err = tree.ReadPriors(opts.Priors)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("reading priors from file, %v", err))
+		}
+	}
+
+	// Read sample weights.
+	if opts.Weights != "" {
+		err = tree.ReadWeightsMatching(opts.Weights, opts.IDMatch)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("reading weights from file, %v", err))
+		}
+	}
+
+	// Remove samples and subclades.
+	for _, id := range opts.ExcludeSamples {
+		if !tree.RemoveSample(id) {
+			return nil, nil, errors.New("could not find sample to exclude " + id)
+		}
+	}
+	// All clades are looked up before any clade is removed, so that
+	// a clade inside another excluded clade is no error.
+	for _, snp := range opts.ExcludeClades {
+		clade, err := tree.FindSubclade(snp)
+		if err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("excluding subclade, %v", err))
+		}
+		if clade == tree {
+			return nil, nil, errors.New("can not exclude the root of the tree " + snp)
+		}
+	}
+	for _, snp := range opts.ExcludeClades {
+		tree.RemoveSubclade(snp)
+	}
+
+	// Select subclades.
+	if len(opts.Subclades) == 0 {
+		return tree, []*phylotree.Clade{tree}, nil
+	}
+	var missing []string
+	for _, name := range opts.Subclades {
+		subclade, err := selectSubclade(tree, name, opts.IDMatch, log)
+		if err != nil {
+			missing = append(missing, err.Error())
+			continue
+		}
+		subclades = append(subclades, subclade)
+	}
+	if len(missing) > 0 {
+		return nil, nil, errors.New("selecting subclades, " + strings.Join(missing, "; "))
+	}
+	// Each subclade becomes the root of it's own tree. The subclades
+	// are cloned, so that nested subclades are calculated independently.
+	for i, _ := range subclades {
+		subclades[i] = subclades[i].Clone()
+	}
+	return tree, subclades, nil
+}
+
+// selectSubclade returns the clade that is named by name. If no
+// clade is found, name may be the ID of a sample. Then the clade
+// that directly contains the sample is returned.
+func selectSubclade(tree *phylotree.Clade, name string, match phylotree.IDMatch, log io.Writer) (*phylotree.Clade, error) {
+	sample, parent := tree.FindSampleMatching(name, match)
+	subclade, err := tree.FindSubclade(name)
+	switch {
+	case err == nil && sample != nil:
+		fmt.Fprintf(log, "Note, %s is a SNP and the sample ID %s, the clade of the SNP is selected.\r\n", name, sample.ID)
+		return subclade, nil
+	case err == nil:
+		return subclade, nil
+	case tree.Subclade(name) != nil:
+		// The SNP name is ambiguous.
+		return nil, err
+	case sample != nil:
+		fmt.Fprintf(log, "Selected clade %s, which contains sample %s.\r\n", parent.SNPs[0], sample.ID)
+		return parent, nil
+	}
+	return nil, errors.New("could not find clade or sample " + name)
+}
+
+// distances are the distance functions by name.
+var distances = map[string]genetic.DistanceFunc{
+	"hybrid":   genetic.DistanceHybrid,
+	"infinite": genetic.DistanceInfiniteAlleles,
+	"stepwise": phylotree.DistanceStepwise}
+
+// DistanceNames returns the names of all distance functions.
+func DistanceNames() []string {
+	return []string{"hybrid", "infinite", "stepwise"}
+}
+
+// Distance returns the distance function, limited by the maximum
+// number of steps. The values of multi-copy markers are sorted
+// before they are compared, Null values differ by one mutation from
+// other values and microalleles are counted by their repeats and
+// partial repeats. For the primary model, this is the function named
+// by DistanceName. If isInfiniteAlleles is true and the primary model
+// is not the infinite alleles model, the distance function of the
+// infinite alleles model is returned.
+func (r *Result) Distance(isInfiniteAlleles bool) genetic.DistanceFunc {
+	if isInfiniteAlleles == true && r.IsInfiniteAlleles == false {
+		return r.distance(genetic.DistanceInfiniteAlleles)
+	}
+	return r.distance(distances[r.DistanceName])
+}
+
+// distance adds the handling of multi-copy markers, Null values,
+// microalleles and the step limit to distance.
+func (r *Result) distance(distance genetic.DistanceFunc) genetic.DistanceFunc {
+	return phylotree.MultiCopyDistance(phylotree.NullDistance(phylotree.MicroalleleDistance(r.Limit.Distance(distance))))
+}
+
+// CalculateModals calculates the modal haplotypes of t by using
+// method, phylofriend or parsimony.
+func (r *Result) CalculateModals(t *phylotree.Clade, method string, isInfiniteAlleles bool) error {
+	switch method {
+	case "phylofriend":
+		t.CalculateModalHaplotypes()
+	case "parsimony":
+		t.CalculateModalHaplotypesParsimonyLimited(r.Statistics, r.options.Stage, isInfiniteAlleles, r.Limit, r.options.MinSupport)
+	default:
+		return errors.New(fmt.Sprintf("unknown method %q to calculate modal haplotypes", method))
+	}
+	return nil
+}
+
+// CalculateAges calculates the distances and the ages of t,
+// whose modal haplotypes must have been calculated.
+func (r *Result) CalculateAges(t *phylotree.Clade) {
+	t.CalculateDistances(r.MutationRates, r.Distance(r.IsInfiniteAlleles))
+	r.calculateAges(t)
+}
+
+// calculateAges calculates the ages of t from it's distances.
+func (r *Result) calculateAges(t *phylotree.Clade) {
+	t.CalculateAgeAveraging(r.options.GenTime, r.Calibration, r.Offset, r.Averaging)
+	// Top down recalculation for more realistic results.
+	if r.options.TopDown == true {
+		t.RecalculateAgeAveraging(r.options.GenTime, r.Calibration, r.Offset, r.Averaging)
+	}
+}
@@ -0,0 +1,233 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yogischogi/phylofriend/genetic"
+)
+
+// TestStdin checks that the tree is read from stdin if the
+// filename is "-".
+func TestStdin(t *testing.T) {
+	opts := DefaultOptions()
+	opts.TreeFiles = []string{"-"}
+	opts.TopDown = false
+	opts.Stdin = strings.NewReader("R\r\n\tA, STR-Count: 4\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 20\r\n\tid:3, STR-Count: 30\r\n")
+	var log bytes.Buffer
+	opts.Log = &log
+	tree, err := Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := tree.Subclade("A")
+	if a == nil || len(a.Samples) != 2 {
+		t.Fatalf("tree from stdin:\n%s", tree)
+	}
+	if a.TMRCA_STR != 15 {
+		t.Errorf("TMRCA of A = %g, want 15", a.TMRCA_STR)
+	}
+}
+
+// writeFile writes text into the file name in dir.
+func writeFile(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// TestCalculateAll runs the whole calculation for a tiny tree
+// and persons file.
+func TestCalculateAll(t *testing.T) {
+	dir := t.TempDir()
+	opts := DefaultOptions()
+	opts.TreeFiles = []string{writeFile(t, dir, "tree.txt", "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tB\r\n\t\tid:3\r\n\t\tid:4\r\n")}
+	var persons bytes.Buffer
+	persons.WriteString("kit,marker,value\r\n")
+	values := map[string][]float64{
+		"DYS393": {13, 14, 13, 13},
+		"DYS390": {24, 24, 23, 24},
+		"DYS19":  {14, 14, 14, 15},
+		"DYS391": {10, 10, 11, 11},
+	}
+	for _, marker := range []string{"DYS393", "DYS390", "DYS19", "DYS391"} {
+		for i, value := range values[marker] {
+			fmt.Fprintf(&persons, "%d,%s,%g\r\n", i+1, marker, value)
+		}
+	}
+	opts.PersonsFiles = []string{writeFile(t, dir, "persons.csv", persons.String())}
+	opts.PersonsFormat = "long"
+	opts.GenTime = 33
+	opts.Subclades = []string{"A", "B"}
+
+	results, err := CalculateAll(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	for i, name := range opts.Subclades {
+		r := results[i]
+		if r.Tree.SNPs[0] != name || len(r.Persons) != 4 {
+			t.Errorf("result %d: clade %s, %d persons", i, r.Tree.SNPs[0], len(r.Persons))
+		}
+		if r.Tree.Person == nil || r.Tree.TMRCA_STR <= 0 {
+			t.Errorf("%s: no modal haplotype or age:\n%s", name, r.Tree)
+		}
+		// The input tree must not contain any results.
+		if r.InputTree == nil || r.InputTree.Person != nil || r.InputTree.Samples[0].Person == nil {
+			t.Errorf("%s: input tree contains results or misses the persons", name)
+		}
+	}
+}
+
+// TestExcludeClades checks that excluded branches are removed
+// together with branches inside them and that the root and
+// unknown names are errors.
+func TestExcludeClades(t *testing.T) {
+	tree := "R\r\n\tA, STR-Count: 4\r\n\t\tC\r\n\t\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 20\r\n\tB\r\n\t\tid:3, STR-Count: 30\r\n"
+	tests := []struct {
+		exclude []string
+		isError bool
+		removed []string
+	}{
+		{[]string{"C", "A"}, false, []string{"A", "C"}},
+		{[]string{"B"}, false, []string{"B"}},
+		{[]string{"R"}, true, nil},
+		{[]string{"D"}, true, nil},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.TreeFiles = []string{"-"}
+		opts.Stdin = strings.NewReader(tree)
+		opts.ExcludeClades = test.exclude
+		opts.Log = ioutil.Discard
+		result, err := Run(opts)
+		if test.isError {
+			if err == nil {
+				t.Errorf("exclude %v: no error", test.exclude)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("exclude %v, %v", test.exclude, err)
+			continue
+		}
+		for _, snp := range test.removed {
+			if result.Subclade(snp) != nil {
+				t.Errorf("exclude %v: clade %s is still part of the tree", test.exclude, snp)
+			}
+		}
+	}
+}
+
+// TestCalibrateSubclades checks that the calibration is fitted once
+// on the whole tree and used for all selected subclades, even if the
+// anchor is not part of a selected subclade.
+func TestCalibrateSubclades(t *testing.T) {
+	tree := "R\r\n\tA, STR-Count: 4\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 20\r\n" +
+		"\tB, STR-Count: 2\r\n\t\tid:3, STR-Count: 30\r\n\t\tid:4, STR-Count: 10\r\n"
+	opts := DefaultOptions()
+	opts.TreeFiles = []string{"-"}
+	opts.Anchors = []Anchor{{Clade: "B", Age: 2000, Weight: 1}}
+	opts.Log = ioutil.Discard
+
+	opts.Stdin = strings.NewReader(tree)
+	whole, err := Calculate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Stdin = strings.NewReader(tree)
+	opts.Subclades = []string{"A", "B"}
+	results, err := CalculateAll(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Calibration != whole.Calibration || r.Offset != whole.Offset {
+			t.Errorf("%s: calibration %g, offset %g, want %g, %g", r.Tree.SNPs[0],
+				r.Calibration, r.Offset, whole.Calibration, whole.Offset)
+		}
+		if n := strings.Count(r.Header, "Calibration factor"); n != 1 {
+			t.Errorf("%s: %d calibration lines in header", r.Tree.SNPs[0], n)
+		}
+	}
+	b := whole.Tree.Subclade("B")
+	if int(b.TMRCA_STR+0.5) != 2000 {
+		t.Errorf("TMRCA of anchor B = %g, want 2000", b.TMRCA_STR)
+	}
+}
+
+// TestCalibrateAncient checks that the age of ancient samples is
+// not part of the calibration factor.
+func TestCalibrateAncient(t *testing.T) {
+	calibrate := func(tree string, age float64) *Result {
+		opts := DefaultOptions()
+		opts.TreeFiles = []string{"-"}
+		opts.Anchors = []Anchor{{Clade: "B", Age: age, Weight: 1}}
+		opts.TopDown = false
+		opts.Log = ioutil.Discard
+		opts.Stdin = strings.NewReader(tree)
+		r, err := Calculate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	modern := calibrate("R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 20\r\n"+
+		"\tB\r\n\t\tid:3, STR-Count: 30\r\n\t\tid:4, STR-Count: 10\r\n", 2000)
+	ancient := calibrate("R\r\n\tA\r\n\t\tid:1, STR-Count: 10\r\n\t\tid:2, STR-Count: 20\r\n"+
+		"\tB\r\n\t\tid:3, STR-Count: 30, sampled: 1000\r\n\t\tid:4, STR-Count: 10, sampled: 1000\r\n", 3000)
+	if ancient.Calibration != modern.Calibration {
+		t.Errorf("calibration = %g, want %g", ancient.Calibration, modern.Calibration)
+	}
+	if b := ancient.Tree.Subclade("B"); int(b.TMRCA_STR+0.5) != 3000 {
+		t.Errorf("TMRCA of anchor B = %g, want 3000", b.TMRCA_STR)
+	}
+}
+
+// TestPanelWarnings checks that clades which mix panels are reported
+// to the Warnings writer of the options.
+func TestPanelWarnings(t *testing.T) {
+	dir := t.TempDir()
+	var persons bytes.Buffer
+	persons.WriteString("kit,marker,value\r\n")
+	for kit, n := range map[int]int{1: 12, 2: 111, 3: 111} {
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&persons, "%d,%s,%d\r\n", kit, genetic.YstrMarkerTable[i].InternalName, 10+i%7)
+		}
+	}
+	opts := DefaultOptions()
+	opts.TreeFiles = []string{writeFile(t, dir, "tree.txt", "R\r\n\tA\r\n\t\tid:1\r\n\t\tid:2\r\n\tid:3\r\n")}
+	opts.PersonsFiles = []string{writeFile(t, dir, "persons.csv", persons.String())}
+	opts.PersonsFormat = "long"
+	var log, warnings bytes.Buffer
+	opts.Log = &log
+	opts.Warnings = &warnings
+
+	if _, err := Calculate(opts); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(warnings.String(), "mixes panels") {
+		t.Errorf("panel warnings without PanelWarnings:\n%s", warnings.String())
+	}
+
+	warnings.Reset()
+	opts.PanelWarnings = true
+	if _, err := Calculate(opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "Warning, A mixes panels from Y12 (1 samples) to Y111 (1 samples).\r\n"
+	if !strings.Contains(warnings.String(), want) || strings.Contains(log.String(), "mixes panels") {
+		t.Errorf("warnings:\n%s\r\nlog:\n%s", warnings.String(), log.String())
+	}
+}